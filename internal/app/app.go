@@ -2,7 +2,9 @@ package app
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 
 	"github.com/stevmwhitfield/recipe-api/internal/data/migrations"
@@ -11,14 +13,32 @@ import (
 )
 
 type Application struct {
-	Logger        *slog.Logger
-	BaseHandler   *handler.BaseHandler
-	RecipeHandler *handler.RecipeHandler
-	TagHandler    *handler.TagHandler
-	DB            *sql.DB
+	Logger              *slog.Logger
+	BaseHandler         *handler.BaseHandler
+	RecipeHandler       *handler.RecipeHandler
+	TagHandler          *handler.TagHandler
+	MealPlanHandler     *handler.MealPlanHandler
+	CalendarHandler     *handler.CalendarHandler
+	UserHandler         *handler.UserHandler
+	CookLogHandler      *handler.CookLogHandler
+	PantryHandler       *handler.PantryHandler
+	ShoppingListHandler *handler.ShoppingListHandler
+	PriceHandler        *handler.PriceHandler
+	IngredientHandler   *handler.IngredientHandler
+	SubstitutionHandler *handler.SubstitutionHandler
+	UserStore           store.UserStore
+	DB                  *sql.DB
 }
 
-func NewApplication() (*Application, error) {
+// NewApplication opens and migrates the database and wires the handlers.
+// publicURL is the absolute http or https url clients reach the api at; it
+// is used in links the api hands out, such as calendar feed urls.
+func NewApplication(publicURL string) (*Application, error) {
+	u, err := url.Parse(publicURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("app: public url %q must be an absolute http or https url", publicURL)
+	}
+
 	// Database
 	db, err := store.Open()
 	if err != nil {
@@ -30,24 +50,61 @@ func NewApplication() (*Application, error) {
 		return nil, err
 	}
 
+	orphans, err := store.CountOrphans(db)
+	if err != nil {
+		return nil, err
+	}
+	if orphans > 0 {
+		return nil, fmt.Errorf("db: %d rows refer to records that no longer exist; run with -clean-orphans to fix them", orphans)
+	}
+
 	// Logger
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
 	// Stores
 	recipeStore := store.NewSQLiteRecipeStore(db)
 	tagStore := store.NewSQLiteTagStore(db)
+	mealPlanStore := store.NewSQLiteMealPlanStore(db)
+	pantryStore := store.NewSQLitePantryStore(db)
+	calendarFeedStore := store.NewSQLiteCalendarFeedStore(db)
+	userStore := store.NewSQLiteUserStore(db)
+	cookLogStore := store.NewSQLiteCookLogStore(db)
+	shoppingListStore := store.NewSQLiteShoppingListStore(db)
+	priceStore := store.NewSQLitePriceStore(db)
+	nutritionStore := store.NewSQLiteNutritionStore(db)
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	substitutionStore := store.NewSQLiteSubstitutionStore(db)
 
 	// Handlers
 	baseHandler := handler.NewBaseHandler(logger)
-	recipeHandler := handler.NewRecipeHandler(logger, recipeStore)
 	tagHandler := handler.NewTagHandler(logger, tagStore)
+	mealPlanHandler := handler.NewMealPlanHandler(logger, mealPlanStore, recipeStore, pantryStore, userStore)
+	calendarHandler := handler.NewCalendarHandler(logger, calendarFeedStore, mealPlanStore, recipeStore, userStore, publicURL)
+	userHandler := handler.NewUserHandler(logger, userStore)
+	cookLogHandler := handler.NewCookLogHandler(logger, cookLogStore, recipeStore, userStore, pantryStore)
+	pantryHandler := handler.NewPantryHandler(logger, pantryStore, recipeStore, userStore)
+	shoppingListHandler := handler.NewShoppingListHandler(logger, shoppingListStore)
+	priceHandler := handler.NewPriceHandler(logger, priceStore)
+	ingredientHandler := handler.NewIngredientHandler(logger, ingredientStore)
+	substitutionHandler := handler.NewSubstitutionHandler(logger, substitutionStore, recipeStore, pantryStore, ingredientStore, userStore)
+	recipeHandler := handler.NewRecipeHandler(logger, recipeStore, priceStore, nutritionStore, userStore, substitutionHandler)
 
 	app := &Application{
-		Logger:        logger,
-		BaseHandler:   baseHandler,
-		RecipeHandler: recipeHandler,
-		TagHandler:    tagHandler,
-		DB:            db,
+		Logger:              logger,
+		BaseHandler:         baseHandler,
+		RecipeHandler:       recipeHandler,
+		TagHandler:          tagHandler,
+		MealPlanHandler:     mealPlanHandler,
+		CalendarHandler:     calendarHandler,
+		UserHandler:         userHandler,
+		CookLogHandler:      cookLogHandler,
+		PantryHandler:       pantryHandler,
+		ShoppingListHandler: shoppingListHandler,
+		PriceHandler:        priceHandler,
+		IngredientHandler:   ingredientHandler,
+		SubstitutionHandler: substitutionHandler,
+		UserStore:           userStore,
+		DB:                  db,
 	}
 
 	return app, nil
@@ -0,0 +1,66 @@
+package cost
+
+import (
+	"math"
+	"sort"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+const (
+	ReasonNoPrice           = "no price"
+	ReasonIncompatibleUnits = "incompatible units"
+)
+
+// Estimate prices every ingredient of the recipe with the most recent
+// price recorded for it whose unit the recipe quantity converts to, using
+// the ingredient's density between weights, volumes and pieces.
+func Estimate(recipe *model.Recipe, prices []model.IngredientPrice) model.RecipeCost {
+	latest := make([]model.IngredientPrice, len(prices))
+	copy(latest, prices)
+	sort.SliceStable(latest, func(a, b int) bool {
+		if latest[a].PricedOn != latest[b].PricedOn {
+			return latest[a].PricedOn > latest[b].PricedOn
+		}
+		return latest[a].CreatedAt.After(latest[b].CreatedAt)
+	})
+
+	estimate := model.RecipeCost{Complete: true, Lines: []model.IngredientCost{}}
+	for _, i := range recipe.Ingredients {
+		line := model.IngredientCost{IngredientID: i.ID, Name: i.Name, Quantity: i.Quantity, Unit: i.Unit, Reason: ReasonNoPrice}
+
+		for _, p := range latest {
+			if p.IngredientID != i.ID || p.Amount <= 0 {
+				continue
+			}
+			line.Reason = ReasonIncompatibleUnits
+
+			quantity, err := units.ConvertWith(i.Quantity, i.Unit, p.Unit, i.Density)
+			if err != nil {
+				continue
+			}
+			line.Cost = round(quantity / p.Amount * p.Price)
+			line.PriceID = p.ID
+			line.Reason = ""
+			break
+		}
+
+		if line.Reason != "" {
+			estimate.Complete = false
+		}
+		estimate.Total += line.Cost
+		estimate.Lines = append(estimate.Lines, line)
+	}
+
+	estimate.Total = round(estimate.Total)
+	if recipe.Servings > 0 {
+		estimate.PerServing = round(estimate.Total / float64(recipe.Servings))
+	}
+
+	return estimate
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
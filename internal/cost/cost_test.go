@@ -0,0 +1,50 @@
+package cost_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/cost"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	recipe := &model.Recipe{
+		Servings: 4,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Name: "Flour", Quantity: 500, Unit: "g"},
+			{ID: "milk", Name: "Milk", Quantity: 2, Unit: "cups"},
+			{ID: "eggs", Name: "Eggs", Quantity: 2, Unit: ""},
+			{ID: "salt", Name: "Salt", Quantity: 1, Unit: "tsp"},
+		},
+	}
+
+	prices := []model.IngredientPrice{
+		{ID: "p1", IngredientID: "flour", Amount: 1, Unit: "kg", Price: 2, PricedOn: "2025-09-01"},
+		{ID: "p2", IngredientID: "flour", Amount: 1, Unit: "kg", Price: 3, PricedOn: "2025-10-01"},
+		{ID: "p3", IngredientID: "milk", Amount: 1, Unit: "l", Price: 1.5, PricedOn: "2025-10-01"},
+		{ID: "p4", IngredientID: "eggs", Amount: 500, Unit: "g", Price: 4, PricedOn: "2025-10-01"},
+	}
+
+	estimate := cost.Estimate(recipe, prices)
+
+	assert.False(t, estimate.Complete)
+	assert.Equal(t, 1.5, estimate.Lines[0].Cost)
+	assert.Equal(t, "p2", estimate.Lines[0].PriceID)
+	assert.Equal(t, 0.71, estimate.Lines[1].Cost)
+	assert.Equal(t, cost.ReasonIncompatibleUnits, estimate.Lines[2].Reason)
+	assert.Equal(t, cost.ReasonNoPrice, estimate.Lines[3].Reason)
+	assert.Equal(t, 2.21, estimate.Total)
+	assert.Equal(t, 0.55, estimate.PerServing)
+}
+
+func TestEstimate_Complete(t *testing.T) {
+	recipe := &model.Recipe{Servings: 2, Ingredients: []model.Ingredient{{ID: "garlic", Quantity: 4, Unit: "cloves"}}}
+	prices := []model.IngredientPrice{{ID: "p1", IngredientID: "garlic", Amount: 10, Unit: "clove", Price: 1, PricedOn: "2025-10-01"}}
+
+	estimate := cost.Estimate(recipe, prices)
+
+	assert.True(t, estimate.Complete)
+	assert.Equal(t, 0.4, estimate.Total)
+	assert.Equal(t, 0.2, estimate.PerServing)
+}
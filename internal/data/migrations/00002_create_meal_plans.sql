@@ -0,0 +1,20 @@
+-- +goose Up
+
+CREATE TABLE meal_plan_entries (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    planned_for TEXT NOT NULL, -- YYYY-MM-DD
+    meal TEXT NOT NULL DEFAULT '',
+    servings INTEGER NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_meal_plan_planned_for ON meal_plan_entries(planned_for); -- searching meal plans by date range
+CREATE INDEX idx_stocked_ingredient ON stocked_ingredients(ingredient_id); -- searching stock for ingredient
+
+-- +goose Down
+
+DROP INDEX idx_stocked_ingredient;
+DROP TABLE meal_plan_entries;
@@ -0,0 +1,12 @@
+-- +goose Up
+
+CREATE TABLE calendar_feeds (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE, -- secret part of the subscription url
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+
+DROP TABLE calendar_feeds;
@@ -0,0 +1,27 @@
+-- +goose Up
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE cook_logs (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    user_id TEXT,
+    cooked_on TEXT NOT NULL, -- YYYY-MM-DD
+    servings INTEGER NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_cook_log_recipe ON cook_logs(recipe_id, cooked_on); -- last cooked per recipe
+CREATE INDEX idx_cook_log_user ON cook_logs(user_id);                -- history per user
+
+-- +goose Down
+
+DROP TABLE cook_logs;
+DROP TABLE users;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+ALTER TABLE stocked_ingredients ADD COLUMN best_before TEXT; -- YYYY-MM-DD
+ALTER TABLE stocked_ingredients ADD COLUMN opened_on TEXT;   -- YYYY-MM-DD
+ALTER TABLE stocked_ingredients ADD COLUMN location TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_stocked_ingredient_best_before ON stocked_ingredients(best_before); -- searching stock expiring soon
+
+-- +goose Down
+
+DROP INDEX idx_stocked_ingredient_best_before;
+ALTER TABLE stocked_ingredients DROP COLUMN location;
+ALTER TABLE stocked_ingredients DROP COLUMN opened_on;
+ALTER TABLE stocked_ingredients DROP COLUMN best_before;
@@ -0,0 +1,39 @@
+-- +goose Up
+
+CREATE TABLE stock_thresholds (
+    ingredient_id TEXT PRIMARY KEY,
+    min_quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE TABLE shopping_lists (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'active', -- active | completed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE TABLE shopping_list_items (
+    id TEXT PRIMARY KEY,
+    shopping_list_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    note TEXT,
+    source TEXT NOT NULL DEFAULT 'manual', -- manual | low stock
+    checked INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (shopping_list_id) REFERENCES shopping_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id)
+);
+
+CREATE UNIQUE INDEX idx_shopping_list_active ON shopping_lists(status) WHERE status = 'active'; -- at most one active list
+CREATE INDEX idx_shopping_list_item_list ON shopping_list_items(shopping_list_id);            -- searching items for list
+
+-- +goose Down
+
+DROP TABLE shopping_list_items;
+DROP TABLE shopping_lists;
+DROP TABLE stock_thresholds;
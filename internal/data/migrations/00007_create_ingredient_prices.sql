@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE ingredient_prices (
+    id TEXT PRIMARY KEY,
+    ingredient_id TEXT NOT NULL,
+    amount REAL NOT NULL,   -- quantity the price is for, in unit
+    unit TEXT NOT NULL,
+    price REAL NOT NULL,
+    store TEXT,
+    priced_on TEXT NOT NULL, -- YYYY-MM-DD
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_ingredient_price ON ingredient_prices(ingredient_id, priced_on); -- searching latest price for ingredient
+
+-- +goose Down
+
+DROP TABLE ingredient_prices;
@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- Amounts are per 100 g of the ingredient
+CREATE TABLE ingredient_nutrition (
+    ingredient_id TEXT PRIMARY KEY,
+    calories REAL NOT NULL DEFAULT 0,
+    protein_g REAL NOT NULL DEFAULT 0,
+    fat_g REAL NOT NULL DEFAULT 0,
+    saturated_fat_g REAL NOT NULL DEFAULT 0,
+    carbs_g REAL NOT NULL DEFAULT 0,
+    fiber_g REAL NOT NULL DEFAULT 0,
+    sugar_g REAL NOT NULL DEFAULT 0,
+    sodium_mg REAL NOT NULL DEFAULT 0,
+    calcium_mg REAL NOT NULL DEFAULT 0,
+    iron_mg REAL NOT NULL DEFAULT 0,
+    potassium_mg REAL NOT NULL DEFAULT 0,
+    vitamin_c_mg REAL NOT NULL DEFAULT 0,
+    source TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE ingredient_nutrition;
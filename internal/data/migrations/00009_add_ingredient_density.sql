@@ -0,0 +1,9 @@
+-- +goose Up
+
+ALTER TABLE ingredients ADD COLUMN density_g_per_ml REAL; -- weight of one milliliter, NULL if unknown
+ALTER TABLE ingredients ADD COLUMN piece_weight_g REAL;   -- weight of one piece, NULL if unknown
+
+-- +goose Down
+
+ALTER TABLE ingredients DROP COLUMN piece_weight_g;
+ALTER TABLE ingredients DROP COLUMN density_g_per_ml;
@@ -0,0 +1,23 @@
+-- +goose Up
+
+CREATE TABLE ingredient_allergens (
+    ingredient_id TEXT NOT NULL,
+    allergen TEXT NOT NULL, -- gluten | dairy | egg | nut | peanut | shellfish | fish | soy | sesame
+    PRIMARY KEY (ingredient_id, allergen),
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE TABLE ingredient_diets (
+    ingredient_id TEXT NOT NULL,
+    diet TEXT NOT NULL, -- vegan | vegetarian
+    PRIMARY KEY (ingredient_id, diet),
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_ingredient_allergen ON ingredient_allergens(allergen); -- filtering recipes by allergen
+CREATE INDEX idx_ingredient_diet ON ingredient_diets(diet);             -- filtering recipes by diet
+
+-- +goose Down
+
+DROP TABLE ingredient_diets;
+DROP TABLE ingredient_allergens;
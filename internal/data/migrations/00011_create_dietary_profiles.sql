@@ -0,0 +1,29 @@
+-- +goose Up
+
+CREATE TABLE user_allergies (
+    user_id TEXT NOT NULL,
+    allergen TEXT NOT NULL,
+    PRIMARY KEY (user_id, allergen),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_diets (
+    user_id TEXT NOT NULL,
+    diet TEXT NOT NULL,
+    PRIMARY KEY (user_id, diet),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_disliked_ingredients (
+    user_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    PRIMARY KEY (user_id, ingredient_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE user_disliked_ingredients;
+DROP TABLE user_diets;
+DROP TABLE user_allergies;
@@ -0,0 +1,26 @@
+-- +goose Up
+
+CREATE TABLE substitutions (
+    id TEXT PRIMARY KEY,
+    ingredient_id TEXT NOT NULL, -- ingredient being replaced
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_substitution_ingredient ON substitutions(ingredient_id);
+
+CREATE TABLE substitution_components (
+    substitution_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    ratio REAL NOT NULL, -- quantity used per unit of the replaced ingredient
+    position INTEGER NOT NULL,
+    PRIMARY KEY (substitution_id, ingredient_id),
+    FOREIGN KEY (substitution_id) REFERENCES substitutions(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE substitution_components;
+DROP TABLE substitutions;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+CREATE TABLE ingredient_aliases (
+    alias TEXT PRIMARY KEY COLLATE NOCASE, -- e.g. green onion for scallion
+    ingredient_id TEXT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_ingredient_alias_ingredient ON ingredient_aliases(ingredient_id); -- searching aliases for ingredient
+
+-- +goose Down
+
+DROP TABLE ingredient_aliases;
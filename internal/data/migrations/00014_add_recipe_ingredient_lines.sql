@@ -0,0 +1,54 @@
+-- +goose Up
+
+CREATE TABLE recipe_ingredient_lines (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    position INTEGER NOT NULL, -- order of the line within the recipe
+    quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    note TEXT,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id)
+);
+
+-- Existing lines get random version 4 ids and keep their insertion order.
+INSERT INTO recipe_ingredient_lines (id, recipe_id, ingredient_id, position, quantity, unit, note)
+SELECT
+    lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' ||
+        substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))),
+    recipe_id,
+    ingredient_id,
+    ROW_NUMBER() OVER (PARTITION BY recipe_id ORDER BY rowid) - 1,
+    quantity,
+    unit,
+    note
+FROM recipe_ingredient;
+
+DROP TABLE recipe_ingredient;
+ALTER TABLE recipe_ingredient_lines RENAME TO recipe_ingredient;
+
+CREATE INDEX idx_recipe_ingredient_recipe ON recipe_ingredient(recipe_id, position); -- listing lines in order
+CREATE INDEX idx_recipe_ingredient_ingredient ON recipe_ingredient(ingredient_id); -- searching recipes by ingredient
+
+-- +goose Down
+
+CREATE TABLE recipe_ingredient_pairs (
+    recipe_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    note TEXT,
+    PRIMARY KEY (recipe_id, ingredient_id),
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id)
+);
+
+-- Only the first line for each ingredient survives.
+INSERT OR IGNORE INTO recipe_ingredient_pairs (recipe_id, ingredient_id, quantity, unit, note)
+SELECT recipe_id, ingredient_id, quantity, unit, note
+FROM recipe_ingredient
+ORDER BY recipe_id, position;
+
+DROP TABLE recipe_ingredient;
+ALTER TABLE recipe_ingredient_pairs RENAME TO recipe_ingredient;
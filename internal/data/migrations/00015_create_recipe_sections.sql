@@ -0,0 +1,21 @@
+-- +goose Up
+
+CREATE TABLE recipe_sections (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    name TEXT NOT NULL, -- e.g. For the frosting
+    position INTEGER NOT NULL,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recipe_section_recipe ON recipe_sections(recipe_id, position); -- listing sections in order
+
+-- NULL outside any section
+ALTER TABLE recipe_ingredient ADD COLUMN section_id TEXT REFERENCES recipe_sections(id) ON DELETE SET NULL;
+ALTER TABLE instructions ADD COLUMN section_id TEXT REFERENCES recipe_sections(id) ON DELETE SET NULL;
+
+-- +goose Down
+
+ALTER TABLE instructions DROP COLUMN section_id;
+ALTER TABLE recipe_ingredient DROP COLUMN section_id;
+DROP TABLE recipe_sections;
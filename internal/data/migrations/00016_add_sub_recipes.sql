@@ -0,0 +1,76 @@
+-- +goose Up
+
+-- A recipe line now uses either an ingredient or another recipe.
+CREATE TABLE recipe_ingredient_lines (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    ingredient_id TEXT,
+    sub_recipe_id TEXT, -- quantity is in servings of this recipe
+    position INTEGER NOT NULL,
+    quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    note TEXT,
+    section_id TEXT,
+    CHECK ((ingredient_id IS NULL) <> (sub_recipe_id IS NULL)),
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id),
+    FOREIGN KEY (sub_recipe_id) REFERENCES recipes(id),
+    FOREIGN KEY (section_id) REFERENCES recipe_sections(id) ON DELETE SET NULL
+);
+
+INSERT INTO recipe_ingredient_lines (id, recipe_id, ingredient_id, position, quantity, unit, note, section_id)
+SELECT id, recipe_id, ingredient_id, position, quantity, unit, note, section_id
+FROM recipe_ingredient;
+
+DROP TABLE recipe_ingredient;
+ALTER TABLE recipe_ingredient_lines RENAME TO recipe_ingredient;
+
+CREATE INDEX idx_recipe_ingredient_recipe ON recipe_ingredient(recipe_id, position); -- listing lines in order
+CREATE INDEX idx_recipe_ingredient_ingredient ON recipe_ingredient(ingredient_id);   -- searching recipes by ingredient
+CREATE INDEX idx_recipe_ingredient_sub_recipe ON recipe_ingredient(sub_recipe_id);   -- searching recipes using a sub-recipe
+
+-- Every ingredient a recipe uses, directly or through its sub-recipes.
+-- UNION stops the recursion if a cycle ever slips in.
+CREATE VIEW recipe_ingredient_closure AS
+WITH RECURSIVE tree(root_id, recipe_id) AS (
+    SELECT id, id FROM recipes
+    UNION
+    SELECT t.root_id, ri.sub_recipe_id
+    FROM tree t
+    JOIN recipe_ingredient ri ON ri.recipe_id = t.recipe_id
+    WHERE ri.sub_recipe_id IS NOT NULL
+)
+SELECT DISTINCT t.root_id AS recipe_id, ri.ingredient_id
+FROM tree t
+JOIN recipe_ingredient ri ON ri.recipe_id = t.recipe_id
+WHERE ri.ingredient_id IS NOT NULL;
+
+-- +goose Down
+
+DROP VIEW recipe_ingredient_closure;
+
+CREATE TABLE recipe_ingredient_lines (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    ingredient_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    quantity REAL NOT NULL,
+    unit TEXT NOT NULL,
+    note TEXT,
+    section_id TEXT,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id),
+    FOREIGN KEY (section_id) REFERENCES recipe_sections(id) ON DELETE SET NULL
+);
+
+-- Sub-recipe lines cannot be represented and are dropped.
+INSERT INTO recipe_ingredient_lines (id, recipe_id, ingredient_id, position, quantity, unit, note, section_id)
+SELECT id, recipe_id, ingredient_id, position, quantity, unit, note, section_id
+FROM recipe_ingredient
+WHERE ingredient_id IS NOT NULL;
+
+DROP TABLE recipe_ingredient;
+ALTER TABLE recipe_ingredient_lines RENAME TO recipe_ingredient;
+
+CREATE INDEX idx_recipe_ingredient_recipe ON recipe_ingredient(recipe_id, position);
+CREATE INDEX idx_recipe_ingredient_ingredient ON recipe_ingredient(ingredient_id);
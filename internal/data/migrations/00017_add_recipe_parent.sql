@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- The recipe this one was forked from; a variation outlives its parent.
+ALTER TABLE recipes ADD COLUMN parent_id TEXT REFERENCES recipes(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_recipe_parent ON recipes(parent_id); -- listing variations of a recipe
+
+-- +goose Down
+
+DROP INDEX idx_recipe_parent;
+ALTER TABLE recipes DROP COLUMN parent_id;
@@ -0,0 +1,15 @@
+-- +goose Up
+
+CREATE TABLE recipe_revisions (
+    recipe_id TEXT NOT NULL,
+    number INTEGER NOT NULL, -- 1 for the oldest revision of the recipe
+    author_id TEXT,          -- users(id), NULL when unknown
+    snapshot TEXT NOT NULL,  -- JSON of the recipe's contents after the change
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (recipe_id, number),
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE recipe_revisions;
@@ -0,0 +1,10 @@
+-- +goose Up
+
+ALTER TABLE recipes ADD COLUMN deleted_at TIMESTAMP; -- set while the recipe is in the trash
+
+CREATE INDEX idx_recipe_deleted_at ON recipes(deleted_at); -- listing and purging the trash
+
+-- +goose Down
+
+DROP INDEX idx_recipe_deleted_at;
+ALTER TABLE recipes DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE recipes ADD COLUMN version INTEGER NOT NULL DEFAULT 1; -- bumped by every change, for optimistic concurrency
+
+-- +goose Down
+
+ALTER TABLE recipes DROP COLUMN version;
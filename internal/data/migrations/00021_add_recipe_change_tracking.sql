@@ -0,0 +1,20 @@
+-- +goose Up
+
+ALTER TABLE recipes ADD COLUMN change_seq INTEGER NOT NULL DEFAULT 0; -- position of the recipe's latest change, for sync
+UPDATE recipes SET change_seq = rowid;
+
+CREATE INDEX idx_recipe_change_seq ON recipes(change_seq);
+
+CREATE TABLE recipe_tombstones (
+    recipe_id TEXT PRIMARY KEY, -- purged recipe, no longer in recipes
+    change_seq INTEGER NOT NULL,
+    deleted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_recipe_tombstone_change_seq ON recipe_tombstones(change_seq);
+
+-- +goose Down
+
+DROP TABLE recipe_tombstones;
+DROP INDEX idx_recipe_change_seq;
+ALTER TABLE recipes DROP COLUMN change_seq;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+ALTER TABLE tags ADD COLUMN created_at TIMESTAMP; -- set on insert; SQLite cannot add a column defaulting to CURRENT_TIMESTAMP
+UPDATE tags SET created_at = CURRENT_TIMESTAMP;
+
+-- +goose Down
+
+ALTER TABLE tags DROP COLUMN created_at;
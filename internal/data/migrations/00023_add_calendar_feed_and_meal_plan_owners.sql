@@ -0,0 +1,71 @@
+-- +goose Up
+
+-- Feeds were listed, tokens and all, to any caller, so none of the existing
+-- tokens can be trusted. They are dropped and their owners create new ones.
+DROP TABLE calendar_feeds;
+
+CREATE TABLE calendar_feeds (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE, -- secret part of the subscription url
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_calendar_feed_user ON calendar_feeds(user_id); -- listing a user's feeds
+
+CREATE TABLE meal_plan_entries_owned (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    user_id TEXT, -- null for the plan anonymous callers share
+    planned_for TEXT NOT NULL, -- YYYY-MM-DD
+    meal TEXT NOT NULL DEFAULT '',
+    servings INTEGER NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Existing entries stay in the shared plan.
+INSERT INTO meal_plan_entries_owned (id, recipe_id, planned_for, meal, servings, note, created_at)
+SELECT id, recipe_id, planned_for, meal, servings, note, created_at
+FROM meal_plan_entries;
+
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_owned RENAME TO meal_plan_entries;
+
+CREATE INDEX idx_meal_plan_planned_for ON meal_plan_entries(user_id, planned_for); -- searching a user's plan by date range
+
+-- +goose Down
+
+CREATE TABLE meal_plan_entries_shared (
+    id TEXT PRIMARY KEY,
+    recipe_id TEXT NOT NULL,
+    planned_for TEXT NOT NULL, -- YYYY-MM-DD
+    meal TEXT NOT NULL DEFAULT '',
+    servings INTEGER NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+-- Every user's entries end up in the one plan.
+INSERT INTO meal_plan_entries_shared (id, recipe_id, planned_for, meal, servings, note, created_at)
+SELECT id, recipe_id, planned_for, meal, servings, note, created_at
+FROM meal_plan_entries;
+
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_shared RENAME TO meal_plan_entries;
+
+CREATE INDEX idx_meal_plan_planned_for ON meal_plan_entries(planned_for); -- searching meal plans by date range
+
+DROP TABLE calendar_feeds;
+
+CREATE TABLE calendar_feeds (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE, -- secret part of the subscription url
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
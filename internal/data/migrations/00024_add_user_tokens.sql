@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Users created before tokens have none, so requests can no longer act as
+-- them; their data is kept.
+ALTER TABLE users ADD COLUMN token_hash TEXT; -- sha-256 of the token issued on creation
+
+CREATE UNIQUE INDEX idx_user_token_hash ON users(token_hash); -- authenticating requests
+
+-- +goose Down
+
+DROP INDEX idx_user_token_hash;
+ALTER TABLE users DROP COLUMN token_hash;
@@ -0,0 +1,97 @@
+package dietary
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+const (
+	WarningAllergen = "allergen"
+	WarningDiet     = "diet"
+	WarningDisliked = "disliked"
+)
+
+// Warnings lists the ways recipe conflicts with profile. It expects the
+// recipe's allergens and diets to be filled in by the store.
+func Warnings(recipe *model.Recipe, profile *model.DietaryProfile) []model.DietaryWarning {
+	warnings := []model.DietaryWarning{}
+	if profile == nil {
+		return warnings
+	}
+
+	for _, a := range profile.Allergies {
+		if slices.Contains(recipe.Allergens, a) {
+			warnings = append(warnings, model.DietaryWarning{
+				Type:    WarningAllergen,
+				Value:   a,
+				Message: fmt.Sprintf("contains %s", a),
+			})
+		}
+	}
+
+	for _, d := range profile.Diets {
+		if !slices.Contains(recipe.Diets, d) {
+			warnings = append(warnings, model.DietaryWarning{
+				Type:    WarningDiet,
+				Value:   d,
+				Message: fmt.Sprintf("not %s", d),
+			})
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, i := range recipe.Ingredients {
+		if seen[i.ID] || !slices.Contains(profile.DislikedIngredientIDs, i.ID) {
+			continue
+		}
+		seen[i.ID] = true
+		warnings = append(warnings, model.DietaryWarning{
+			Type:    WarningDisliked,
+			Value:   i.ID,
+			Message: fmt.Sprintf("uses %s", i.Name),
+		})
+	}
+
+	return warnings
+}
+
+// IngredientWarnings lists the ways a single catalog ingredient conflicts
+// with profile.
+func IngredientWarnings(ingredient model.CatalogIngredient, profile *model.DietaryProfile) []model.DietaryWarning {
+	warnings := []model.DietaryWarning{}
+	if profile == nil {
+		return warnings
+	}
+
+	for _, a := range profile.Allergies {
+		if slices.Contains(ingredient.Allergens, a) {
+			warnings = append(warnings, model.DietaryWarning{
+				Type:    WarningAllergen,
+				Value:   a,
+				Message: fmt.Sprintf("contains %s", a),
+			})
+		}
+	}
+
+	for _, d := range profile.Diets {
+		if !slices.Contains(ingredient.Diets, d) {
+			warnings = append(warnings, model.DietaryWarning{
+				Type:    WarningDiet,
+				Value:   d,
+				Message: fmt.Sprintf("not %s", d),
+			})
+		}
+	}
+
+	if slices.Contains(profile.DislikedIngredientIDs, ingredient.ID) {
+		warnings = append(warnings, model.DietaryWarning{
+			Type:    WarningDisliked,
+			Value:   ingredient.ID,
+			Message: "disliked",
+		})
+	}
+
+	return warnings
+}
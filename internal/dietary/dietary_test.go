@@ -0,0 +1,77 @@
+package dietary_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/dietary"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	recipe := &model.Recipe{
+		Allergens: []string{"dairy", "gluten"},
+		Diets:     []string{"vegetarian"},
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Name: "Flour"},
+			{ID: "mushroom", Name: "Mushroom"},
+			{ID: "mushroom", Name: "Mushroom"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		profile *model.DietaryProfile
+		want    []model.DietaryWarning
+	}{
+		{
+			name:    "no profile",
+			profile: nil,
+			want:    []model.DietaryWarning{},
+		},
+		{
+			name: "compatible",
+			profile: &model.DietaryProfile{
+				Allergies:             []string{"peanut"},
+				Diets:                 []string{"vegetarian"},
+				DislikedIngredientIDs: []string{"olives"},
+			},
+			want: []model.DietaryWarning{},
+		},
+		{
+			name: "conflicts",
+			profile: &model.DietaryProfile{
+				Allergies:             []string{"dairy", "peanut"},
+				Diets:                 []string{"vegan", "vegetarian"},
+				DislikedIngredientIDs: []string{"mushroom"},
+			},
+			want: []model.DietaryWarning{
+				{Type: "allergen", Value: "dairy", Message: "contains dairy"},
+				{Type: "diet", Value: "vegan", Message: "not vegan"},
+				{Type: "disliked", Value: "mushroom", Message: "uses Mushroom"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dietary.Warnings(recipe, tt.profile))
+		})
+	}
+}
+
+func TestIngredientWarnings(t *testing.T) {
+	butter := model.CatalogIngredient{ID: "butter", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}}
+	profile := &model.DietaryProfile{
+		Allergies:             []string{"dairy"},
+		Diets:                 []string{"vegan"},
+		DislikedIngredientIDs: []string{"butter"},
+	}
+
+	assert.Equal(t, []model.DietaryWarning{
+		{Type: "allergen", Value: "dairy", Message: "contains dairy"},
+		{Type: "diet", Value: "vegan", Message: "not vegan"},
+		{Type: "disliked", Value: "butter", Message: "disliked"},
+	}, dietary.IngredientWarnings(butter, profile))
+	assert.Equal(t, []model.DietaryWarning{}, dietary.IngredientWarnings(butter, nil))
+}
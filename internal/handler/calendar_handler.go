@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/ical"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+const (
+	feedPastDays   = 30
+	feedFutureDays = 365
+)
+
+// Meals without a time of day are placed at dinner.
+var mealTimes = map[string]time.Duration{
+	"breakfast": 8 * time.Hour,
+	"lunch":     12*time.Hour + 30*time.Minute,
+	"snack":     15 * time.Hour,
+	"dinner":    18*time.Hour + 30*time.Minute,
+	"":          18*time.Hour + 30*time.Minute,
+}
+
+type CalendarHandler struct {
+	logger        *slog.Logger
+	feedStore     store.CalendarFeedStore
+	mealPlanStore store.MealPlanStore
+	recipeStore   store.RecipeStore
+	userStore     store.UserStore
+
+	// publicURL is where clients reach the api, without a trailing slash.
+	// Feed and recipe links are built from it rather than from request
+	// headers, which the client controls.
+	publicURL string
+}
+
+func NewCalendarHandler(l *slog.Logger, fs store.CalendarFeedStore, ms store.MealPlanStore, rs store.RecipeStore, us store.UserStore, publicURL string) *CalendarHandler {
+	return &CalendarHandler{
+		logger:        l,
+		feedStore:     fs,
+		mealPlanStore: ms,
+		recipeStore:   rs,
+		userStore:     us,
+		publicURL:     strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// Routes manages the caller's feeds, so every route needs a user token.
+func (h *CalendarHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequireUserID)
+	r.Get("/", h.ListFeeds)
+	r.Post("/", h.CreateFeed)
+	r.Delete("/{id}", h.DeleteFeed)
+
+	return r
+}
+
+// ListFeeds leaves out tokens and urls. They are shown once, by CreateFeed;
+// a lost url is replaced by deleting the feed and creating another.
+func (h *CalendarHandler) ListFeeds(w http.ResponseWriter, r *http.Request) {
+	feeds, err := h.feedStore.ListFeeds(middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		h.logger.Error("ListFeeds", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch calendar feeds"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"feeds": feeds, "total": len(feeds)})
+}
+
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	var feed model.CalendarFeed
+	err := json.NewDecoder(r.Body).Decode(&feed)
+	if err != nil {
+		h.logger.Error("CreateFeed", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(feed.Name) == "" {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "name cannot be blank"})
+		return
+	}
+
+	feed.UserID = middleware.UserIDFromContext(r.Context())
+	user, err := h.userStore.GetUserByID(feed.UserID)
+	if err != nil {
+		h.logger.Error("CreateFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+		return
+	}
+	if user == nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "user does not exist"})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	feed.ID = id
+
+	token, err := util.GenerateToken()
+	if err != nil {
+		h.logger.Error("CreateFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate token"})
+		return
+	}
+	feed.Token = token
+
+	createdFeed, err := h.feedStore.CreateFeed(&feed)
+	if err != nil {
+		h.logger.Error("CreateFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create calendar feed"})
+		return
+	}
+	createdFeed.URL = h.publicURL + "/api/v1/calendar/" + createdFeed.Token + ".ics"
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"feed": createdFeed})
+}
+
+func (h *CalendarHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	feedID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteFeed", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid calendar feed id"})
+		return
+	}
+
+	err = h.feedStore.DeleteFeed(feedID, middleware.UserIDFromContext(r.Context()))
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete calendar feed"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeFeed renders the owner's meal plan as an iCalendar feed. The token in
+// the url is the only credential, so unknown tokens are reported as not
+// found.
+func (h *CalendarHandler) ServeFeed(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	feed, err := h.feedStore.GetFeedByToken(token)
+	if err != nil {
+		h.logger.Error("ServeFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch calendar feed"})
+		return
+	}
+	if feed == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -feedPastDays).Format(dateLayout)
+	to := now.AddDate(0, 0, feedFutureDays).Format(dateLayout)
+
+	entries, err := h.mealPlanStore.ListEntries(feed.UserID, from, to)
+	if err != nil {
+		h.logger.Error("ServeFeed", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch meal plan"})
+		return
+	}
+
+	recipes := map[string]*model.Recipe{}
+	events := []ical.Event{}
+	for _, e := range entries {
+		recipe, ok := recipes[e.RecipeID]
+		if !ok {
+			recipe, err = h.recipeStore.GetRecipeByID(e.RecipeID)
+			if err != nil {
+				h.logger.Error("ServeFeed", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+				return
+			}
+			recipes[e.RecipeID] = recipe
+		}
+		if recipe == nil {
+			continue
+		}
+
+		event, err := mealPlanEvent(e, recipe, h.publicURL)
+		if err != nil {
+			h.logger.Error("ServeFeed", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="meal-plan.ics"`)
+	w.WriteHeader(http.StatusOK)
+	if err := ical.Write(w, feed.Name, now, events); err != nil {
+		h.logger.Error("ServeFeed", "error", err)
+	}
+}
+
+// mealPlanEvent builds an event that ends at the meal time and starts early
+// enough to prepare and cook the recipe.
+func mealPlanEvent(e model.MealPlanEntry, recipe *model.Recipe, base string) (ical.Event, error) {
+	day, err := time.Parse(dateLayout, e.Date)
+	if err != nil {
+		return ical.Event{}, err
+	}
+
+	mealAt := day.Add(mealTimes[e.Meal])
+	duration := time.Duration(recipe.PrepTimeSeconds+recipe.CookTimeSeconds) * time.Second
+
+	description := []string{
+		fmt.Sprintf("Servings: %d", e.Servings),
+		fmt.Sprintf("Prep time: %s", formatMinutes(recipe.PrepTimeSeconds)),
+		fmt.Sprintf("Cook time: %s", formatMinutes(recipe.CookTimeSeconds)),
+	}
+	if e.Note != "" {
+		description = append(description, e.Note)
+	}
+
+	return ical.Event{
+		UID:         e.ID + "@recipe-api",
+		Start:       mealAt.Add(-duration),
+		End:         mealAt,
+		Summary:     recipe.Name,
+		Description: strings.Join(description, "\n"),
+		URL:         base + "/api/v1/recipes/" + recipe.ID,
+	}, nil
+}
+
+func formatMinutes(seconds int) string {
+	return fmt.Sprintf("%d min", (seconds+59)/60)
+}
@@ -0,0 +1,206 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockCalendarFeedStore struct {
+	mock.Mock
+}
+
+func (m *MockCalendarFeedStore) ListFeeds(userID string) ([]model.CalendarFeed, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.CalendarFeed), args.Error(1)
+}
+func (m *MockCalendarFeedStore) CreateFeed(f *model.CalendarFeed) (*model.CalendarFeed, error) {
+	args := m.Called(f)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CalendarFeed), args.Error(1)
+}
+func (m *MockCalendarFeedStore) GetFeedByToken(token string) (*model.CalendarFeed, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CalendarFeed), args.Error(1)
+}
+func (m *MockCalendarFeedStore) DeleteFeed(id, userID string) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+// tests
+
+func TestCalendarHandler(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+
+	setup := func() (*MockCalendarFeedStore, *MockMealPlanStore, *MockRecipeStore, *MockUserStore, *chi.Mux) {
+		feedStore := &MockCalendarFeedStore{}
+		mealPlanStore := &MockMealPlanStore{}
+		recipeStore := &MockRecipeStore{}
+		userStore := &MockUserStore{}
+
+		h := handler.NewCalendarHandler(logger, feedStore, mealPlanStore, recipeStore, userStore, "https://recipes.example.org/")
+
+		r := chi.NewRouter()
+		r.Use(chiMiddleware.URLFormat)
+		r.Use(middleware.UserIDCtx(tokenIsUserID))
+		r.Mount("/calendar-feeds", h.Routes())
+		r.Get("/calendar/{token}", h.ServeFeed)
+		return feedStore, mealPlanStore, recipeStore, userStore, r
+	}
+
+	t.Run("create feed", func(t *testing.T) {
+		feedStore, _, _, userStore, r := setup()
+		userStore.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+		feedStore.On("CreateFeed", mock.MatchedBy(func(f *model.CalendarFeed) bool {
+			return f.Name == "Kitchen" && f.UserID == userID && len(f.Token) == 64
+		})).Return(&model.CalendarFeed{ID: "f1", UserID: userID, Name: "Kitchen", Token: "secret"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/calendar-feeds", strings.NewReader(`{"name": "Kitchen"}`))
+		req.Header.Set(middleware.AuthorizationHeader, "Bearer "+userID)
+		// The url comes from configuration, not from headers the client sets.
+		req.Host = "attacker.example.net"
+		req.Header.Set("X-Forwarded-Proto", "http")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		wantJSON, _ := json.Marshal(util.Envelope{"feed": model.CalendarFeed{
+			ID: "f1", UserID: userID, Name: "Kitchen", Token: "secret", URL: "https://recipes.example.org/api/v1/calendar/secret.ics",
+		}})
+		assert.JSONEq(t, string(wantJSON), w.Body.String())
+		feedStore.AssertExpectations(t)
+		userStore.AssertExpectations(t)
+	})
+
+	t.Run("create feed for unknown user", func(t *testing.T) {
+		_, _, _, userStore, r := setup()
+		userStore.On("GetUserByID", userID).Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/calendar-feeds", strings.NewReader(`{"name": "Kitchen"}`))
+		req.Header.Set(middleware.AuthorizationHeader, "Bearer "+userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"error": "user does not exist"}`, w.Body.String())
+	})
+
+	t.Run("create feed with blank name", func(t *testing.T) {
+		_, _, _, _, r := setup()
+
+		req := httptest.NewRequest(http.MethodPost, "/calendar-feeds", strings.NewReader(`{"name": " "}`))
+		req.Header.Set(middleware.AuthorizationHeader, "Bearer "+userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"error": "name cannot be blank"}`, w.Body.String())
+	})
+
+	t.Run("feeds require a user", func(t *testing.T) {
+		_, _, _, _, r := setup()
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/calendar-feeds", nil),
+			httptest.NewRequest(http.MethodPost, "/calendar-feeds", strings.NewReader(`{"name": "Kitchen"}`)),
+			httptest.NewRequest(http.MethodDelete, "/calendar-feeds/019a40de-02cd-7865-84ae-c038b75596f5", nil),
+		} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.JSONEq(t, `{"error": "a user token is required"}`, w.Body.String())
+		}
+	})
+
+	t.Run("list feeds without tokens", func(t *testing.T) {
+		feedStore, _, _, _, r := setup()
+		feedStore.On("ListFeeds", userID).Return([]model.CalendarFeed{{ID: "f1", UserID: userID, Name: "Kitchen"}}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/calendar-feeds", nil)
+		req.Header.Set(middleware.AuthorizationHeader, "Bearer "+userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"feeds": [{"id": "f1", "userId": "`+userID+`", "name": "Kitchen", "createdAt": "0001-01-01T00:00:00Z"}], "total": 1}`, w.Body.String())
+		feedStore.AssertExpectations(t)
+	})
+
+	t.Run("delete another user's feed", func(t *testing.T) {
+		feedStore, _, _, _, r := setup()
+		feedStore.On("DeleteFeed", "019a40de-02cd-7865-84ae-c038b75596f5", userID).Return(sql.ErrNoRows)
+
+		req := httptest.NewRequest(http.MethodDelete, "/calendar-feeds/019a40de-02cd-7865-84ae-c038b75596f5", nil)
+		req.Header.Set(middleware.AuthorizationHeader, "Bearer "+userID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		feedStore.AssertExpectations(t)
+	})
+
+	t.Run("serve feed with unknown token", func(t *testing.T) {
+		feedStore, _, _, _, r := setup()
+		feedStore.On("GetFeedByToken", "nope").Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/calendar/nope.ics", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		feedStore.AssertExpectations(t)
+	})
+
+	t.Run("serve feed", func(t *testing.T) {
+		feedStore, mealPlanStore, recipeStore, _, r := setup()
+		date := time.Now().Format("2006-01-02")
+		recipe := &model.Recipe{ID: "r1", Name: "Classic Pancakes", Servings: 4, PrepTimeSeconds: 600, CookTimeSeconds: 900}
+
+		feedStore.On("GetFeedByToken", "secret").Return(&model.CalendarFeed{ID: "f1", UserID: userID, Name: "Kitchen", Token: "secret"}, nil)
+		mealPlanStore.On("ListEntries", userID, mock.Anything, mock.Anything).Return([]model.MealPlanEntry{
+			{ID: "m1", RecipeID: "r1", Date: date, Meal: "breakfast", Servings: 4},
+			{ID: "m2", RecipeID: "r1", Date: date, Meal: "", Servings: 2},
+		}, nil)
+		recipeStore.On("GetRecipeByID", "r1").Return(recipe, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/calendar/secret.ics", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		compact := strings.ReplaceAll(date, "-", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "DTSTART:"+compact+"T073500\r\n")
+		assert.Contains(t, w.Body.String(), "DTEND:"+compact+"T080000\r\n")
+		assert.Contains(t, w.Body.String(), "DTSTART:"+compact+"T180500\r\n")
+		assert.Contains(t, w.Body.String(), "URL:https://recipes.example.org/api/v1/recipes/r1\r\n")
+		feedStore.AssertExpectations(t)
+		mealPlanStore.AssertExpectations(t)
+		recipeStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+type CookLogHandler struct {
+	logger       *slog.Logger
+	cookLogStore store.CookLogStore
+	recipeStore  store.RecipeStore
+	userStore    store.UserStore
+	pantryStore  store.PantryStore
+}
+
+func NewCookLogHandler(l *slog.Logger, cs store.CookLogStore, rs store.RecipeStore, us store.UserStore, ps store.PantryStore) *CookLogHandler {
+	return &CookLogHandler{
+		logger:       l,
+		cookLogStore: cs,
+		recipeStore:  rs,
+		userStore:    us,
+		pantryStore:  ps,
+	}
+}
+
+func (h *CookLogHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListCookLogs)
+	r.Post("/", h.CreateCookLog)
+	r.Delete("/{id}", h.DeleteCookLog)
+
+	return r
+}
+
+func (h *CookLogHandler) ListCookLogs(w http.ResponseWriter, r *http.Request) {
+	filter := store.CookLogFilter{
+		RecipeID: r.URL.Query().Get("recipeId"),
+		UserID:   r.URL.Query().Get("userId"),
+	}
+	for _, id := range []string{filter.RecipeID, filter.UserID} {
+		if id == "" {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid id filter"})
+			return
+		}
+	}
+
+	logs, err := h.cookLogStore.ListCookLogs(filter)
+	if err != nil {
+		h.logger.Error("ListCookLogs", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch cook logs"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"cookLogs": logs, "total": len(logs)})
+}
+
+// CreateCookLog records that the caller cooked a recipe. Cooked-on defaults
+// to today and servings default to the recipe's servings. The scaled
+// ingredients are deducted from the pantry unless deductPantry=false, and
+// dryRun=true previews the deduction without recording anything.
+func (h *CookLogHandler) CreateCookLog(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	deductPantry := r.URL.Query().Get("deductPantry") != "false"
+
+	var cookLog model.CookLog
+	err := json.NewDecoder(r.Body).Decode(&cookLog)
+	if err != nil {
+		h.logger.Error("CreateCookLog", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if cookLog.CookedOn == "" {
+		cookLog.CookedOn = time.Now().Format(dateLayout)
+	}
+	if err := validateCookLog(&cookLog); err != nil {
+		h.logger.Error("CreateCookLog", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(cookLog.RecipeID)
+	if err != nil {
+		h.logger.Error("CreateCookLog", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "recipe does not exist"})
+		return
+	}
+	cookLog.RecipeName = recipe.Name
+	if cookLog.Servings == 0 {
+		cookLog.Servings = recipe.Servings
+	}
+
+	cookLog.UserID = middleware.UserIDFromContext(r.Context())
+	if cookLog.UserID != "" {
+		user, err := h.userStore.GetUserByID(cookLog.UserID)
+		if err != nil {
+			h.logger.Error("CreateCookLog", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+			return
+		}
+		if user == nil {
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "user does not exist"})
+			return
+		}
+	}
+
+	var expanded *model.Recipe
+	if deductPantry {
+		expanded, err = subrecipe.Expand(recipe, h.recipeStore.GetRecipeByID)
+		if err != nil {
+			h.logger.Error("CreateCookLog", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+			return
+		}
+	}
+
+	if dryRun {
+		report := model.PantryDeductionReport{
+			Deductions: []model.PantryDeduction{},
+			Depleted:   []model.PantryDeduction{},
+			Unmatched:  []model.UnmatchedIngredient{},
+		}
+		if expanded != nil {
+			stock, err := h.pantryStore.ListStockedIngredients()
+			if err != nil {
+				h.logger.Error("CreateCookLog", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+				return
+			}
+			report = pantry.PlanDeduction(expanded, cookLog.Servings, stock)
+		}
+		util.WriteJSON(w, http.StatusOK, util.Envelope{"cookLog": cookLog, "pantry": report})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateCookLog", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	cookLog.ID = id
+
+	// The store plans the deduction from the stock it reads in its own
+	// transaction, so concurrent cooks cannot both use the same stock.
+	createdCookLog, report, err := h.cookLogStore.CreateCookLog(&cookLog, expanded)
+	if err != nil {
+		h.logger.Error("CreateCookLog", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create cook log"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"cookLog": createdCookLog, "pantry": report})
+}
+
+func (h *CookLogHandler) DeleteCookLog(w http.ResponseWriter, r *http.Request) {
+	cookLogID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteCookLog", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid cook log id"})
+		return
+	}
+
+	err = h.cookLogStore.DeleteCookLog(cookLogID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteCookLog", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete cook log"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateCookLog(l *model.CookLog) error {
+	if l.RecipeID == "" {
+		return errors.New("recipe id cannot be blank")
+	}
+
+	if _, err := time.Parse(dateLayout, l.CookedOn); err != nil {
+		return errors.New("cooked on must be a date in YYYY-MM-DD format")
+	}
+
+	if l.Servings < 0 {
+		return errors.New("servings cannot be a negative value")
+	}
+	return nil
+}
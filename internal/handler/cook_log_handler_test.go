@@ -0,0 +1,236 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockCookLogStore struct {
+	mock.Mock
+}
+
+func (m *MockCookLogStore) ListCookLogs(f store.CookLogFilter) ([]model.CookLog, error) {
+	args := m.Called(f)
+	return args.Get(0).([]model.CookLog), args.Error(1)
+}
+func (m *MockCookLogStore) CreateCookLog(l *model.CookLog, r *model.Recipe) (*model.CookLog, model.PantryDeductionReport, error) {
+	args := m.Called(l, r)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(model.PantryDeductionReport), args.Error(2)
+	}
+	return args.Get(0).(*model.CookLog), args.Get(1).(model.PantryDeductionReport), args.Error(2)
+}
+func (m *MockCookLogStore) DeleteCookLog(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// tests
+
+func TestCookLogHandler(t *testing.T) {
+	recipeID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+	pancakes := &model.Recipe{
+		ID:       recipeID,
+		Name:     "Classic Pancakes",
+		Servings: 4,
+		Ingredients: []model.Ingredient{
+			{ID: "i1", Name: "Flour", Quantity: 2, Unit: "cup"},
+			{ID: "i3", Name: "Eggs", Quantity: 2, Unit: ""},
+		},
+	}
+	stock := []model.StockedIngredient{
+		{ID: "s1", IngredientID: "i1", Name: "Flour", Quantity: 1000, Unit: "ml"},
+		{ID: "s2", IngredientID: "i3", Name: "Eggs", Quantity: 1, Unit: ""},
+	}
+	deductions := []model.PantryDeduction{
+		{StockID: "s1", IngredientID: "i1", Name: "Flour", Quantity: 473.176, Unit: "ml", Remaining: 526.824},
+		{StockID: "s2", IngredientID: "i3", Name: "Eggs", Quantity: 1, Unit: "", Remaining: 0},
+	}
+	report := model.PantryDeductionReport{
+		Deductions: deductions,
+		Depleted:   deductions[1:],
+		Unmatched: []model.UnmatchedIngredient{
+			{IngredientID: "i3", Name: "Eggs", Quantity: 1, Unit: "", Reason: "insufficient stock"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		userID    string
+		data      io.Reader
+		setupMock func(*MockCookLogStore, *MockRecipeStore, *MockUserStore, *MockPantryStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list cook logs for user",
+			method: http.MethodGet,
+			uri:    "/?userId=" + userID,
+			setupMock: func(m *MockCookLogStore, _ *MockRecipeStore, _ *MockUserStore, _ *MockPantryStore) {
+				m.On("ListCookLogs", store.CookLogFilter{UserID: userID}).Return([]model.CookLog{
+					{ID: "c1", RecipeID: recipeID, UserID: userID, CookedOn: "2025-11-01", Servings: 4},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"cookLogs": []model.CookLog{
+				{ID: "c1", RecipeID: recipeID, UserID: userID, CookedOn: "2025-11-01", Servings: 4},
+			}, "total": 1},
+		},
+		{
+			name:     "list cook logs with invalid filter",
+			method:   http.MethodGet,
+			uri:      "/?recipeId=123",
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "invalid id filter"},
+		},
+		{
+			name:   "create cook log with defaults",
+			method: http.MethodPost,
+			uri:    "/",
+			userID: userID,
+			data:   strings.NewReader(`{"recipeId": "` + recipeID + `", "notes": "extra crispy"}`),
+			setupMock: func(m *MockCookLogStore, rs *MockRecipeStore, us *MockUserStore, _ *MockPantryStore) {
+				rs.On("GetRecipeByID", recipeID).Return(pancakes, nil)
+				us.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+				m.On("CreateCookLog", mock.MatchedBy(func(l *model.CookLog) bool {
+					return l.UserID == userID && l.Servings == 4 && l.CookedOn == time.Now().Format("2006-01-02")
+				}), mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.ID == recipeID && len(r.Ingredients) == 2
+				})).Return(&model.CookLog{ID: "c1", RecipeID: recipeID, UserID: userID, Servings: 4}, report, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{
+				"cookLog": &model.CookLog{ID: "c1", RecipeID: recipeID, UserID: userID, Servings: 4},
+				"pantry":  report,
+			},
+		},
+		{
+			name:   "create cook log dry run",
+			method: http.MethodPost,
+			uri:    "/?dryRun=true",
+			data:   strings.NewReader(`{"recipeId": "` + recipeID + `", "cookedOn": "2025-11-01", "servings": 2}`),
+			setupMock: func(_ *MockCookLogStore, rs *MockRecipeStore, _ *MockUserStore, ps *MockPantryStore) {
+				rs.On("GetRecipeByID", recipeID).Return(pancakes, nil)
+				ps.On("ListStockedIngredients").Return(stock, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"cookLog": model.CookLog{RecipeID: recipeID, RecipeName: "Classic Pancakes", CookedOn: "2025-11-01", Servings: 2},
+				"pantry": model.PantryDeductionReport{
+					Deductions: []model.PantryDeduction{
+						{StockID: "s1", IngredientID: "i1", Name: "Flour", Quantity: 236.588, Unit: "ml", Remaining: 763.412},
+						{StockID: "s2", IngredientID: "i3", Name: "Eggs", Quantity: 1, Unit: "", Remaining: 0},
+					},
+					Depleted: []model.PantryDeduction{
+						{StockID: "s2", IngredientID: "i3", Name: "Eggs", Quantity: 1, Unit: "", Remaining: 0},
+					},
+					Unmatched: []model.UnmatchedIngredient{},
+				},
+			},
+		},
+		{
+			name:   "create cook log without pantry deduction",
+			method: http.MethodPost,
+			uri:    "/?deductPantry=false",
+			data:   strings.NewReader(`{"recipeId": "` + recipeID + `", "cookedOn": "2025-11-01"}`),
+			setupMock: func(m *MockCookLogStore, rs *MockRecipeStore, _ *MockUserStore, _ *MockPantryStore) {
+				rs.On("GetRecipeByID", recipeID).Return(pancakes, nil)
+				m.On("CreateCookLog", mock.AnythingOfType("*model.CookLog"), (*model.Recipe)(nil)).Return(&model.CookLog{ID: "c1"}, model.PantryDeductionReport{}, nil)
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:   "create cook log with unknown user",
+			method: http.MethodPost,
+			uri:    "/",
+			userID: userID,
+			data:   strings.NewReader(`{"recipeId": "` + recipeID + `"}`),
+			setupMock: func(_ *MockCookLogStore, rs *MockRecipeStore, us *MockUserStore, _ *MockPantryStore) {
+				rs.On("GetRecipeByID", recipeID).Return(pancakes, nil)
+				us.On("GetUserByID", userID).Return(nil, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "user does not exist"},
+		},
+		{
+			name:     "create cook log with invalid date",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"recipeId": "` + recipeID + `", "cookedOn": "yesterday"}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "cooked on must be a date in YYYY-MM-DD format"},
+		},
+		{
+			name:   "delete cook log with error",
+			method: http.MethodDelete,
+			uri:    "/" + recipeID,
+			setupMock: func(m *MockCookLogStore, _ *MockRecipeStore, _ *MockUserStore, _ *MockPantryStore) {
+				m.On("DeleteCookLog", recipeID).Return(errors.New("database error"))
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: util.Envelope{"error": "failed to delete cook log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			cookLogStore := &MockCookLogStore{}
+			recipeStore := &MockRecipeStore{}
+			userStore := &MockUserStore{}
+			pantryStore := &MockPantryStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(cookLogStore, recipeStore, userStore, pantryStore)
+			}
+
+			h := handler.NewCookLogHandler(logger, cookLogStore, recipeStore, userStore, pantryStore)
+
+			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			cookLogStore.AssertExpectations(t)
+			recipeStore.AssertExpectations(t)
+			userStore.AssertExpectations(t)
+			pantryStore.AssertExpectations(t)
+		})
+	}
+}
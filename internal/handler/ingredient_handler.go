@@ -0,0 +1,376 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+var validAllergens = map[string]bool{
+	"gluten": true, "dairy": true, "egg": true, "nut": true, "peanut": true,
+	"shellfish": true, "fish": true, "soy": true, "sesame": true,
+}
+
+var validDiets = map[string]bool{"vegan": true, "vegetarian": true}
+
+type IngredientHandler struct {
+	logger          *slog.Logger
+	ingredientStore store.IngredientStore
+}
+
+func NewIngredientHandler(l *slog.Logger, is store.IngredientStore) *IngredientHandler {
+	return &IngredientHandler{
+		logger:          l,
+		ingredientStore: is,
+	}
+}
+
+func (h *IngredientHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListIngredients)
+	r.Post("/", h.CreateIngredient)
+
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", h.GetIngredientByID)
+		r.Put("/", h.UpdateIngredient)
+		r.Post("/merge", h.MergeIngredients)
+	})
+
+	return r
+}
+
+// ListIngredients supports name, which finds the ingredient with that name
+// or alias instead of listing the whole catalog.
+func (h *IngredientHandler) ListIngredients(w http.ResponseWriter, r *http.Request) {
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		ingredient, err := h.ingredientStore.FindIngredientByName(name)
+		if err != nil {
+			h.logger.Error("ListIngredients", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch ingredients"})
+			return
+		}
+
+		ingredients := []model.CatalogIngredient{}
+		if ingredient != nil {
+			ingredients = append(ingredients, *ingredient)
+		}
+		util.WriteJSON(w, http.StatusOK, util.Envelope{"ingredients": ingredients, "total": len(ingredients)})
+		return
+	}
+
+	ingredients, err := h.ingredientStore.ListIngredients()
+	if err != nil {
+		h.logger.Error("ListIngredients", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch ingredients"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"ingredients": ingredients, "total": len(ingredients)})
+}
+
+func (h *IngredientHandler) CreateIngredient(w http.ResponseWriter, r *http.Request) {
+	var ingredient model.CatalogIngredient
+	err := json.NewDecoder(r.Body).Decode(&ingredient)
+	if err != nil {
+		h.logger.Error("CreateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	ingredient.Allergens = normalizeFlags(ingredient.Allergens)
+	ingredient.Diets = normalizeDiets(ingredient.Diets)
+	ingredient.Aliases = normalizeAliases(ingredient.Aliases, ingredient.Name)
+	if err := validateIngredient(&ingredient); err != nil {
+		h.logger.Error("CreateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	ingredient.ID = id
+
+	createdIngredient, err := h.ingredientStore.CreateIngredient(&ingredient)
+	if errors.Is(err, store.ErrAliasTaken) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "alias is already used by another ingredient"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("CreateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create ingredient"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"ingredient": createdIngredient})
+}
+
+func (h *IngredientHandler) GetIngredientByID(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("GetIngredientByID", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid ingredient id"})
+		return
+	}
+
+	ingredient, err := h.ingredientStore.GetIngredientByID(ingredientID)
+	if err != nil {
+		h.logger.Error("GetIngredientByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch ingredient"})
+		return
+	}
+	if ingredient == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"ingredient": ingredient})
+}
+
+// UpdateIngredient merges the given fields into the catalog entry. Sending
+// a density or piece weight of 0 clears it, and allergens, diets or aliases
+// replace the current lists. Recipes pick up changed flags on their next read.
+func (h *IngredientHandler) UpdateIngredient(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("UpdateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid ingredient id"})
+		return
+	}
+
+	existingIngredient, err := h.ingredientStore.GetIngredientByID(ingredientID)
+	if err != nil {
+		h.logger.Error("UpdateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch ingredient"})
+		return
+	}
+	if existingIngredient == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var ingredientUpdateRequest struct {
+		Name        *string  `json:"name"`
+		Category    *string  `json:"category"`
+		Density     *float64 `json:"density"`
+		PieceWeight *float64 `json:"pieceWeight"`
+		Allergens   []string `json:"allergens"`
+		Diets       []string `json:"diets"`
+		Aliases     []string `json:"aliases"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&ingredientUpdateRequest)
+	if err != nil {
+		h.logger.Error("UpdateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if ingredientUpdateRequest.Name != nil {
+		existingIngredient.Name = *ingredientUpdateRequest.Name
+	}
+	if ingredientUpdateRequest.Category != nil {
+		existingIngredient.Category = *ingredientUpdateRequest.Category
+	}
+	if ingredientUpdateRequest.Density != nil {
+		existingIngredient.Density = nilIfZero(*ingredientUpdateRequest.Density)
+	}
+	if ingredientUpdateRequest.PieceWeight != nil {
+		existingIngredient.PieceWeight = nilIfZero(*ingredientUpdateRequest.PieceWeight)
+	}
+	if ingredientUpdateRequest.Allergens != nil {
+		existingIngredient.Allergens = normalizeFlags(ingredientUpdateRequest.Allergens)
+	}
+	if ingredientUpdateRequest.Diets != nil {
+		existingIngredient.Diets = normalizeDiets(ingredientUpdateRequest.Diets)
+	}
+	if ingredientUpdateRequest.Aliases != nil {
+		existingIngredient.Aliases = ingredientUpdateRequest.Aliases
+	}
+	existingIngredient.Aliases = normalizeAliases(existingIngredient.Aliases, existingIngredient.Name)
+
+	if err := validateIngredient(existingIngredient); err != nil {
+		h.logger.Error("UpdateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	updatedIngredient, err := h.ingredientStore.UpdateIngredient(existingIngredient)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, store.ErrAliasTaken) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "alias is already used by another ingredient"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("UpdateIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to update ingredient"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"ingredient": updatedIngredient})
+}
+
+// MergeIngredients folds the duplicates in the body into the ingredient in
+// the url, which keeps their names as aliases.
+func (h *IngredientHandler) MergeIngredients(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("MergeIngredients", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid ingredient id"})
+		return
+	}
+
+	var mergeRequest struct {
+		DuplicateIDs []string `json:"duplicateIds"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&mergeRequest)
+	if err != nil {
+		h.logger.Error("MergeIngredients", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if err := validateMerge(ingredientID, mergeRequest.DuplicateIDs); err != nil {
+		h.logger.Error("MergeIngredients", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	mergedIngredient, err := h.ingredientStore.MergeIngredients(ingredientID, mergeRequest.DuplicateIDs)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("MergeIngredients", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to merge ingredients"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"ingredient": mergedIngredient})
+}
+
+func validateMerge(survivorID string, duplicateIDs []string) error {
+	if len(duplicateIDs) == 0 {
+		return errors.New("duplicate ids cannot be empty")
+	}
+
+	seen := map[string]bool{}
+	for _, id := range duplicateIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("invalid duplicate id")
+		}
+		if id == survivorID {
+			return errors.New("cannot merge an ingredient into itself")
+		}
+		if seen[id] {
+			return errors.New("duplicate ids must be unique")
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+func validateIngredient(i *model.CatalogIngredient) error {
+	if strings.TrimSpace(i.Name) == "" {
+		return errors.New("name cannot be blank")
+	}
+
+	if i.Density != nil && *i.Density <= 0 {
+		return errors.New("density must be greater than zero")
+	}
+
+	if i.PieceWeight != nil && *i.PieceWeight <= 0 {
+		return errors.New("piece weight must be greater than zero")
+	}
+
+	for _, a := range i.Allergens {
+		if !validAllergens[a] {
+			return fmt.Errorf("unknown allergen %q", a)
+		}
+	}
+
+	for _, d := range i.Diets {
+		if !validDiets[d] {
+			return fmt.Errorf("unknown diet %q", d)
+		}
+	}
+	return nil
+}
+
+// normalizeFlags lower-cases, de-duplicates and sorts allergen or diet
+// names.
+func normalizeFlags(values []string) []string {
+	seen := map[string]bool{}
+	flags := []string{}
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		flags = append(flags, v)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// normalizeDiets also marks vegan ingredients as vegetarian.
+func normalizeDiets(values []string) []string {
+	diets := normalizeFlags(values)
+	if slices.Contains(diets, "vegan") {
+		diets = normalizeFlags(append(diets, "vegetarian"))
+	}
+	return diets
+}
+
+// normalizeAliases trims and de-duplicates aliases, ignoring case, and
+// drops any that match the ingredient's own name.
+func normalizeAliases(values []string, name string) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(name)): true}
+	aliases := []string{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		aliases = append(aliases, v)
+	}
+	sort.Slice(aliases, func(a, b int) bool {
+		return strings.ToLower(aliases[a]) < strings.ToLower(aliases[b])
+	})
+	return aliases
+}
+
+func nilIfZero(f float64) *float64 {
+	if f == 0 {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,280 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockIngredientStore struct {
+	mock.Mock
+}
+
+func (m *MockIngredientStore) ListIngredients() ([]model.CatalogIngredient, error) {
+	args := m.Called()
+	return args.Get(0).([]model.CatalogIngredient), args.Error(1)
+}
+func (m *MockIngredientStore) GetIngredientByID(id string) (*model.CatalogIngredient, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogIngredient), args.Error(1)
+}
+func (m *MockIngredientStore) CreateIngredient(i *model.CatalogIngredient) (*model.CatalogIngredient, error) {
+	args := m.Called(i)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogIngredient), args.Error(1)
+}
+func (m *MockIngredientStore) UpdateIngredient(i *model.CatalogIngredient) (*model.CatalogIngredient, error) {
+	args := m.Called(i)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogIngredient), args.Error(1)
+}
+func (m *MockIngredientStore) FindIngredientByName(name string) (*model.CatalogIngredient, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogIngredient), args.Error(1)
+}
+func (m *MockIngredientStore) MergeIngredients(survivorID string, duplicateIDs []string) (*model.CatalogIngredient, error) {
+	args := m.Called(survivorID, duplicateIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogIngredient), args.Error(1)
+}
+
+// tests
+
+func TestIngredientHandler(t *testing.T) {
+	flourID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+	density := 0.53
+	flour := &model.CatalogIngredient{ID: flourID, Name: "Flour", Category: "baking", Density: &density}
+	scallionID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d91"
+	greenOnionID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d92"
+	scallion := &model.CatalogIngredient{ID: scallionID, Name: "Scallion", Aliases: []string{"green onion", "Spring onion"}}
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		setupMock func(*MockIngredientStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list ingredients",
+			method: http.MethodGet,
+			uri:    "/",
+			setupMock: func(m *MockIngredientStore) {
+				m.On("ListIngredients").Return([]model.CatalogIngredient{*flour}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"ingredients": []model.CatalogIngredient{*flour}, "total": 1},
+		},
+		{
+			name:   "create ingredient",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Flour", "category": "baking", "density": 0.53}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("CreateIngredient", mock.MatchedBy(func(i *model.CatalogIngredient) bool {
+					return i.ID != "" && *i.Density == density && i.PieceWeight == nil
+				})).Return(flour, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"ingredient": flour},
+		},
+		{
+			name:   "create vegan ingredient",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Tofu", "allergens": ["Soy", "soy"], "diets": ["vegan"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("CreateIngredient", mock.MatchedBy(func(i *model.CatalogIngredient) bool {
+					return assert.ObjectsAreEqual([]string{"soy"}, i.Allergens) &&
+						assert.ObjectsAreEqual([]string{"vegan", "vegetarian"}, i.Diets)
+				})).Return(&model.CatalogIngredient{ID: flourID, Name: "Tofu"}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"ingredient": &model.CatalogIngredient{ID: flourID, Name: "Tofu"}},
+		},
+		{
+			name:     "create ingredient with unknown diet",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"name": "Tofu", "diets": ["keto"]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": `unknown diet "keto"`},
+		},
+		{
+			name:     "create ingredient with negative piece weight",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"name": "Egg", "pieceWeight": -50}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "piece weight must be greater than zero"},
+		},
+		{
+			name:   "update ingredient clears density",
+			method: http.MethodPut,
+			uri:    "/" + flourID,
+			data:   strings.NewReader(`{"density": 0, "pieceWeight": 120}`),
+			setupMock: func(m *MockIngredientStore) {
+				existing := *flour
+				m.On("GetIngredientByID", flourID).Return(&existing, nil)
+				m.On("UpdateIngredient", mock.MatchedBy(func(i *model.CatalogIngredient) bool {
+					return i.Density == nil && *i.PieceWeight == 120
+				})).Return(&model.CatalogIngredient{ID: flourID, Name: "Flour"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"ingredient": &model.CatalogIngredient{ID: flourID, Name: "Flour"}},
+		},
+		{
+			name:   "find ingredient by alias",
+			method: http.MethodGet,
+			uri:    "/?name=green+onion",
+			setupMock: func(m *MockIngredientStore) {
+				m.On("FindIngredientByName", "green onion").Return(scallion, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"ingredients": []model.CatalogIngredient{*scallion}, "total": 1},
+		},
+		{
+			name:   "find unknown ingredient by name",
+			method: http.MethodGet,
+			uri:    "/?name=leek",
+			setupMock: func(m *MockIngredientStore) {
+				m.On("FindIngredientByName", "leek").Return(nil, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"ingredients": []model.CatalogIngredient{}, "total": 0},
+		},
+		{
+			name:   "create ingredient with aliases",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Scallion", "aliases": ["Spring onion", " green onion", "scallion", "Green Onion"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("CreateIngredient", mock.MatchedBy(func(i *model.CatalogIngredient) bool {
+					return assert.ObjectsAreEqual([]string{"green onion", "Spring onion"}, i.Aliases)
+				})).Return(scallion, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"ingredient": scallion},
+		},
+		{
+			name:   "create ingredient with alias in use",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Green Onion", "aliases": ["scallion"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("CreateIngredient", mock.AnythingOfType("*model.CatalogIngredient")).Return(nil, store.ErrAliasTaken)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "alias is already used by another ingredient"},
+		},
+		{
+			name:   "merge ingredients",
+			method: http.MethodPost,
+			uri:    "/" + scallionID + "/merge",
+			data:   strings.NewReader(`{"duplicateIds": ["` + greenOnionID + `"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("MergeIngredients", scallionID, []string{greenOnionID}).Return(scallion, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"ingredient": scallion},
+		},
+		{
+			name:     "merge ingredient into itself",
+			method:   http.MethodPost,
+			uri:      "/" + scallionID + "/merge",
+			data:     strings.NewReader(`{"duplicateIds": ["` + scallionID + `"]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "cannot merge an ingredient into itself"},
+		},
+		{
+			name:   "merge unknown duplicate",
+			method: http.MethodPost,
+			uri:    "/" + scallionID + "/merge",
+			data:   strings.NewReader(`{"duplicateIds": ["` + greenOnionID + `"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("MergeIngredients", scallionID, []string{greenOnionID}).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:   "merge into missing ingredient",
+			method: http.MethodPost,
+			uri:    "/" + scallionID + "/merge",
+			data:   strings.NewReader(`{"duplicateIds": ["` + greenOnionID + `"]}`),
+			setupMock: func(m *MockIngredientStore) {
+				m.On("MergeIngredients", scallionID, []string{greenOnionID}).Return(nil, sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "get missing ingredient",
+			method: http.MethodGet,
+			uri:    "/" + flourID,
+			setupMock: func(m *MockIngredientStore) {
+				m.On("GetIngredientByID", flourID).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			ingredientStore := &MockIngredientStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(ingredientStore)
+			}
+
+			h := handler.NewIngredientHandler(logger, ingredientStore)
+
+			r := chi.NewRouter()
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			ingredientStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/shopping"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+const dateLayout = "2006-01-02"
+
+var validMeals = map[string]bool{"": true, "breakfast": true, "lunch": true, "dinner": true, "snack": true}
+
+type MealPlanHandler struct {
+	logger        *slog.Logger
+	mealPlanStore store.MealPlanStore
+	recipeStore   store.RecipeStore
+	pantryStore   store.PantryStore
+	userStore     store.UserStore
+}
+
+func NewMealPlanHandler(l *slog.Logger, ms store.MealPlanStore, rs store.RecipeStore, ps store.PantryStore, us store.UserStore) *MealPlanHandler {
+	return &MealPlanHandler{
+		logger:        l,
+		mealPlanStore: ms,
+		recipeStore:   rs,
+		pantryStore:   ps,
+		userStore:     us,
+	}
+}
+
+// Routes manages the caller's plan, so every route needs a user token.
+func (h *MealPlanHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequireUserID)
+	r.Get("/", h.ListEntries)
+	r.Post("/", h.CreateEntry)
+	r.Get("/shopping-list", h.GetShoppingList)
+	r.Delete("/{id}", h.DeleteEntry)
+
+	return r
+}
+
+// ListEntries lists the caller's plan. Plans are not shared; households
+// are not supported yet.
+func (h *MealPlanHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	from, to, err := readDateRange(r)
+	if err != nil {
+		h.logger.Error("ListEntries", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.mealPlanStore.ListEntries(middleware.UserIDFromContext(r.Context()), from, to)
+	if err != nil {
+		h.logger.Error("ListEntries", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch meal plan"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"entries": entries, "total": len(entries)})
+}
+
+func (h *MealPlanHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	var entry model.MealPlanEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if err := validateMealPlanEntry(&entry); err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(entry.RecipeID)
+	if err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "recipe does not exist"})
+		return
+	}
+	entry.RecipeName = recipe.Name
+	if entry.Servings == 0 {
+		entry.Servings = recipe.Servings
+	}
+
+	entry.UserID = middleware.UserIDFromContext(r.Context())
+	user, err := h.userStore.GetUserByID(entry.UserID)
+	if err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+		return
+	}
+	if user == nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "user does not exist"})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	entry.ID = id
+
+	createdEntry, err := h.mealPlanStore.CreateEntry(&entry)
+	if err != nil {
+		h.logger.Error("CreateEntry", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create meal plan entry"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"entry": createdEntry})
+}
+
+func (h *MealPlanHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteEntry", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid meal plan entry id"})
+		return
+	}
+
+	err = h.mealPlanStore.DeleteEntry(entryID, middleware.UserIDFromContext(r.Context()))
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteEntry", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete meal plan entry"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MealPlanHandler) GetShoppingList(w http.ResponseWriter, r *http.Request) {
+	from, to, err := readDateRange(r)
+	if err != nil {
+		h.logger.Error("GetShoppingList", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.mealPlanStore.ListEntries(middleware.UserIDFromContext(r.Context()), from, to)
+	if err != nil {
+		h.logger.Error("GetShoppingList", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch meal plan"})
+		return
+	}
+
+	recipes := map[string]*model.Recipe{}
+	for _, e := range entries {
+		if _, ok := recipes[e.RecipeID]; ok {
+			continue
+		}
+		recipe, err := h.recipeStore.GetRecipeByID(e.RecipeID)
+		if err != nil {
+			h.logger.Error("GetShoppingList", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+			return
+		}
+		if recipe != nil {
+			// Shop for what the sub-recipes need.
+			recipe, err = subrecipe.Expand(recipe, h.recipeStore.GetRecipeByID)
+			if err != nil {
+				h.logger.Error("GetShoppingList", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+				return
+			}
+		}
+		recipes[e.RecipeID] = recipe
+	}
+
+	stock, err := h.pantryStore.ListStockedIngredients()
+	if err != nil {
+		h.logger.Error("GetShoppingList", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	items := shopping.Build(entries, recipes, stock)
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"from": from, "to": to, "items": items, "total": len(items)})
+}
+
+func readDateRange(r *http.Request) (string, string, error) {
+	q := r.URL.Query()
+
+	from, err := time.Parse(dateLayout, q.Get("from"))
+	if err != nil {
+		return "", "", errors.New("from must be a date in YYYY-MM-DD format")
+	}
+	to, err := time.Parse(dateLayout, q.Get("to"))
+	if err != nil {
+		return "", "", errors.New("to must be a date in YYYY-MM-DD format")
+	}
+	if to.Before(from) {
+		return "", "", errors.New("to cannot be before from")
+	}
+
+	return from.Format(dateLayout), to.Format(dateLayout), nil
+}
+
+func validateMealPlanEntry(e *model.MealPlanEntry) error {
+	if e.RecipeID == "" {
+		return errors.New("recipe id cannot be blank")
+	}
+
+	if _, err := time.Parse(dateLayout, e.Date); err != nil {
+		return errors.New("date must be in YYYY-MM-DD format")
+	}
+
+	if !validMeals[e.Meal] {
+		return errors.New("meal must be one of breakfast, lunch, dinner or snack")
+	}
+
+	if e.Servings < 0 {
+		return errors.New("servings cannot be a negative value")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockMealPlanStore struct {
+	mock.Mock
+}
+
+func (m *MockMealPlanStore) ListEntries(userID, from, to string) ([]model.MealPlanEntry, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]model.MealPlanEntry), args.Error(1)
+}
+func (m *MockMealPlanStore) CreateEntry(e *model.MealPlanEntry) (*model.MealPlanEntry, error) {
+	args := m.Called(e)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MealPlanEntry), args.Error(1)
+}
+func (m *MockMealPlanStore) DeleteEntry(id, userID string) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+// tests
+
+func TestMealPlanHandler(t *testing.T) {
+	pancakes := &model.Recipe{
+		ID:       "019a40de-02cd-7865-84ae-c038b75596f5",
+		Name:     "Classic Pancakes",
+		Servings: 4,
+		Ingredients: []model.Ingredient{
+			{ID: "i1", Name: "Flour", Quantity: 2, Unit: "cup"},
+			{ID: "i3", Name: "Eggs", Quantity: 2, Unit: ""},
+		},
+	}
+	entries := []model.MealPlanEntry{
+		{ID: "m1", RecipeID: pancakes.ID, RecipeName: pancakes.Name, Date: "2025-11-03", Meal: "breakfast", Servings: 8},
+	}
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		userID    string
+		setupMock func(*MockMealPlanStore, *MockRecipeStore, *MockPantryStore, *MockUserStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list entries",
+			method: http.MethodGet,
+			uri:    "/?from=2025-11-03&to=2025-11-09",
+			userID: userID,
+			setupMock: func(m *MockMealPlanStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockUserStore) {
+				m.On("ListEntries", userID, "2025-11-03", "2025-11-09").Return(entries, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"entries": entries, "total": 1},
+		},
+		{
+			name:     "list entries anonymously",
+			method:   http.MethodGet,
+			uri:      "/?from=2025-11-03&to=2025-11-09",
+			wantCode: http.StatusUnauthorized,
+			wantBody: util.Envelope{"error": "a user token is required"},
+		},
+		{
+			name:     "list entries with invalid range",
+			method:   http.MethodGet,
+			uri:      "/?from=2025-11-09&to=2025-11-03",
+			userID:   userID,
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "to cannot be before from"},
+		},
+		{
+			name:     "list entries with missing dates",
+			method:   http.MethodGet,
+			uri:      "/",
+			userID:   userID,
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "from must be a date in YYYY-MM-DD format"},
+		},
+		{
+			name:   "create entry defaults servings to recipe",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"recipeId": "019a40de-02cd-7865-84ae-c038b75596f5", "date": "2025-11-03", "meal": "breakfast"}`),
+			userID: userID,
+			setupMock: func(m *MockMealPlanStore, rs *MockRecipeStore, _ *MockPantryStore, us *MockUserStore) {
+				rs.On("GetRecipeByID", pancakes.ID).Return(pancakes, nil)
+				us.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+				m.On("CreateEntry", mock.MatchedBy(func(e *model.MealPlanEntry) bool {
+					return e.Servings == 4 && e.ID != "" && e.UserID == userID
+				})).Return(&model.MealPlanEntry{ID: "m1", RecipeID: pancakes.ID, UserID: userID, Date: "2025-11-03", Servings: 4}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"entry": &model.MealPlanEntry{ID: "m1", RecipeID: pancakes.ID, UserID: userID, Date: "2025-11-03", Servings: 4}},
+		},
+		{
+			name:   "create entry for unknown user",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"recipeId": "019a40de-02cd-7865-84ae-c038b75596f5", "date": "2025-11-03"}`),
+			userID: userID,
+			setupMock: func(_ *MockMealPlanStore, rs *MockRecipeStore, _ *MockPantryStore, us *MockUserStore) {
+				rs.On("GetRecipeByID", pancakes.ID).Return(pancakes, nil)
+				us.On("GetUserByID", userID).Return(nil, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "user does not exist"},
+		},
+		{
+			name:   "create entry with unknown recipe",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"recipeId": "019a40de-02cd-7865-84ae-c038b75596f5", "date": "2025-11-03"}`),
+			userID: userID,
+			setupMock: func(_ *MockMealPlanStore, rs *MockRecipeStore, _ *MockPantryStore, _ *MockUserStore) {
+				rs.On("GetRecipeByID", pancakes.ID).Return(nil, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "recipe does not exist"},
+		},
+		{
+			name:     "create entry with invalid meal",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"recipeId": "r1", "date": "2025-11-03", "meal": "brunch"}`),
+			userID:   userID,
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "meal must be one of breakfast, lunch, dinner or snack"},
+		},
+		{
+			name:   "delete entry with error",
+			method: http.MethodDelete,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			userID: userID,
+			setupMock: func(m *MockMealPlanStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockUserStore) {
+				m.On("DeleteEntry", "019a40de-02cd-7865-84ae-c038b75596f5", userID).Return(errors.New("database error"))
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: util.Envelope{"error": "failed to delete meal plan entry"},
+		},
+		{
+			name:   "shopping list",
+			method: http.MethodGet,
+			uri:    "/shopping-list?from=2025-11-03&to=2025-11-09",
+			userID: userID,
+			setupMock: func(m *MockMealPlanStore, rs *MockRecipeStore, ps *MockPantryStore, _ *MockUserStore) {
+				m.On("ListEntries", userID, "2025-11-03", "2025-11-09").Return(entries, nil)
+				rs.On("GetRecipeByID", pancakes.ID).Return(pancakes, nil)
+				ps.On("ListStockedIngredients").Return([]model.StockedIngredient{
+					{IngredientID: "i3", Quantity: 1, Unit: "dozen"},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"from": "2025-11-03",
+				"to":   "2025-11-09",
+				"items": []model.ShoppingListItem{
+					{IngredientID: "i1", Name: "Flour", Quantity: 4, Unit: "cup", Needed: 4, RecipeIDs: []string{pancakes.ID}},
+				},
+				"total": 1,
+			},
+		},
+		{
+			name:   "shopping list with pantry error",
+			method: http.MethodGet,
+			uri:    "/shopping-list?from=2025-11-03&to=2025-11-09",
+			userID: userID,
+			setupMock: func(m *MockMealPlanStore, rs *MockRecipeStore, ps *MockPantryStore, _ *MockUserStore) {
+				m.On("ListEntries", userID, "2025-11-03", "2025-11-09").Return(entries, nil)
+				rs.On("GetRecipeByID", pancakes.ID).Return(pancakes, nil)
+				ps.On("ListStockedIngredients").Return([]model.StockedIngredient{}, errors.New("database error"))
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: util.Envelope{"error": "failed to fetch pantry"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			mealPlanStore := &MockMealPlanStore{}
+			recipeStore := &MockRecipeStore{}
+			pantryStore := &MockPantryStore{}
+			userStore := &MockUserStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(mealPlanStore, recipeStore, pantryStore, userStore)
+			}
+
+			h := handler.NewMealPlanHandler(logger, mealPlanStore, recipeStore, pantryStore, userStore)
+
+			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			mealPlanStore.AssertExpectations(t)
+			recipeStore.AssertExpectations(t)
+			pantryStore.AssertExpectations(t)
+			userStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+const defaultExpiringDays = 7
+
+var validLocations = map[string]bool{"": true, "fridge": true, "freezer": true, "pantry": true}
+
+type PantryHandler struct {
+	logger      *slog.Logger
+	pantryStore store.PantryStore
+	recipeStore store.RecipeStore
+	userStore   store.UserStore
+}
+
+func NewPantryHandler(l *slog.Logger, ps store.PantryStore, rs store.RecipeStore, us store.UserStore) *PantryHandler {
+	return &PantryHandler{
+		logger:      l,
+		pantryStore: ps,
+		recipeStore: rs,
+		userStore:   us,
+	}
+}
+
+func (h *PantryHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListStockedIngredients)
+	r.Post("/", h.CreateStockedIngredient)
+	r.Get("/expiring", h.ListExpiringIngredients)
+	r.Get("/suggestions", h.SuggestRecipes)
+	r.Get("/low-stock", h.ListLowStock)
+
+	r.Route("/thresholds", func(r chi.Router) {
+		r.Get("/", h.ListThresholds)
+		r.Put("/{id}", h.SetThreshold)
+		r.Delete("/{id}", h.DeleteThreshold)
+	})
+
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", h.GetStockedIngredientByID)
+		r.Put("/", h.UpdateStockedIngredient)
+		r.Delete("/", h.DeleteStockedIngredient)
+	})
+
+	return r
+}
+
+func (h *PantryHandler) ListStockedIngredients(w http.ResponseWriter, r *http.Request) {
+	stock, err := h.pantryStore.ListStockedIngredients()
+	if err != nil {
+		h.logger.Error("ListStockedIngredients", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"items": stock, "total": len(stock)})
+}
+
+func (h *PantryHandler) CreateStockedIngredient(w http.ResponseWriter, r *http.Request) {
+	var item model.StockedIngredient
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		h.logger.Error("CreateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if item.IngredientID == "" {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient id cannot be blank"})
+		return
+	}
+	if err := validateStockedIngredient(&item); err != nil {
+		h.logger.Error("CreateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	item.ID = id
+
+	createdItem, err := h.pantryStore.CreateStockedIngredient(&item)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("CreateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create pantry item"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"item": createdItem})
+}
+
+func (h *PantryHandler) GetStockedIngredientByID(w http.ResponseWriter, r *http.Request) {
+	itemID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("GetStockedIngredientByID", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid pantry item id"})
+		return
+	}
+
+	item, err := h.pantryStore.GetStockedIngredientByID(itemID)
+	if err != nil {
+		h.logger.Error("GetStockedIngredientByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry item"})
+		return
+	}
+	if item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"item": item})
+}
+
+// UpdateStockedIngredient merges the given fields into the pantry item.
+// Sending an empty bestBefore or openedOn clears the date.
+func (h *PantryHandler) UpdateStockedIngredient(w http.ResponseWriter, r *http.Request) {
+	itemID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("UpdateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid pantry item id"})
+		return
+	}
+
+	existingItem, err := h.pantryStore.GetStockedIngredientByID(itemID)
+	if err != nil {
+		h.logger.Error("UpdateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry item"})
+		return
+	}
+	if existingItem == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var itemUpdateRequest struct {
+		Quantity   *float64 `json:"quantity"`
+		Unit       *string  `json:"unit"`
+		Note       *string  `json:"note"`
+		BestBefore *string  `json:"bestBefore"`
+		OpenedOn   *string  `json:"openedOn"`
+		Location   *string  `json:"location"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&itemUpdateRequest)
+	if err != nil {
+		h.logger.Error("UpdateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if itemUpdateRequest.Quantity != nil {
+		existingItem.Quantity = *itemUpdateRequest.Quantity
+	}
+	if itemUpdateRequest.Unit != nil {
+		existingItem.Unit = *itemUpdateRequest.Unit
+	}
+	if itemUpdateRequest.Note != nil {
+		existingItem.Note = *itemUpdateRequest.Note
+	}
+	if itemUpdateRequest.BestBefore != nil {
+		existingItem.BestBefore = nilIfEmpty(*itemUpdateRequest.BestBefore)
+	}
+	if itemUpdateRequest.OpenedOn != nil {
+		existingItem.OpenedOn = nilIfEmpty(*itemUpdateRequest.OpenedOn)
+	}
+	if itemUpdateRequest.Location != nil {
+		existingItem.Location = *itemUpdateRequest.Location
+	}
+
+	if err := validateStockedIngredient(existingItem); err != nil {
+		h.logger.Error("UpdateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	updatedItem, err := h.pantryStore.UpdateStockedIngredient(existingItem)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("UpdateStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to update pantry item"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"item": updatedItem})
+}
+
+func (h *PantryHandler) DeleteStockedIngredient(w http.ResponseWriter, r *http.Request) {
+	itemID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid pantry item id"})
+		return
+	}
+
+	err = h.pantryStore.DeleteStockedIngredient(itemID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteStockedIngredient", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete pantry item"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListExpiringIngredients lists stock with a best-before date within the
+// next `days` days, including stock that has already expired.
+func (h *PantryHandler) ListExpiringIngredients(w http.ResponseWriter, r *http.Request) {
+	before, err := readExpiringBefore(r)
+	if err != nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	stock, err := h.pantryStore.ListExpiringIngredients(before)
+	if err != nil {
+		h.logger.Error("ListExpiringIngredients", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"items": stock, "total": len(stock)})
+}
+
+func (h *PantryHandler) SuggestRecipes(w http.ResponseWriter, r *http.Request) {
+	before, err := readExpiringBefore(r)
+	if err != nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	stock, err := h.pantryStore.ListExpiringIngredients(before)
+	if err != nil {
+		h.logger.Error("SuggestRecipes", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	applyProfile, err := readApplyProfile(r)
+	if err != nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	var filter store.RecipeFilter
+	if applyProfile {
+		profile, err := readDietaryProfile(r, h.userStore)
+		if err != nil {
+			h.logger.Error("SuggestRecipes", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch dietary profile"})
+			return
+		}
+		applyDietaryProfile(&filter, profile)
+	}
+
+	recipes, err := h.recipeStore.ListRecipes(filter)
+	if err != nil {
+		h.logger.Error("SuggestRecipes", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipes"})
+		return
+	}
+
+	for n := range recipes {
+		expanded, err := subrecipe.Expand(&recipes[n], h.recipeStore.GetRecipeByID)
+		if err != nil {
+			h.logger.Error("SuggestRecipes", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+			return
+		}
+		recipes[n] = *expanded
+	}
+
+	suggestions := pantry.SuggestRecipes(recipes, stock)
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"suggestions": suggestions, "total": len(suggestions)})
+}
+
+func (h *PantryHandler) ListThresholds(w http.ResponseWriter, r *http.Request) {
+	thresholds, err := h.pantryStore.ListThresholds()
+	if err != nil {
+		h.logger.Error("ListThresholds", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch thresholds"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"thresholds": thresholds, "total": len(thresholds)})
+}
+
+// SetThreshold creates or replaces the minimum quantity to keep of the
+// ingredient in the url.
+func (h *PantryHandler) SetThreshold(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("SetThreshold", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid ingredient id"})
+		return
+	}
+
+	var threshold model.StockThreshold
+	err = json.NewDecoder(r.Body).Decode(&threshold)
+	if err != nil {
+		h.logger.Error("SetThreshold", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+	threshold.IngredientID = ingredientID
+
+	if threshold.MinQuantity <= 0 {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "min quantity must be greater than zero"})
+		return
+	}
+
+	savedThreshold, err := h.pantryStore.SetThreshold(&threshold)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("SetThreshold", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to save threshold"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"threshold": savedThreshold})
+}
+
+func (h *PantryHandler) DeleteThreshold(w http.ResponseWriter, r *http.Request) {
+	ingredientID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteThreshold", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid ingredient id"})
+		return
+	}
+
+	err = h.pantryStore.DeleteThreshold(ingredientID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteThreshold", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete threshold"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PantryHandler) ListLowStock(w http.ResponseWriter, r *http.Request) {
+	thresholds, err := h.pantryStore.ListThresholds()
+	if err != nil {
+		h.logger.Error("ListLowStock", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch thresholds"})
+		return
+	}
+
+	stock, err := h.pantryStore.ListStockedIngredients()
+	if err != nil {
+		h.logger.Error("ListLowStock", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	items := pantry.LowStock(thresholds, stock)
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"items": items, "total": len(items)})
+}
+
+func readExpiringBefore(r *http.Request) (string, error) {
+	days := defaultExpiringDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 {
+			return "", errors.New("days must be a non-negative integer")
+		}
+		days = d
+	}
+
+	return time.Now().AddDate(0, 0, days).Format(dateLayout), nil
+}
+
+func validateStockedIngredient(si *model.StockedIngredient) error {
+	if si.Quantity < 0 {
+		return errors.New("quantity cannot be a negative value")
+	}
+
+	if si.BestBefore != nil {
+		if _, err := time.Parse(dateLayout, *si.BestBefore); err != nil {
+			return errors.New("best before must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if si.OpenedOn != nil {
+		if _, err := time.Parse(dateLayout, *si.OpenedOn); err != nil {
+			return errors.New("opened on must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if !validLocations[si.Location] {
+		return errors.New("location must be one of fridge, freezer or pantry")
+	}
+	return nil
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,287 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockPantryStore struct {
+	mock.Mock
+}
+
+func (m *MockPantryStore) ListStockedIngredients() ([]model.StockedIngredient, error) {
+	args := m.Called()
+	return args.Get(0).([]model.StockedIngredient), args.Error(1)
+}
+func (m *MockPantryStore) ListExpiringIngredients(before string) ([]model.StockedIngredient, error) {
+	args := m.Called(before)
+	return args.Get(0).([]model.StockedIngredient), args.Error(1)
+}
+func (m *MockPantryStore) GetStockedIngredientByID(id string) (*model.StockedIngredient, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StockedIngredient), args.Error(1)
+}
+func (m *MockPantryStore) CreateStockedIngredient(si *model.StockedIngredient) (*model.StockedIngredient, error) {
+	args := m.Called(si)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StockedIngredient), args.Error(1)
+}
+func (m *MockPantryStore) UpdateStockedIngredient(si *model.StockedIngredient) (*model.StockedIngredient, error) {
+	args := m.Called(si)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StockedIngredient), args.Error(1)
+}
+func (m *MockPantryStore) DeleteStockedIngredient(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockPantryStore) ListThresholds() ([]model.StockThreshold, error) {
+	args := m.Called()
+	return args.Get(0).([]model.StockThreshold), args.Error(1)
+}
+func (m *MockPantryStore) SetThreshold(st *model.StockThreshold) (*model.StockThreshold, error) {
+	args := m.Called(st)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StockThreshold), args.Error(1)
+}
+func (m *MockPantryStore) DeleteThreshold(ingredientID string) error {
+	args := m.Called(ingredientID)
+	return args.Error(0)
+} // tests
+
+func TestPantryHandler(t *testing.T) {
+	itemID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	milkID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+	bestBefore := "2025-11-05"
+	milk := &model.StockedIngredient{ID: itemID, IngredientID: "milk", Name: "Milk", Quantity: 1, Unit: "l", BestBefore: &bestBefore, Location: "fridge"}
+	inAWeek := time.Now().AddDate(0, 0, 7).Format("2006-01-02")
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		userID    string
+		setupMock func(*MockPantryStore, *MockRecipeStore, *MockUserStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "create item",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"ingredientId": "milk", "quantity": 1, "unit": "l", "bestBefore": "2025-11-05", "location": "fridge"}`),
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("CreateStockedIngredient", mock.MatchedBy(func(si *model.StockedIngredient) bool {
+					return si.ID != "" && *si.BestBefore == bestBefore && si.Location == "fridge"
+				})).Return(milk, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"item": milk},
+		},
+		{
+			name:   "create item with unknown ingredient",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"ingredientId": "nope", "quantity": 1, "unit": "l"}`),
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("CreateStockedIngredient", mock.AnythingOfType("*model.StockedIngredient")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:     "create item with invalid location",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"ingredientId": "milk", "quantity": 1, "unit": "l", "location": "garage"}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "location must be one of fridge, freezer or pantry"},
+		},
+		{
+			name:     "create item with invalid best before",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"ingredientId": "milk", "quantity": 1, "unit": "l", "bestBefore": "soon"}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "best before must be a date in YYYY-MM-DD format"},
+		},
+		{
+			name:   "update item clears best before",
+			method: http.MethodPut,
+			uri:    "/" + itemID,
+			data:   strings.NewReader(`{"bestBefore": "", "location": "freezer"}`),
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				existing := *milk
+				m.On("GetStockedIngredientByID", itemID).Return(&existing, nil)
+				m.On("UpdateStockedIngredient", mock.MatchedBy(func(si *model.StockedIngredient) bool {
+					return si.BestBefore == nil && si.Location == "freezer"
+				})).Return(&model.StockedIngredient{ID: itemID, Location: "freezer"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"item": &model.StockedIngredient{ID: itemID, Location: "freezer"}},
+		},
+		{
+			name:   "list expiring items",
+			method: http.MethodGet,
+			uri:    "/expiring",
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("ListExpiringIngredients", inAWeek).Return([]model.StockedIngredient{*milk}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"items": []model.StockedIngredient{*milk}, "total": 1},
+		},
+		{
+			name:     "list expiring items with invalid days",
+			method:   http.MethodGet,
+			uri:      "/expiring?days=-1",
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "days must be a non-negative integer"},
+		},
+		{
+			name:   "suggest recipes",
+			method: http.MethodGet,
+			uri:    "/suggestions?days=3",
+			setupMock: func(m *MockPantryStore, rs *MockRecipeStore, _ *MockUserStore) {
+				m.On("ListExpiringIngredients", time.Now().AddDate(0, 0, 3).Format("2006-01-02")).Return([]model.StockedIngredient{*milk}, nil)
+				rs.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"suggestions": []model.RecipeSuggestion{}, "total": 0},
+		},
+		{
+			name:   "suggest recipes with dietary profile",
+			method: http.MethodGet,
+			uri:    "/suggestions?days=3",
+			userID: userID,
+			setupMock: func(m *MockPantryStore, rs *MockRecipeStore, us *MockUserStore) {
+				m.On("ListExpiringIngredients", time.Now().AddDate(0, 0, 3).Format("2006-01-02")).Return([]model.StockedIngredient{*milk}, nil)
+				us.On("GetDietaryProfile", userID).Return(&model.DietaryProfile{UserID: userID, Diets: []string{"vegan"}}, nil)
+				rs.On("ListRecipes", store.RecipeFilter{Diets: []string{"vegan"}}).Return([]model.Recipe{}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"suggestions": []model.RecipeSuggestion{}, "total": 0},
+		},
+		{
+			name:   "set threshold",
+			method: http.MethodPut,
+			uri:    "/thresholds/" + milkID,
+			data:   strings.NewReader(`{"minQuantity": 2, "unit": "l"}`),
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("SetThreshold", &model.StockThreshold{IngredientID: milkID, MinQuantity: 2, Unit: "l"}).
+					Return(&model.StockThreshold{IngredientID: "milk", Name: "Milk", MinQuantity: 2, Unit: "l"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"threshold": &model.StockThreshold{IngredientID: "milk", Name: "Milk", MinQuantity: 2, Unit: "l"}},
+		},
+		{
+			name:     "set threshold with zero quantity",
+			method:   http.MethodPut,
+			uri:      "/thresholds/" + milkID,
+			data:     strings.NewReader(`{"minQuantity": 0, "unit": "l"}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "min quantity must be greater than zero"},
+		},
+		{
+			name:   "set threshold with unknown ingredient",
+			method: http.MethodPut,
+			uri:    "/thresholds/" + itemID,
+			data:   strings.NewReader(`{"minQuantity": 1, "unit": "l"}`),
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("SetThreshold", mock.AnythingOfType("*model.StockThreshold")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:   "delete missing threshold",
+			method: http.MethodDelete,
+			uri:    "/thresholds/" + milkID,
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("DeleteThreshold", milkID).Return(sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "list low stock",
+			method: http.MethodGet,
+			uri:    "/low-stock",
+			setupMock: func(m *MockPantryStore, _ *MockRecipeStore, _ *MockUserStore) {
+				m.On("ListThresholds").Return([]model.StockThreshold{{IngredientID: "milk", Name: "Milk", MinQuantity: 2, Unit: "l"}}, nil)
+				m.On("ListStockedIngredients").Return([]model.StockedIngredient{*milk}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"items": []model.LowStockItem{{IngredientID: "milk", Name: "Milk", MinQuantity: 2, InStock: 1, Shortfall: 1, Unit: "l"}},
+				"total": 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			pantryStore := &MockPantryStore{}
+			recipeStore := &MockRecipeStore{}
+			userStore := &MockUserStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(pantryStore, recipeStore, userStore)
+			}
+
+			h := handler.NewPantryHandler(logger, pantryStore, recipeStore, userStore)
+
+			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			pantryStore.AssertExpectations(t)
+			recipeStore.AssertExpectations(t)
+			userStore.AssertExpectations(t)
+		})
+	}
+}
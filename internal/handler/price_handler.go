@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+type PriceHandler struct {
+	logger     *slog.Logger
+	priceStore store.PriceStore
+}
+
+func NewPriceHandler(l *slog.Logger, ps store.PriceStore) *PriceHandler {
+	return &PriceHandler{
+		logger:     l,
+		priceStore: ps,
+	}
+}
+
+func (h *PriceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListPrices)
+	r.Post("/", h.CreatePrice)
+	r.Delete("/{id}", h.DeletePrice)
+
+	return r
+}
+
+func (h *PriceHandler) ListPrices(w http.ResponseWriter, r *http.Request) {
+	ingredientID := r.URL.Query().Get("ingredientId")
+	if ingredientID != "" {
+		if _, err := uuid.Parse(ingredientID); err != nil {
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid id filter"})
+			return
+		}
+	}
+
+	prices, err := h.priceStore.ListPrices(ingredientID)
+	if err != nil {
+		h.logger.Error("ListPrices", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch prices"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"prices": prices, "total": len(prices)})
+}
+
+// CreatePrice records what an amount of an ingredient cost. Priced-on
+// defaults to today.
+func (h *PriceHandler) CreatePrice(w http.ResponseWriter, r *http.Request) {
+	var price model.IngredientPrice
+	err := json.NewDecoder(r.Body).Decode(&price)
+	if err != nil {
+		h.logger.Error("CreatePrice", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if price.PricedOn == "" {
+		price.PricedOn = time.Now().Format(dateLayout)
+	}
+	if err := validatePrice(&price); err != nil {
+		h.logger.Error("CreatePrice", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreatePrice", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	price.ID = id
+
+	createdPrice, err := h.priceStore.CreatePrice(&price)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("CreatePrice", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create price"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"price": createdPrice})
+}
+
+func (h *PriceHandler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	priceID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeletePrice", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid price id"})
+		return
+	}
+
+	err = h.priceStore.DeletePrice(priceID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeletePrice", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete price"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validatePrice(p *model.IngredientPrice) error {
+	if p.IngredientID == "" {
+		return errors.New("ingredient id cannot be blank")
+	}
+
+	if p.Amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if p.Price < 0 {
+		return errors.New("price cannot be a negative value")
+	}
+
+	if _, err := time.Parse(dateLayout, p.PricedOn); err != nil {
+		return errors.New("priced on must be a date in YYYY-MM-DD format")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockPriceStore struct {
+	mock.Mock
+}
+
+func (m *MockPriceStore) ListPrices(ingredientID string) ([]model.IngredientPrice, error) {
+	args := m.Called(ingredientID)
+	return args.Get(0).([]model.IngredientPrice), args.Error(1)
+}
+func (m *MockPriceStore) GetPriceByID(id string) (*model.IngredientPrice, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IngredientPrice), args.Error(1)
+}
+func (m *MockPriceStore) CreatePrice(p *model.IngredientPrice) (*model.IngredientPrice, error) {
+	args := m.Called(p)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IngredientPrice), args.Error(1)
+}
+func (m *MockPriceStore) DeletePrice(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// tests
+
+func TestPriceHandler(t *testing.T) {
+	priceID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	flourID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+	flour := &model.IngredientPrice{ID: priceID, IngredientID: flourID, Name: "Flour", Amount: 1, Unit: "kg", Price: 2.5, Store: "Market", PricedOn: "2025-10-01"}
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		setupMock func(*MockPriceStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list prices for ingredient",
+			method: http.MethodGet,
+			uri:    "/?ingredientId=" + flourID,
+			setupMock: func(m *MockPriceStore) {
+				m.On("ListPrices", flourID).Return([]model.IngredientPrice{*flour}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"prices": []model.IngredientPrice{*flour}, "total": 1},
+		},
+		{
+			name:     "list prices with invalid ingredient",
+			method:   http.MethodGet,
+			uri:      "/?ingredientId=flour",
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "invalid id filter"},
+		},
+		{
+			name:   "create price defaults to today",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"ingredientId": "` + flourID + `", "amount": 1, "unit": "kg", "price": 2.5, "store": "Market"}`),
+			setupMock: func(m *MockPriceStore) {
+				m.On("CreatePrice", mock.MatchedBy(func(p *model.IngredientPrice) bool {
+					return p.ID != "" && p.PricedOn == time.Now().Format("2006-01-02")
+				})).Return(flour, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"price": flour},
+		},
+		{
+			name:     "create price with zero amount",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"ingredientId": "` + flourID + `", "amount": 0, "unit": "kg", "price": 2.5}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "amount must be greater than zero"},
+		},
+		{
+			name:   "create price with unknown ingredient",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"ingredientId": "` + flourID + `", "amount": 1, "unit": "kg", "price": 2.5}`),
+			setupMock: func(m *MockPriceStore) {
+				m.On("CreatePrice", mock.AnythingOfType("*model.IngredientPrice")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:   "delete missing price",
+			method: http.MethodDelete,
+			uri:    "/" + priceID,
+			setupMock: func(m *MockPriceStore) {
+				m.On("DeletePrice", priceID).Return(sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			priceStore := &MockPriceStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(priceStore)
+			}
+
+			h := handler.NewPriceHandler(logger, priceStore)
+
+			r := chi.NewRouter()
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			priceStore.AssertExpectations(t)
+		})
+	}
+}
@@ -1,29 +1,54 @@
 package handler
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/gosimple/slug"
+	"github.com/stevmwhitfield/recipe-api/internal/cost"
+	"github.com/stevmwhitfield/recipe-api/internal/dietary"
+	"github.com/stevmwhitfield/recipe-api/internal/jsonpatch"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
 	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/nutrition"
+	"github.com/stevmwhitfield/recipe-api/internal/recipediff"
 	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
 	"github.com/stevmwhitfield/recipe-api/internal/util"
 )
 
 type RecipeHandler struct {
-	logger      *slog.Logger
-	recipeStore store.RecipeStore
+	logger         *slog.Logger
+	recipeStore    store.RecipeStore
+	priceStore     store.PriceStore
+	nutritionStore store.NutritionStore
+	userStore      store.UserStore
+
+	// substitutionHandler serves substitution suggestions under a recipe.
+	substitutionHandler *SubstitutionHandler
 }
 
-func NewRecipeHandler(l *slog.Logger, rs store.RecipeStore) *RecipeHandler {
+func NewRecipeHandler(l *slog.Logger, rs store.RecipeStore, ps store.PriceStore, ns store.NutritionStore, us store.UserStore, sh *SubstitutionHandler) *RecipeHandler {
 	return &RecipeHandler{
-		logger:      l,
-		recipeStore: rs,
+		logger:              l,
+		recipeStore:         rs,
+		priceStore:          ps,
+		nutritionStore:      ns,
+		userStore:           us,
+		substitutionHandler: sh,
 	}
 }
 
@@ -33,24 +58,108 @@ func (h *RecipeHandler) Routes() chi.Router {
 	r.Get("/", h.ListRecipes)
 	r.Post("/", h.CreateRecipe)
 
+	r.Get("/sync", h.SyncRecipes)
+	r.Get("/trash", h.ListTrash)
+	r.Post("/trash/{id}/restore", h.RestoreRecipe)
+	r.Delete("/trash/{id}", h.PurgeRecipe)
+
 	r.Route("/{id}", func(r chi.Router) {
 		r.Get("/", h.GetRecipeByID)
 		r.Put("/", h.UpdateRecipe)
+		r.Patch("/", h.PatchRecipe)
 		r.Delete("/", h.DeleteRecipe)
+		r.Post("/fork", h.ForkRecipe)
+		r.Get("/diff", h.DiffRecipe)
+		r.Get("/revisions", h.ListRevisions)
+		r.Get("/revisions/diff", h.DiffRevisions)
+		r.Get("/revisions/{number}", h.GetRevision)
+		r.Post("/revisions/{number}/restore", h.RestoreRevision)
+		r.Get("/substitutions", h.substitutionHandler.SuggestSubstitutions)
 	})
 
 	return r
 }
 
+// ListRecipes supports maxCostPerServing, which keeps only recipes whose
+// estimated cost per serving is known for every ingredient and within the
+// limit. The caller's dietary profile is applied unless applyProfile=false.
+// The response supports conditional requests; its ETag covers the whole
+// result set and its Last-Modified is the newest recipe's.
 func (h *RecipeHandler) ListRecipes(w http.ResponseWriter, r *http.Request) {
-	recipes, err := h.recipeStore.ListRecipes()
+	filter, err := readRecipeFilter(r)
+	if err != nil {
+		h.logger.Error("ListRecipes", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	applyProfile, err := readApplyProfile(r)
+	if err != nil {
+		h.logger.Error("ListRecipes", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	if applyProfile {
+		profile, err := readDietaryProfile(r, h.userStore)
+		if err != nil {
+			h.logger.Error("ListRecipes", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch dietary profile"})
+			return
+		}
+		applyDietaryProfile(&filter, profile)
+	}
+
+	maxCostPerServing, err := readMaxCostPerServing(r)
+	if err != nil {
+		h.logger.Error("ListRecipes", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	recipes, err := h.recipeStore.ListRecipes(filter)
 	if err != nil {
 		h.logger.Error("ListRecipes", "error", err)
 		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipes"})
 		return
 	}
 
-	util.WriteJSON(w, http.StatusOK, util.Envelope{"recipes": recipes, "total": len(recipes)})
+	if maxCostPerServing != nil {
+		prices, err := h.priceStore.ListPrices("")
+		if err != nil {
+			h.logger.Error("ListRecipes", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch prices"})
+			return
+		}
+
+		affordable := []model.Recipe{}
+		for _, recipe := range recipes {
+			expanded, err := subrecipe.Expand(&recipe, h.recipeStore.GetRecipeByID)
+			if err != nil {
+				h.logger.Error("ListRecipes", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+				return
+			}
+			estimate := cost.Estimate(expanded, prices)
+			if estimate.Complete && estimate.PerServing <= *maxCostPerServing {
+				affordable = append(affordable, recipe)
+			}
+		}
+		recipes = affordable
+	}
+
+	var lastModified time.Time
+	for _, recipe := range recipes {
+		if recipe.UpdatedAt.After(lastModified) {
+			lastModified = recipe.UpdatedAt
+		}
+	}
+
+	w.Header().Add("Vary", middleware.AuthorizationHeader)
+	util.WriteJSONConditional(w, r, util.Envelope{"recipes": recipes, "total": len(recipes)}, util.CacheOptions{
+		CacheControl: recipeCacheControl,
+		LastModified: lastModified,
+	})
 }
 
 func (h *RecipeHandler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
@@ -62,12 +171,32 @@ func (h *RecipeHandler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(recipe.Sections) > 0 {
+		if len(recipe.Ingredients) > 0 || len(recipe.Instructions) > 0 {
+			h.logger.Error("CreateRecipe", "error", errMixedSections)
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": errMixedSections.Error()})
+			return
+		}
+		if err := flattenSections(&recipe); err != nil {
+			h.logger.Error("CreateRecipe", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+			return
+		}
+	}
+
 	if err := validateRecipe(&recipe); err != nil {
 		h.logger.Error("CreateRecipe", "error", err)
 		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
 		return
 	}
 
+	// Expanding catches unknown sub-recipes and cycles.
+	if _, err := subrecipe.Expand(&recipe, h.recipeStore.GetRecipeByID); err != nil {
+		h.logger.Error("CreateRecipe", "error", err)
+		h.writeSubRecipeError(w, &recipe, err)
+		return
+	}
+
 	id, err := uuid.NewV7()
 	if err != nil {
 		h.logger.Error("CreateRecipe", "error", err)
@@ -76,18 +205,36 @@ func (h *RecipeHandler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
 	}
 	recipe.ID = id.String()
 
+	if err := assignItemIDs(&recipe, nil); err != nil {
+		h.logger.Error("CreateRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+
 	recipe.Slug = slug.Make(recipe.Name)
 
 	createdRecipe, err := h.recipeStore.CreateRecipe(&recipe)
+	var refErr *store.ReferenceError
+	if errors.As(err, &refErr) {
+		writeReferenceError(w, refErr.Missing)
+		return
+	}
 	if err != nil {
 		h.logger.Error("CreateRecipe", "error", err)
 		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create recipe"})
 		return
 	}
 
+	w.Header().Set("ETag", recipeETag(createdRecipe.Version))
 	util.WriteJSON(w, http.StatusCreated, util.Envelope{"recipe": createdRecipe})
 }
 
+// GetRecipeByID adds extra sections to the response when asked for with
+// include, a comma-separated list of cost and nutrition. Identified callers
+// also get warnings for conflicts with their dietary profile. The ETag
+// starts with the recipe's version and hashes the rest of the response for
+// If-None-Match. If-Match takes the tag of the version alone, as writes
+// return it.
 func (h *RecipeHandler) GetRecipeByID(w http.ResponseWriter, r *http.Request) {
 	recipeID, err := util.ReadIDParam(r)
 	if err != nil {
@@ -107,7 +254,53 @@ func (h *RecipeHandler) GetRecipeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	util.WriteJSON(w, http.StatusOK, util.Envelope{"recipe": recipe})
+	// Cost, nutrition and warnings look through sub-recipes.
+	expanded, err := subrecipe.Expand(recipe, h.recipeStore.GetRecipeByID)
+	if err != nil {
+		h.logger.Error("GetRecipeByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+		return
+	}
+
+	response := util.Envelope{"recipe": recipe}
+	include := readIncludes(r)
+
+	if include["cost"] {
+		prices, err := h.priceStore.ListPrices("")
+		if err != nil {
+			h.logger.Error("GetRecipeByID", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch prices"})
+			return
+		}
+		response["cost"] = cost.Estimate(expanded, prices)
+	}
+
+	if include["nutrition"] {
+		data, err := h.nutritionStore.ListNutrition()
+		if err != nil {
+			h.logger.Error("GetRecipeByID", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch nutrition"})
+			return
+		}
+		response["nutrition"] = nutrition.Compute(expanded, data)
+	}
+
+	profile, err := readDietaryProfile(r, h.userStore)
+	if err != nil {
+		h.logger.Error("GetRecipeByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch dietary profile"})
+		return
+	}
+	if profile != nil {
+		response["warnings"] = dietary.Warnings(expanded, profile)
+	}
+
+	w.Header().Add("Vary", middleware.AuthorizationHeader)
+	util.WriteJSONConditional(w, r, response, util.CacheOptions{
+		CacheControl: recipeCacheControl,
+		LastModified: recipe.UpdatedAt,
+		ETagPrefix:   strconv.Itoa(recipe.Version) + "-",
+	})
 }
 
 func (h *RecipeHandler) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
@@ -128,6 +321,11 @@ func (h *RecipeHandler) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if !ifMatch(r, existingRecipe.Version) {
+		util.WriteJSON(w, http.StatusPreconditionFailed, util.Envelope{"error": "recipe has changed since it was fetched"})
+		return
+	}
+	ownIDs := itemIDs(existingRecipe)
 
 	var recipeUpdateRequest struct {
 		Name            *string             `json:"name"`
@@ -136,6 +334,7 @@ func (h *RecipeHandler) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 		CookTimeSeconds *int                `json:"cookTimeSeconds"`
 		Ingredients     []model.Ingredient  `json:"ingredients"`
 		Instructions    []model.Instruction `json:"instructions"`
+		Sections        []model.Section     `json:"sections"`
 		Tags            []model.Tag         `json:"tags"`
 	}
 
@@ -164,26 +363,165 @@ func (h *RecipeHandler) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 	if recipeUpdateRequest.Instructions != nil {
 		existingRecipe.Instructions = recipeUpdateRequest.Instructions
 	}
+	if recipeUpdateRequest.Sections != nil {
+		if recipeUpdateRequest.Ingredients != nil || recipeUpdateRequest.Instructions != nil {
+			h.logger.Error("UpdateRecipe", "error", errMixedSections)
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": errMixedSections.Error()})
+			return
+		}
+		existingRecipe.Sections = recipeUpdateRequest.Sections
+		if err := flattenSections(existingRecipe); err != nil {
+			h.logger.Error("UpdateRecipe", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+			return
+		}
+	}
 	if recipeUpdateRequest.Tags != nil {
 		existingRecipe.Tags = recipeUpdateRequest.Tags
 	}
 
-	if err := validateRecipe(existingRecipe); err != nil {
-		h.logger.Error("CreateRecipe", "error", err)
+	if err := assignItemIDs(existingRecipe, ownIDs); err != nil {
+		h.logger.Error("UpdateRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+
+	h.saveRecipe(w, r, "UpdateRecipe", existingRecipe)
+}
+
+// PatchRecipe edits part of a recipe. It takes either a JSON Merge Patch
+// (application/merge-patch+json) or a JSON Patch
+// (application/json-patch+json), applied to the document described by
+// recipePatch, so a client can change one ingredient or step by its
+// position without sending the rest. Like updates, it honors If-Match.
+func (h *RecipeHandler) PatchRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	var applyPatch func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType:
+		applyPatch = jsonpatch.MergePatch
+	case jsonPatchType:
+		applyPatch = jsonpatch.Apply
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		util.WriteJSON(w, http.StatusUnsupportedMediaType, util.Envelope{"error": "unsupported patch format"})
+		return
+	}
+
+	existingRecipe, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if existingRecipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !ifMatch(r, existingRecipe.Version) {
+		util.WriteJSON(w, http.StatusPreconditionFailed, util.Envelope{"error": "recipe has changed since it was fetched"})
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	doc, err := json.Marshal(newRecipePatch(existingRecipe))
+	if err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to patch recipe"})
+		return
+	}
+
+	patched, err := applyPatch(doc, patch)
+	if err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		switch {
+		case errors.Is(err, jsonpatch.ErrInvalidPatch):
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		case errors.Is(err, jsonpatch.ErrConflict):
+			util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": err.Error()})
+		default:
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to patch recipe"})
+		}
+		return
+	}
+
+	var result recipePatch
+	d := json.NewDecoder(bytes.NewReader(patched))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&result); err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "patched recipe is invalid: " + err.Error()})
+		return
+	}
+
+	ownIDs := itemIDs(existingRecipe)
+	if err := result.applyTo(existingRecipe); err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	if err := assignItemIDs(existingRecipe, ownIDs); err != nil {
+		h.logger.Error("PatchRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+
+	h.saveRecipe(w, r, "PatchRecipe", existingRecipe)
+}
+
+// saveRecipe validates an edited recipe and stores it as a new version,
+// responding with the result.
+func (h *RecipeHandler) saveRecipe(w http.ResponseWriter, r *http.Request, method string, recipe *model.Recipe) {
+	if err := validateRecipe(recipe); err != nil {
+		h.logger.Error(method, "error", err)
 		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
 		return
 	}
 
-	updatedRecipe, err := h.recipeStore.UpdateRecipe(existingRecipe)
+	// Expanding catches unknown sub-recipes and cycles.
+	if _, err := subrecipe.Expand(recipe, h.recipeStore.GetRecipeByID); err != nil {
+		h.logger.Error(method, "error", err)
+		h.writeSubRecipeError(w, recipe, err)
+		return
+	}
+
+	updatedRecipe, err := h.recipeStore.UpdateRecipe(recipe, middleware.UserIDFromContext(r.Context()))
+	if errors.Is(err, store.ErrVersionConflict) {
+		util.WriteJSON(w, http.StatusPreconditionFailed, util.Envelope{"error": "recipe has changed since it was fetched"})
+		return
+	}
+	var refErr *store.ReferenceError
+	if errors.As(err, &refErr) {
+		writeReferenceError(w, refErr.Missing)
+		return
+	}
 	if err != nil {
-		h.logger.Error("UpdateRecipe", "error", err)
+		h.logger.Error(method, "error", err)
 		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to update recipe"})
 		return
 	}
 
+	w.Header().Set("ETag", recipeETag(updatedRecipe.Version))
 	util.WriteJSON(w, http.StatusOK, util.Envelope{"recipe": updatedRecipe})
 }
 
+// DeleteRecipe moves a recipe to the trash, from which it can be restored
+// until it is purged. It refuses to delete a recipe with variations, which
+// would lose their lineage, unless detachVariations=true makes them
+// standalone as part of the delete. Like updates, it honors If-Match.
 func (h *RecipeHandler) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 	recipeID, err := util.ReadIDParam(r)
 	if err != nil {
@@ -192,11 +530,44 @@ func (h *RecipeHandler) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.recipeStore.DeleteRecipe(recipeID)
+	// The store checks a single version as part of the delete. When
+	// If-Match lists several, the current one is checked if listed.
+	version := 0
+	if versions, anyVersion := readIfMatch(r); !anyVersion {
+		version = -1
+		if len(versions) == 1 {
+			version = versions[0]
+		} else if len(versions) > 1 {
+			recipe, err := h.recipeStore.GetRecipeByID(recipeID)
+			if err != nil {
+				h.logger.Error("DeleteRecipe", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete recipe"})
+				return
+			}
+			if recipe != nil && slices.Contains(versions, recipe.Version) {
+				version = recipe.Version
+			}
+		}
+	}
+
+	detachVariations := r.URL.Query().Get("detachVariations") == "true"
+	err = h.recipeStore.DeleteRecipe(recipeID, version, detachVariations)
 	if err == sql.ErrNoRows {
 		http.NotFound(w, r)
 		return
 	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		util.WriteJSON(w, http.StatusPreconditionFailed, util.Envelope{"error": "recipe has changed since it was fetched"})
+		return
+	}
+	if errors.Is(err, store.ErrRecipeInUse) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "recipe is used as a sub-recipe by other recipes"})
+		return
+	}
+	if errors.Is(err, store.ErrRecipeHasVariations) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "recipe has variations; delete them or set detachVariations=true"})
+		return
+	}
 	if err != nil {
 		h.logger.Error("DeleteRecipe", "error", err)
 		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete recipe"})
@@ -206,6 +577,732 @@ func (h *RecipeHandler) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// SyncRecipes returns the recipes created, updated or deleted after the
+// since cursor, for clients that keep an offline copy. The response holds
+// the cursor to pass next time; without since, every recipe is returned.
+func (h *RecipeHandler) SyncRecipes(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		since, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || since < 0 {
+			h.logger.Error("SyncRecipes", "error", fmt.Errorf("invalid cursor %q", v))
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid sync cursor"})
+			return
+		}
+	}
+
+	sync, err := h.recipeStore.SyncRecipes(since)
+	if err != nil {
+		h.logger.Error("SyncRecipes", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to sync recipes"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, sync)
+}
+
+// ListTrash lists the deleted recipes, most recently deleted first.
+func (h *RecipeHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	recipes, err := h.recipeStore.ListDeletedRecipes()
+	if err != nil {
+		h.logger.Error("ListTrash", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipes"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"recipes": recipes, "total": len(recipes)})
+}
+
+// RestoreRecipe takes a recipe out of the trash. A recipe using a
+// sub-recipe that is still in the trash cannot be restored before it.
+func (h *RecipeHandler) RestoreRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("RestoreRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	err = h.recipeStore.RestoreRecipe(recipeID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, store.ErrDeletedSubRecipe) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "recipe uses a sub-recipe that is in the trash; restore it first"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("RestoreRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to restore recipe"})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("RestoreRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("ETag", recipeETag(recipe.Version))
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"recipe": recipe})
+}
+
+// PurgeRecipe permanently deletes a recipe in the trash. A sub-recipe of
+// recipes still in the trash is kept, so they can be restored whole.
+func (h *RecipeHandler) PurgeRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("PurgeRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	err = h.recipeStore.PurgeRecipe(recipeID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, store.ErrRecipeInUse) {
+		util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "recipe is used as a sub-recipe by recipes in the trash; purge them first"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("PurgeRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to purge recipe"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForkRecipe copies a recipe into a new variation that records the recipe
+// as its parent. The body may give the variation a name; it defaults to the
+// parent's name marked as a variation.
+func (h *RecipeHandler) ForkRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	var forkRequest struct {
+		Name *string `json:"name"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&forkRequest)
+	if err != nil && err != io.EOF {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	parent, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if parent == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := parent.Name + " (variation)"
+	if forkRequest.Name != nil {
+		name = *forkRequest.Name
+	}
+
+	fork, err := forkRecipe(parent, name)
+	if err != nil {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+
+	if err := validateRecipe(fork); err != nil {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdRecipe, err := h.recipeStore.CreateRecipe(fork)
+	if err != nil {
+		h.logger.Error("ForkRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create recipe"})
+		return
+	}
+
+	w.Header().Set("ETag", recipeETag(createdRecipe.Version))
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"recipe": createdRecipe})
+}
+
+// DiffRecipe lists how a variation differs from its parent.
+func (h *RecipeHandler) DiffRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DiffRecipe", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("DiffRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if recipe.ParentID == nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "recipe is not a variation"})
+		return
+	}
+
+	parent, err := h.recipeStore.GetRecipeByID(*recipe.ParentID)
+	if err != nil {
+		h.logger.Error("DiffRecipe", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if parent == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{
+		"parent": model.RecipeRef{ID: parent.ID, Slug: parent.Slug, Name: parent.Name},
+		"diff":   recipediff.Compare(parent, recipe),
+	})
+}
+
+// ListRevisions lists a recipe's revisions, oldest first, without their
+// contents.
+func (h *RecipeHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("ListRevisions", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("ListRevisions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	revisions, err := h.recipeStore.ListRevisions(recipeID)
+	if err != nil {
+		h.logger.Error("ListRevisions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch revisions"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"revisions": revisions, "total": len(revisions)})
+}
+
+func (h *RecipeHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := h.readRevision(w, r, "GetRevision", chi.URLParam(r, "number"))
+	if !ok {
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"revision": revision})
+}
+
+// DiffRevisions lists the changes between the revisions given by the from
+// and to query parameters.
+func (h *RecipeHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	from, ok := h.readRevision(w, r, "DiffRevisions", r.URL.Query().Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := h.readRevision(w, r, "DiffRevisions", r.URL.Query().Get("to"))
+	if !ok {
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{
+		"from": from.Number,
+		"to":   to.Number,
+		"diff": recipediff.Compare(from.Recipe, to.Recipe),
+	})
+}
+
+// RestoreRevision puts a revision's contents back as a new update, so the
+// history in between is kept.
+func (h *RecipeHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := h.readRevision(w, r, "RestoreRevision", chi.URLParam(r, "number"))
+	if !ok {
+		return
+	}
+
+	existingRecipe, err := h.recipeStore.GetRecipeByID(revision.RecipeID)
+	if err != nil {
+		h.logger.Error("RestoreRevision", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if existingRecipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !ifMatch(r, existingRecipe.Version) {
+		util.WriteJSON(w, http.StatusPreconditionFailed, util.Envelope{"error": "recipe has changed since it was fetched"})
+		return
+	}
+
+	// Items keep the ids they had in the revision, which were the
+	// recipe's own.
+	ownIDs := itemIDs(existingRecipe, revision.Recipe)
+
+	existingRecipe.Name = revision.Recipe.Name
+	existingRecipe.Servings = revision.Recipe.Servings
+	existingRecipe.PrepTimeSeconds = revision.Recipe.PrepTimeSeconds
+	existingRecipe.CookTimeSeconds = revision.Recipe.CookTimeSeconds
+	existingRecipe.Ingredients = revision.Recipe.Ingredients
+	existingRecipe.Instructions = revision.Recipe.Instructions
+	existingRecipe.Sections = revision.Recipe.Sections
+	existingRecipe.Tags = revision.Recipe.Tags
+
+	if err := assignItemIDs(existingRecipe, ownIDs); err != nil {
+		h.logger.Error("RestoreRevision", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+
+	// The recipe may have changed since, e.g. a sub-recipe it used may be
+	// gone, so the restored contents are checked like any update.
+	h.saveRecipe(w, r, "RestoreRevision", existingRecipe)
+}
+
+// readRevision fetches the recipe's revision with the given number,
+// writing the error response itself when it cannot.
+func (h *RecipeHandler) readRevision(w http.ResponseWriter, r *http.Request, method, number string) (*model.RecipeRevision, bool) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error(method, "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return nil, false
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		h.logger.Error(method, "error", fmt.Errorf("invalid revision number %q", number))
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid revision number"})
+		return nil, false
+	}
+
+	revision, err := h.recipeStore.GetRevision(recipeID, n)
+	if err != nil {
+		h.logger.Error(method, "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch revision"})
+		return nil, false
+	}
+	if revision == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	return revision, true
+}
+
+// recipeCacheControl lets clients keep recipe responses but has them
+// revalidate each time. Responses depend on the caller's dietary profile,
+// so shared caches must not keep them.
+const recipeCacheControl = "private, no-cache"
+
+// recipeETag is the entity tag of a recipe version, as sent with writes
+// and compared by If-Match. GETs follow the version with a hash of the
+// response, which If-Match does not accept.
+func recipeETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// readIfMatch returns the recipe versions the If-Match header lists, and
+// true when it allows any version, as without the header or with "*".
+// If-Match uses strong comparison, so weak tags and tags recipeETag does
+// not issue match no version.
+func readIfMatch(r *http.Request) ([]int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err == nil && version > 0 && recipeETag(version) == tag {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false
+}
+
+// ifMatch reports whether the If-Match header allows changing a recipe at
+// the given version.
+func ifMatch(r *http.Request, version int) bool {
+	versions, anyVersion := readIfMatch(r)
+	return anyVersion || slices.Contains(versions, version)
+}
+
+// forkRecipe copies the parent's contents into a new recipe with its own
+// ids, keeping each item in the matching copied section.
+func forkRecipe(parent *model.Recipe, name string) (*model.Recipe, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	fork := &model.Recipe{
+		ID:              id.String(),
+		Slug:            slug.Make(name),
+		ParentID:        &parent.ID,
+		Name:            name,
+		Servings:        parent.Servings,
+		PrepTimeSeconds: parent.PrepTimeSeconds,
+		CookTimeSeconds: parent.CookTimeSeconds,
+		Ingredients:     append([]model.Ingredient{}, parent.Ingredients...),
+		Instructions:    append([]model.Instruction{}, parent.Instructions...),
+		Sections:        []model.Section{},
+		Tags:            append([]model.Tag{}, parent.Tags...),
+	}
+
+	sectionIDs := map[string]string{}
+	for _, section := range parent.Sections {
+		sectionID, err := util.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		sectionIDs[section.ID] = sectionID
+		fork.Sections = append(fork.Sections, model.Section{ID: sectionID, Name: section.Name})
+	}
+
+	if err := assignItemIDs(fork, nil); err != nil {
+		return nil, err
+	}
+	for n := range fork.Ingredients {
+		fork.Ingredients[n].SectionID = sectionIDs[fork.Ingredients[n].SectionID]
+	}
+	for n := range fork.Instructions {
+		fork.Instructions[n].SectionID = sectionIDs[fork.Instructions[n].SectionID]
+	}
+
+	return fork, nil
+}
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// recipePatch is the document a PATCH edits. It holds only the fields a
+// client can change. Ingredients and steps are in flat lists in recipe
+// order and name their section by id; sections carry just their id and
+// name. Step numbers follow the order of the list.
+type recipePatch struct {
+	Name            string              `json:"name"`
+	Servings        int                 `json:"servings"`
+	PrepTimeSeconds int                 `json:"prepTimeSeconds"`
+	CookTimeSeconds int                 `json:"cookTimeSeconds"`
+	Ingredients     []model.Ingredient  `json:"ingredients"`
+	Instructions    []model.Instruction `json:"instructions"`
+	Sections        []patchSection      `json:"sections"`
+	Tags            []model.Tag         `json:"tags"`
+}
+
+type patchSection struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newRecipePatch(recipe *model.Recipe) recipePatch {
+	p := recipePatch{
+		Name:            recipe.Name,
+		Servings:        recipe.Servings,
+		PrepTimeSeconds: recipe.PrepTimeSeconds,
+		CookTimeSeconds: recipe.CookTimeSeconds,
+		Ingredients:     append([]model.Ingredient{}, recipe.Ingredients...),
+		Instructions:    append([]model.Instruction{}, recipe.Instructions...),
+		Sections:        []patchSection{},
+		Tags:            append([]model.Tag{}, recipe.Tags...),
+	}
+	for _, s := range recipe.Sections {
+		p.Sections = append(p.Sections, patchSection{ID: s.ID, Name: s.Name})
+	}
+	return p
+}
+
+// applyTo copies the patched fields onto recipe. Sections added by the
+// patch get new ids.
+func (p recipePatch) applyTo(recipe *model.Recipe) error {
+	recipe.Name = p.Name
+	recipe.Servings = p.Servings
+	recipe.PrepTimeSeconds = p.PrepTimeSeconds
+	recipe.CookTimeSeconds = p.CookTimeSeconds
+	recipe.Ingredients = append([]model.Ingredient{}, p.Ingredients...)
+	recipe.Instructions = append([]model.Instruction{}, p.Instructions...)
+	recipe.Sections = []model.Section{}
+	recipe.Tags = append([]model.Tag{}, p.Tags...)
+
+	for _, s := range p.Sections {
+		if s.ID == "" {
+			id, err := util.GenerateUUID()
+			if err != nil {
+				return err
+			}
+			s.ID = id
+		}
+		recipe.Sections = append(recipe.Sections, model.Section{ID: s.ID, Name: s.Name})
+	}
+	for n := range recipe.Instructions {
+		recipe.Instructions[n].StepNumber = n + 1
+	}
+
+	return nil
+}
+
+// assignItemIDs gives the recipe's ingredient lines and steps their ids.
+// An item keeps the id the client sent only if it is one of own, the ids
+// the recipe's items already have, and no earlier item claimed it; every
+// other item gets a new id. This keeps ids stable across edits while a
+// client can neither pick ids nor take over another recipe's items.
+func assignItemIDs(recipe *model.Recipe, own map[string]bool) error {
+	used := map[string]bool{}
+	assign := func(id *string) error {
+		if own[*id] && !used[*id] {
+			used[*id] = true
+			return nil
+		}
+		newID, err := util.GenerateUUID()
+		if err != nil {
+			return err
+		}
+		*id = newID
+		return nil
+	}
+
+	for n := range recipe.Ingredients {
+		if err := assign(&recipe.Ingredients[n].LineID); err != nil {
+			return err
+		}
+	}
+	for n := range recipe.Instructions {
+		if err := assign(&recipe.Instructions[n].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemIDs returns the ids of the recipes' ingredient lines and steps.
+func itemIDs(recipes ...*model.Recipe) map[string]bool {
+	ids := map[string]bool{}
+	for _, r := range recipes {
+		for _, i := range r.Ingredients {
+			if i.LineID != "" {
+				ids[i.LineID] = true
+			}
+		}
+		for _, i := range r.Instructions {
+			if i.ID != "" {
+				ids[i.ID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// writeSubRecipeError responds to an error from expanding a recipe's
+// sub-recipes while creating or updating it. An unknown sub-recipe is
+// reported like any other missing reference, on each line that leads to
+// it. When it is used further down, the line's recipeId exists and the
+// reported id is the one that does not.
+func (h *RecipeHandler) writeSubRecipeError(w http.ResponseWriter, recipe *model.Recipe, err error) {
+	var unknown *subrecipe.UnknownRecipeError
+	switch {
+	case errors.Is(err, subrecipe.ErrCycle):
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "recipe cannot use itself as a sub-recipe"})
+	case errors.As(err, &unknown):
+		missing := []model.MissingReference{}
+		for n, i := range recipe.Ingredients {
+			if i.RecipeID == "" {
+				continue
+			}
+			line := &model.Recipe{ID: recipe.ID, Ingredients: []model.Ingredient{i}}
+			_, err := subrecipe.Expand(line, h.recipeStore.GetRecipeByID)
+			var lineUnknown *subrecipe.UnknownRecipeError
+			switch {
+			case errors.As(err, &lineUnknown):
+				missing = append(missing, model.MissingReference{Field: fmt.Sprintf("/ingredients/%d/recipeId", n), ID: lineUnknown.ID})
+			case err != nil && !errors.Is(err, subrecipe.ErrCycle):
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+				return
+			}
+		}
+		if len(missing) == 0 {
+			missing = append(missing, model.MissingReference{Field: "/ingredients", ID: unknown.ID})
+		}
+		writeReferenceError(w, missing)
+	default:
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+	}
+}
+
+// writeReferenceError responds to a recipe that uses records that do not
+// exist, pointing at each offending field.
+func writeReferenceError(w http.ResponseWriter, missing []model.MissingReference) {
+	util.WriteJSON(w, http.StatusUnprocessableEntity, util.Envelope{
+		"error":   "recipe refers to records that do not exist",
+		"missing": missing,
+	})
+}
+
+var errMixedSections = errors.New("sections cannot be combined with ingredients or instructions")
+
+// flattenSections gives each section a new id and replaces the recipe's
+// flat ingredient and instruction lists with the sections' items, in
+// section order. Steps are numbered across the whole recipe.
+func flattenSections(recipe *model.Recipe) error {
+	recipe.Ingredients = []model.Ingredient{}
+	recipe.Instructions = []model.Instruction{}
+
+	for n := range recipe.Sections {
+		section := &recipe.Sections[n]
+		id, err := util.GenerateUUID()
+		if err != nil {
+			return err
+		}
+		section.ID = id
+
+		for _, i := range section.Ingredients {
+			i.SectionID = id
+			recipe.Ingredients = append(recipe.Ingredients, i)
+		}
+		for _, i := range section.Instructions {
+			i.SectionID = id
+			i.StepNumber = len(recipe.Instructions) + 1
+			recipe.Instructions = append(recipe.Instructions, i)
+		}
+	}
+
+	return nil
+}
+
+func readRecipeFilter(r *http.Request) (store.RecipeFilter, error) {
+	var filter store.RecipeFilter
+	q := r.URL.Query()
+
+	if v := q.Get("notCookedInDays"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			return filter, errors.New("notCookedInDays must be a positive integer")
+		}
+		filter.NotCookedSince = time.Now().AddDate(0, 0, -days).Format(dateLayout)
+	}
+
+	if v := q.Get("excludeAllergens"); v != "" {
+		filter.ExcludeAllergens = normalizeFlags(strings.Split(v, ","))
+		for _, a := range filter.ExcludeAllergens {
+			if !validAllergens[a] {
+				return filter, fmt.Errorf("unknown allergen %q", a)
+			}
+		}
+	}
+
+	if v := q.Get("diet"); v != "" {
+		filter.Diets = normalizeFlags(strings.Split(v, ","))
+		for _, d := range filter.Diets {
+			if !validDiets[d] {
+				return filter, fmt.Errorf("unknown diet %q", d)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// readApplyProfile reports whether the caller's dietary profile should
+// filter the results; it does unless applyProfile=false.
+func readApplyProfile(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("applyProfile")
+	if v == "" {
+		return true, nil
+	}
+
+	apply, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("applyProfile must be true or false")
+	}
+
+	return apply, nil
+}
+
+// readDietaryProfile returns nil for anonymous requests.
+func readDietaryProfile(r *http.Request, us store.UserStore) (*model.DietaryProfile, error) {
+	userID := middleware.UserIDFromContext(r.Context())
+	if userID == "" {
+		return nil, nil
+	}
+
+	return us.GetDietaryProfile(userID)
+}
+
+// applyDietaryProfile adds the profile to the filter on top of whatever the
+// query string already asked for.
+func applyDietaryProfile(filter *store.RecipeFilter, p *model.DietaryProfile) {
+	if p == nil {
+		return
+	}
+
+	if len(p.Allergies) > 0 {
+		filter.ExcludeAllergens = normalizeFlags(append(filter.ExcludeAllergens, p.Allergies...))
+	}
+	if len(p.Diets) > 0 {
+		filter.Diets = normalizeFlags(append(filter.Diets, p.Diets...))
+	}
+	filter.ExcludeIngredientIDs = append(filter.ExcludeIngredientIDs, p.DislikedIngredientIDs...)
+}
+
+func readIncludes(r *http.Request) map[string]bool {
+	include := map[string]bool{}
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			include[v] = true
+		}
+	}
+	return include
+}
+
+func readMaxCostPerServing(r *http.Request) (*float64, error) {
+	v := r.URL.Query().Get("maxCostPerServing")
+	if v == "" {
+		return nil, nil
+	}
+
+	maxCost, err := strconv.ParseFloat(v, 64)
+	if err != nil || maxCost < 0 {
+		return nil, errors.New("maxCostPerServing must be a non-negative number")
+	}
+
+	return &maxCost, nil
+}
+
 func validateRecipe(r *model.Recipe) error {
 	if r.Name == "" {
 		return errors.New("name cannot be blank")
@@ -222,5 +1319,41 @@ func validateRecipe(r *model.Recipe) error {
 	if r.CookTimeSeconds < 0 {
 		return errors.New("cook time cannot be a negative value")
 	}
+
+	for n, i := range r.Ingredients {
+		if i.RecipeID == "" {
+			if i.ID == "" {
+				return fmt.Errorf("ingredient line /ingredients/%d needs an id or a recipeId", n)
+			}
+			continue
+		}
+		if i.ID != "" {
+			return errors.New("ingredient line cannot use both an ingredient and a recipe")
+		}
+		if i.Quantity <= 0 {
+			return errors.New("sub-recipe quantity must be greater than zero")
+		}
+		if unit := strings.ToLower(i.Unit); unit != "" && unit != "serving" && unit != "servings" {
+			return errors.New("sub-recipe quantity must be in servings")
+		}
+	}
+
+	sections := map[string]bool{}
+	for _, section := range r.Sections {
+		if strings.TrimSpace(section.Name) == "" {
+			return errors.New("section name cannot be blank")
+		}
+		sections[section.ID] = true
+	}
+	for _, i := range r.Ingredients {
+		if i.SectionID != "" && !sections[i.SectionID] {
+			return fmt.Errorf("unknown section id %q", i.SectionID)
+		}
+	}
+	for _, i := range r.Instructions {
+		if i.SectionID != "" && !sections[i.SectionID] {
+			return fmt.Errorf("unknown section id %q", i.SectionID)
+		}
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
@@ -13,7 +14,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
 	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
 	"github.com/stevmwhitfield/recipe-api/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -25,8 +28,8 @@ type MockRecipeStore struct {
 	mock.Mock
 }
 
-func (m *MockRecipeStore) ListRecipes() ([]model.Recipe, error) {
-	args := m.Called()
+func (m *MockRecipeStore) ListRecipes(f store.RecipeFilter) ([]model.Recipe, error) {
+	args := m.Called(f)
 	return args.Get(0).([]model.Recipe), args.Error(1)
 }
 func (m *MockRecipeStore) CreateRecipe(r *model.Recipe) (*model.Recipe, error) {
@@ -44,37 +47,90 @@ func (m *MockRecipeStore) GetRecipeByID(id string) (*model.Recipe, error) {
 	}
 	return args.Get(0).(*model.Recipe), args.Error(1)
 }
-func (m *MockRecipeStore) UpdateRecipe(r *model.Recipe) (*model.Recipe, error) {
-	args := m.Called(r)
+func (m *MockRecipeStore) UpdateRecipe(r *model.Recipe, authorID string) (*model.Recipe, error) {
+	args := m.Called(r, authorID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*model.Recipe), args.Error(1)
 }
-func (m *MockRecipeStore) DeleteRecipe(id string) error {
+func (m *MockRecipeStore) DeleteRecipe(id string, version int, detachVariations bool) error {
+	args := m.Called(id, version, detachVariations)
+	return args.Error(0)
+}
+func (m *MockRecipeStore) ListRevisions(recipeID string) ([]model.RecipeRevision, error) {
+	args := m.Called(recipeID)
+	return args.Get(0).([]model.RecipeRevision), args.Error(1)
+}
+func (m *MockRecipeStore) GetRevision(recipeID string, number int) (*model.RecipeRevision, error) {
+	args := m.Called(recipeID, number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecipeRevision), args.Error(1)
+}
+func (m *MockRecipeStore) ListDeletedRecipes() ([]model.Recipe, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Recipe), args.Error(1)
+}
+func (m *MockRecipeStore) RestoreRecipe(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+func (m *MockRecipeStore) PurgeRecipe(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockRecipeStore) PurgeDeletedRecipes(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRecipeStore) SyncRecipes(since int64) (*model.RecipeSync, error) {
+	args := m.Called(since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecipeSync), args.Error(1)
+}
+
+type MockNutritionStore struct {
+	mock.Mock
+}
+
+func (m *MockNutritionStore) ListNutrition() ([]model.IngredientNutrition, error) {
+	args := m.Called()
+	return args.Get(0).([]model.IngredientNutrition), args.Error(1)
+}
+func (m *MockNutritionStore) ImportNutrition(data []model.IngredientNutrition) ([]model.IngredientNutrition, error) {
+	args := m.Called(data)
+	return args.Get(0).([]model.IngredientNutrition), args.Error(1)
+}
 
 // tests
 
 // TODO: finish writing tests for all handlers
 func TestRecipeHandler(t *testing.T) {
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+	ingredientID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+
 	tests := []struct {
 		name      string
 		method    string
 		uri       string
-		data      io.Reader // optional
-		setupMock func(*MockRecipeStore)
+		data      io.Reader         // optional
+		userID    string            // optional
+		headers   map[string]string // optional
+		setupMock func(*MockRecipeStore, *MockPriceStore, *MockNutritionStore, *MockUserStore)
 		wantCode  int
 		wantBody  util.Envelope // optional
+		wantETag  string        // optional, a regexp
 	}{
 		{
 			name:   "list recipes",
 			method: http.MethodGet,
 			uri:    "/",
-			setupMock: func(m *MockRecipeStore) {
-				m.On("ListRecipes").Return(getListRecipeData(), nil)
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
 			},
 			wantCode: http.StatusOK,
 			wantBody: util.Envelope{"recipes": getListRecipeData(), "total": 2},
@@ -83,18 +139,236 @@ func TestRecipeHandler(t *testing.T) {
 			name:   "list recipes with error",
 			method: http.MethodGet,
 			uri:    "/",
-			setupMock: func(m *MockRecipeStore) {
-				m.On("ListRecipes").Return([]model.Recipe{}, errors.New("database error"))
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return([]model.Recipe{}, errors.New("database error"))
 			},
 			wantCode: http.StatusInternalServerError,
 			wantBody: util.Envelope{"error": "failed to fetch recipes"},
 		},
+		{
+			name:   "list recipes not cooked recently",
+			method: http.MethodGet,
+			uri:    "/?notCookedInDays=30",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{
+					NotCookedSince: time.Now().AddDate(0, 0, -30).Format("2006-01-02"),
+				}).Return(getListRecipeData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": getListRecipeData(), "total": 2},
+		},
+		{
+			name:      "list recipes with invalid notCookedInDays",
+			method:    http.MethodGet,
+			uri:       "/?notCookedInDays=soon",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "notCookedInDays must be a positive integer"},
+		},
+		{
+			name:   "list recipes by allergens and diet",
+			method: http.MethodGet,
+			uri:    "/?excludeAllergens=Gluten,nut&diet=vegetarian",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{
+					ExcludeAllergens: []string{"gluten", "nut"},
+					Diets:            []string{"vegetarian"},
+				}).Return([]model.Recipe{}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": []model.Recipe{}, "total": 0},
+		},
+		{
+			name:      "list recipes with unknown allergen",
+			method:    http.MethodGet,
+			uri:       "/?excludeAllergens=cilantro",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": `unknown allergen "cilantro"`},
+		},
+		{
+			name:   "list recipes under a cost per serving",
+			method: http.MethodGet,
+			uri:    "/?maxCostPerServing=1.5",
+			setupMock: func(m *MockRecipeStore, ps *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
+				ps.On("ListPrices", "").Return([]model.IngredientPrice{
+					{ID: "p1", IngredientID: "i1", Amount: 1, Unit: "cup", Price: 0.5, PricedOn: "2025-10-01"},
+					{ID: "p2", IngredientID: "i2", Amount: 1, Unit: "cup", Price: 0.5, PricedOn: "2025-10-01"},
+					{ID: "p3", IngredientID: "i3", Amount: 12, Unit: "", Price: 3, PricedOn: "2025-10-01"},
+					{ID: "p4", IngredientID: "i4", Amount: 1, Unit: "tbsp", Price: 0.25, PricedOn: "2025-10-01"},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": getListRecipeData()[:1], "total": 1},
+		},
+		{
+			name:      "list recipes with invalid maxCostPerServing",
+			method:    http.MethodGet,
+			uri:       "/?maxCostPerServing=cheap",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "maxCostPerServing must be a non-negative number"},
+		},
+		{
+			name:   "get recipe with cost",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5?include=cost",
+			setupMock: func(m *MockRecipeStore, ps *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				recipe := getListRecipeData()[0]
+				recipe.Ingredients = recipe.Ingredients[:1]
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&recipe, nil)
+				ps.On("ListPrices", "").Return([]model.IngredientPrice{
+					{ID: "p1", IngredientID: "i1", Amount: 1, Unit: "cup", Price: 0.5, PricedOn: "2025-10-01"},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"recipe": func() model.Recipe {
+					recipe := getListRecipeData()[0]
+					recipe.Ingredients = recipe.Ingredients[:1]
+					return recipe
+				}(),
+				"cost": model.RecipeCost{
+					Total:      1,
+					PerServing: 0.25,
+					Complete:   true,
+					Lines:      []model.IngredientCost{{IngredientID: "i1", Name: "Flour", Quantity: 2, Unit: "cup", Cost: 1, PriceID: "p1"}},
+				},
+			},
+		},
+		{
+			name:   "get recipe with cost of sub-recipe",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7bc7-b171-710c99947f08?include=cost",
+			setupMock: func(m *MockRecipeStore, ps *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("GetRecipeByID", "019a40de-02cd-7bc7-b171-710c99947f08").Return(&model.Recipe{
+					ID: "019a40de-02cd-7bc7-b171-710c99947f08", Name: "Pancake Stack", Servings: 2,
+					Ingredients: []model.Ingredient{{RecipeID: "019a40de-02cd-7865-84ae-c038b75596f5", Name: "Classic Pancakes", Quantity: 2}},
+				}, nil)
+				pancakes := getListRecipeData()[0]
+				pancakes.Ingredients = pancakes.Ingredients[:1]
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&pancakes, nil)
+				ps.On("ListPrices", "").Return([]model.IngredientPrice{
+					{ID: "p1", IngredientID: "i1", Amount: 1, Unit: "cup", Price: 0.5, PricedOn: "2025-10-01"},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"recipe": &model.Recipe{
+					ID: "019a40de-02cd-7bc7-b171-710c99947f08", Name: "Pancake Stack", Servings: 2,
+					Ingredients: []model.Ingredient{{RecipeID: "019a40de-02cd-7865-84ae-c038b75596f5", Name: "Classic Pancakes", Quantity: 2}},
+				},
+				"cost": model.RecipeCost{
+					Total:      0.5,
+					PerServing: 0.25,
+					Complete:   true,
+					Lines:      []model.IngredientCost{{IngredientID: "i1", Name: "Flour", Quantity: 1, Unit: "cup", Cost: 0.5, PriceID: "p1"}},
+				},
+			},
+		},
+		{
+			name:   "get recipe with nutrition",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7bc7-b171-710c99947f08?include=nutrition",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, ns *MockNutritionStore, _ *MockUserStore) {
+				recipe := getListRecipeData()[1]
+				recipe.Ingredients = recipe.Ingredients[:2]
+				m.On("GetRecipeByID", "019a40de-02cd-7bc7-b171-710c99947f08").Return(&recipe, nil)
+				ns.On("ListNutrition").Return([]model.IngredientNutrition{
+					{IngredientID: "i5", Per100g: model.NutritionFacts{Calories: 371, Protein: 13}},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"recipe": func() model.Recipe {
+					recipe := getListRecipeData()[1]
+					recipe.Ingredients = recipe.Ingredients[:2]
+					return recipe
+				}(),
+				"nutrition": model.RecipeNutrition{
+					Total:      model.NutritionFacts{Calories: 1855, Protein: 65},
+					PerServing: model.NutritionFacts{Calories: 309.2, Protein: 10.8},
+					Unresolved: []model.UnmatchedIngredient{{IngredientID: "i6", Name: "Ground Beef", Quantity: 500, Unit: "g", Reason: "no nutrition data"}},
+				},
+			},
+		},
+		{
+			name:   "list recipes with dietary profile",
+			method: http.MethodGet,
+			uri:    "/?excludeAllergens=gluten",
+			userID: userID,
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, us *MockUserStore) {
+				us.On("GetDietaryProfile", userID).Return(&model.DietaryProfile{
+					UserID:                userID,
+					Allergies:             []string{"dairy"},
+					Diets:                 []string{"vegetarian"},
+					DislikedIngredientIDs: []string{ingredientID},
+				}, nil)
+				m.On("ListRecipes", store.RecipeFilter{
+					ExcludeAllergens:     []string{"dairy", "gluten"},
+					Diets:                []string{"vegetarian"},
+					ExcludeIngredientIDs: []string{ingredientID},
+				}).Return([]model.Recipe{}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": []model.Recipe{}, "total": 0},
+		},
+		{
+			name:   "list recipes without dietary profile",
+			method: http.MethodGet,
+			uri:    "/?applyProfile=false",
+			userID: userID,
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": getListRecipeData(), "total": 2},
+		},
+		{
+			name:      "list recipes with invalid applyProfile",
+			method:    http.MethodGet,
+			uri:       "/?applyProfile=maybe",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "applyProfile must be true or false"},
+		},
+		{
+			name:   "get recipe with dietary warnings",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			userID: userID,
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, us *MockUserStore) {
+				recipe := getListRecipeData()[0]
+				recipe.Ingredients = recipe.Ingredients[:1]
+				recipe.Allergens = []string{"gluten"}
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&recipe, nil)
+				us.On("GetDietaryProfile", userID).Return(&model.DietaryProfile{
+					UserID:                userID,
+					Allergies:             []string{"gluten"},
+					DislikedIngredientIDs: []string{"i1"},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"recipe": func() model.Recipe {
+					recipe := getListRecipeData()[0]
+					recipe.Ingredients = recipe.Ingredients[:1]
+					recipe.Allergens = []string{"gluten"}
+					return recipe
+				}(),
+				"warnings": []model.DietaryWarning{
+					{Type: "allergen", Value: "gluten", Message: "contains gluten"},
+					{Type: "disliked", Value: "i1", Message: "uses Flour"},
+				},
+			},
+		},
 		{
 			name:   "create recipe",
 			method: http.MethodPost,
 			uri:    "/",
 			data:   getNewRecipeData(),
-			setupMock: func(m *MockRecipeStore) {
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
 				m.On("CreateRecipe", mock.AnythingOfType("*model.Recipe")).Return(
 					&model.Recipe{
 						Name:            "Classic Pancakes",
@@ -112,6 +386,803 @@ func TestRecipeHandler(t *testing.T) {
 				CookTimeSeconds: 900,
 			}},
 		},
+		{
+			name:   "create recipe with repeated ingredient",
+			method: http.MethodPost,
+			uri:    "/",
+			data: strings.NewReader(`{"name": "Crumble", "servings": 4, "ingredients": [
+				{"id": "butter", "quantity": 50, "unit": "g", "note": "for the dough"},
+				{"id": "butter", "quantity": 25, "unit": "g", "note": "for the topping", "lineId": "client"}
+			]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("CreateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					lines := r.Ingredients
+					return len(lines) == 2 && lines[0].LineID != "" && lines[1].LineID != "client" && lines[0].LineID != lines[1].LineID
+				})).Return(&model.Recipe{Name: "Crumble", Servings: 4}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Crumble", Servings: 4}},
+		},
+		{
+			name:   "create recipe assigns step ids",
+			method: http.MethodPost,
+			uri:    "/",
+			data: strings.NewReader(`{"name": "Crumble", "servings": 4, "instructions": [
+				{"stepNumber": 1, "description": "Rub in the butter."},
+				{"id": "client", "stepNumber": 2, "description": "Bake."}
+			]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("CreateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					steps := r.Instructions
+					return len(steps) == 2 && steps[0].ID != "" && steps[1].ID != "client" && steps[0].ID != steps[1].ID
+				})).Return(&model.Recipe{Name: "Crumble", Servings: 4}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Crumble", Servings: 4}},
+		},
+		{
+			name:   "create recipe with sections",
+			method: http.MethodPost,
+			uri:    "/",
+			data: strings.NewReader(`{"name": "Layer Cake", "servings": 8, "sections": [
+				{"name": "For the sponge", "ingredients": [{"id": "flour", "quantity": 200, "unit": "g"}], "instructions": [{"stepNumber": 1, "description": "Bake."}]},
+				{"name": "For the frosting", "ingredients": [{"id": "butter", "quantity": 50, "unit": "g"}], "instructions": [{"stepNumber": 1, "description": "Beat."}]}
+			]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("CreateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					sponge, frosting := r.Sections[0].ID, r.Sections[1].ID
+					return sponge != "" && frosting != "" && sponge != frosting &&
+						len(r.Ingredients) == 2 && r.Ingredients[0].SectionID == sponge && r.Ingredients[1].SectionID == frosting &&
+						len(r.Instructions) == 2 && r.Instructions[1].SectionID == frosting && r.Instructions[1].StepNumber == 2
+				})).Return(&model.Recipe{Name: "Layer Cake", Servings: 8}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Layer Cake", Servings: 8}},
+		},
+		{
+			name:      "create recipe with sections and flat ingredients",
+			method:    http.MethodPost,
+			uri:       "/",
+			data:      strings.NewReader(`{"name": "Layer Cake", "servings": 8, "sections": [{"name": "For the sponge"}], "ingredients": [{"id": "flour", "quantity": 200}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "sections cannot be combined with ingredients or instructions"},
+		},
+		{
+			name:      "create recipe with blank section name",
+			method:    http.MethodPost,
+			uri:       "/",
+			data:      strings.NewReader(`{"name": "Layer Cake", "servings": 8, "sections": [{"name": " "}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "section name cannot be blank"},
+		},
+		{
+			name:      "create recipe with unknown section id",
+			method:    http.MethodPost,
+			uri:       "/",
+			data:      strings.NewReader(`{"name": "Layer Cake", "servings": 8, "instructions": [{"stepNumber": 1, "description": "Bake.", "sectionId": "sponge"}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": `unknown section id "sponge"`},
+		},
+		{
+			name:   "create recipe with unknown sub-recipe",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Lasagne", "servings": 6, "ingredients": [{"recipeId": "bechamel", "quantity": 2, "unit": "servings"}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("GetRecipeByID", "bechamel").Return(nil, nil)
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: util.Envelope{
+				"error":   "recipe refers to records that do not exist",
+				"missing": []model.MissingReference{{Field: "/ingredients/0/recipeId", ID: "bechamel"}},
+			},
+		},
+		{
+			name:   "create recipe with unknown nested sub-recipe",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Lasagne", "servings": 6, "ingredients": [{"id": "pasta", "quantity": 500, "unit": "g"}, {"recipeId": "bechamel", "quantity": 2, "unit": "servings"}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("GetRecipeByID", "bechamel").Return(&model.Recipe{ID: "bechamel", Servings: 4, Ingredients: []model.Ingredient{
+					{RecipeID: "roux", Quantity: 1},
+				}}, nil)
+				m.On("GetRecipeByID", "roux").Return(nil, nil)
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: util.Envelope{
+				"error":   "recipe refers to records that do not exist",
+				"missing": []model.MissingReference{{Field: "/ingredients/1/recipeId", ID: "roux"}},
+			},
+		},
+		{
+			name:      "create recipe with empty ingredient line",
+			method:    http.MethodPost,
+			uri:       "/",
+			data:      strings.NewReader(`{"name": "Crumble", "servings": 4, "ingredients": [{"id": "flour", "quantity": 200, "unit": "g"}, {"quantity": 1}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "ingredient line /ingredients/1 needs an id or a recipeId"},
+		},
+		{
+			name:   "create recipe with unknown ingredient and tag",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "Crumble", "servings": 4, "ingredients": [{"id": "flour", "quantity": 200, "unit": "g"}, {"id": "nope", "quantity": 1}], "tags": [{"id": "gone"}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("CreateRecipe", mock.AnythingOfType("*model.Recipe")).Return(nil, &store.ReferenceError{
+					Missing: []model.MissingReference{{Field: "/ingredients/1/id", ID: "nope"}, {Field: "/tags/0/id", ID: "gone"}},
+				})
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: util.Envelope{
+				"error":   "recipe refers to records that do not exist",
+				"missing": []model.MissingReference{{Field: "/ingredients/1/id", ID: "nope"}, {Field: "/tags/0/id", ID: "gone"}},
+			},
+		},
+		{
+			name:      "create recipe with sub-recipe in grams",
+			method:    http.MethodPost,
+			uri:       "/",
+			data:      strings.NewReader(`{"name": "Lasagne", "servings": 6, "ingredients": [{"recipeId": "bechamel", "quantity": 200, "unit": "g"}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "sub-recipe quantity must be in servings"},
+		},
+		{
+			name:   "update recipe into a sub-recipe cycle",
+			method: http.MethodPut,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:   strings.NewReader(`{"ingredients": [{"recipeId": "019a40de-02cd-7bc7-b171-710c99947f08", "quantity": 1}]}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&pancakes, nil)
+				m.On("GetRecipeByID", "019a40de-02cd-7bc7-b171-710c99947f08").Return(&model.Recipe{
+					ID: "019a40de-02cd-7bc7-b171-710c99947f08", Name: "Pancake Stack", Servings: 2,
+					Ingredients: []model.Ingredient{{RecipeID: "019a40de-02cd-7865-84ae-c038b75596f5", Quantity: 2}},
+				}, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "recipe cannot use itself as a sub-recipe"},
+		},
+		{
+			name:   "fork recipe",
+			method: http.MethodPost,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/fork",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Sections = []model.Section{{ID: "batter", Name: "For the batter"}}
+				pancakes.Instructions[0].SectionID = "batter"
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&pancakes, nil)
+				m.On("CreateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.ID != pancakes.ID && *r.ParentID == pancakes.ID &&
+						r.Name == "Classic Pancakes (variation)" && r.Slug == "classic-pancakes-variation" &&
+						len(r.Ingredients) == 4 && r.Ingredients[0].ID == "i1" &&
+						r.Instructions[0].ID != "s1" && r.Instructions[0].SectionID == r.Sections[0].ID && r.Sections[0].ID != "batter"
+				})).Return(&model.Recipe{Name: "Classic Pancakes (variation)"}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Classic Pancakes (variation)"}},
+		},
+		{
+			name:   "fork recipe with name",
+			method: http.MethodPost,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/fork",
+			data:   strings.NewReader(`{"name": "Vegan Pancakes"}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", "019a40de-02cd-7865-84ae-c038b75596f5").Return(&pancakes, nil)
+				m.On("CreateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.Name == "Vegan Pancakes" && r.Slug == "vegan-pancakes"
+				})).Return(&model.Recipe{Name: "Vegan Pancakes"}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Vegan Pancakes"}},
+		},
+		{
+			name:   "diff variation against parent",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7bc7-b171-710c99947f08/diff",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				variation := getListRecipeData()[0]
+				variation.ID = "019a40de-02cd-7bc7-b171-710c99947f08"
+				variation.ParentID = &pancakes.ID
+				variation.CookTimeSeconds = 600
+				variation.Ingredients = variation.Ingredients[1:]
+				m.On("GetRecipeByID", variation.ID).Return(&variation, nil)
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"parent": model.RecipeRef{ID: "019a40de-02cd-7865-84ae-c038b75596f5", Slug: "classic-pancakes", Name: "Classic Pancakes"},
+				"diff": model.RecipeDiff{
+					Fields:       []model.FieldChange{{Field: "cookTimeSeconds", From: 900, To: 600}},
+					Ingredients:  []model.IngredientChange{{Change: "removed", From: &getListRecipeData()[0].Ingredients[0]}},
+					Instructions: []model.InstructionChange{},
+				},
+			},
+		},
+		{
+			name:   "diff recipe without parent",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/diff",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "recipe is not a variation"},
+		},
+		{
+			name:   "delete recipe with variations",
+			method: http.MethodDelete,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("DeleteRecipe", "019a40de-02cd-7865-84ae-c038b75596f5", 0, false).Return(store.ErrRecipeHasVariations)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "recipe has variations; delete them or set detachVariations=true"},
+		},
+		{
+			name:   "delete recipe detaching variations",
+			method: http.MethodDelete,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5?detachVariations=true",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("DeleteRecipe", "019a40de-02cd-7865-84ae-c038b75596f5", 0, true).Return(nil)
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:   "delete recipe used as a sub-recipe",
+			method: http.MethodDelete,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("DeleteRecipe", "019a40de-02cd-7865-84ae-c038b75596f5", 0, false).Return(store.ErrRecipeInUse)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "recipe is used as a sub-recipe by other recipes"},
+		},
+		{
+			name:   "get recipe with etag",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusOK,
+			wantETag: `^"3-[0-9a-f]{32}"$`,
+		},
+		{
+			name:    "get recipe not modified since",
+			method:  http.MethodGet,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			headers: map[string]string{"If-Modified-Since": "Sun, 02 Nov 2025 00:00:00 GMT"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:    "get recipe with stale etag",
+			method:  http.MethodGet,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			headers: map[string]string{"If-None-Match": `"0-stale"`, "If-Modified-Since": "Sun, 02 Nov 2025 00:00:00 GMT"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "update recipe with etag from get",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `"3-1beeebab9cdd6ec06e1dc0e617534063"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:    "update recipe matching one of several if-match tags",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `W/"3", "2", "3"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.AnythingOfType("*model.Recipe"), "").Return(&model.Recipe{Name: "Fluffy Pancakes", Version: 4}, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "update recipe with weak if-match",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `W/"3"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:    "update recipe with any if-match",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `*`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.AnythingOfType("*model.Recipe"), "").Return(&model.Recipe{Name: "Fluffy Pancakes", Version: 2}, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "list recipes not modified since the newest recipe",
+			method:  http.MethodGet,
+			uri:     "/",
+			headers: map[string]string{"If-Modified-Since": "Sat, 01 Nov 2025 19:32:00 GMT"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
+			},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:    "list recipes modified since",
+			method:  http.MethodGet,
+			uri:     "/",
+			headers: map[string]string{"If-Modified-Since": "Sat, 01 Nov 2025 19:31:59 GMT"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListRecipes", store.RecipeFilter{}).Return(getListRecipeData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": getListRecipeData(), "total": 2},
+			wantETag: `^"[0-9a-f]{32}"$`,
+		},
+		{
+			name:    "update recipe matching if-match",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `"3"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.Name == "Fluffy Pancakes" && r.Version == 3
+				}), "").Return(&model.Recipe{Name: "Fluffy Pancakes", Version: 4}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Fluffy Pancakes", Version: 4}},
+			wantETag: `^"4"$`,
+		},
+		{
+			name:    "update recipe with stale if-match",
+			method:  http.MethodPut,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			headers: map[string]string{"If-Match": `"2"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:   "update recipe changed concurrently",
+			method: http.MethodPut,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:   strings.NewReader(`{"name": "Fluffy Pancakes"}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.AnythingOfType("*model.Recipe"), "").Return(nil, store.ErrVersionConflict)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:   "update recipe keeps its own item ids",
+			method: http.MethodPut,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data: strings.NewReader(`{
+				"ingredients": [
+					{"lineId": "l1", "id": "i1", "quantity": 3, "unit": "cup"},
+					{"lineId": "l1", "id": "i2", "quantity": 1, "unit": "cup"},
+					{"lineId": "other-recipe", "id": "i3", "quantity": 2}
+				],
+				"instructions": [
+					{"id": "s2", "stepNumber": 1, "description": "Melt the butter."},
+					{"stepNumber": 2, "description": "Whisk everything together."}
+				]
+			}`),
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Ingredients[0].LineID = "l1"
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					lines, steps := r.Ingredients, r.Instructions
+					return len(lines) == 3 && lines[0].LineID == "l1" &&
+						lines[1].LineID != "l1" && lines[1].LineID != "" &&
+						lines[2].LineID != "other-recipe" && lines[2].LineID != "" &&
+						len(steps) == 2 && steps[0].ID == "s2" && steps[1].ID != ""
+				}), "").Return(&model.Recipe{Name: "Classic Pancakes"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Classic Pancakes"}},
+		},
+		{
+			name:    "patch recipe with unknown tag",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`[{"op": "add", "path": "/tags/-", "value": {"id": "gone"}}]`),
+			headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.AnythingOfType("*model.Recipe"), "").Return(nil, &store.ReferenceError{
+					Missing: []model.MissingReference{{Field: "/tags/2/id", ID: "gone"}},
+				})
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: util.Envelope{
+				"error":   "recipe refers to records that do not exist",
+				"missing": []model.MissingReference{{Field: "/tags/2/id", ID: "gone"}},
+			},
+		},
+		{
+			name:    "merge patch recipe",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"servings": 6, "cookTimeSeconds": 600}`),
+			userID:  userID,
+			headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.Name == "Classic Pancakes" && r.Servings == 6 && r.CookTimeSeconds == 600 &&
+						len(r.Ingredients) == 4 && len(r.Instructions) == 3 && len(r.Tags) == 2
+				}), userID).Return(&model.Recipe{Name: "Classic Pancakes", Version: 1}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Classic Pancakes", Version: 1}},
+			wantETag: `^"1"$`,
+		},
+		{
+			name:    "json patch one ingredient",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`[{"op": "test", "path": "/ingredients/1/id", "value": "i2"}, {"op": "replace", "path": "/ingredients/1/quantity", "value": 1.5}]`),
+			headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return len(r.Ingredients) == 4 && r.Ingredients[1].ID == "i2" && r.Ingredients[1].Quantity == 1.5 &&
+						r.Ingredients[0].Quantity == 2 && r.Ingredients[1].LineID != ""
+				}), "").Return(&model.Recipe{Name: "Classic Pancakes"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Classic Pancakes"}},
+		},
+		{
+			name:    "json patch inserting a step",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`[{"op": "add", "path": "/instructions/1", "value": {"description": "Rest the batter."}}]`),
+			headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return len(r.Instructions) == 4 && r.Instructions[1].Description == "Rest the batter." &&
+						r.Instructions[1].ID != "" && r.Instructions[1].StepNumber == 2 &&
+						r.Instructions[3].ID == "s3" && r.Instructions[3].StepNumber == 4
+				}), "").Return(&model.Recipe{Name: "Classic Pancakes"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Classic Pancakes"}},
+		},
+		{
+			name:    "json patch failing a test",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`[{"op": "test", "path": "/ingredients/1/id", "value": "i3"}, {"op": "remove", "path": "/ingredients/1"}]`),
+			headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": `operation 0: patch does not apply: test failed at "/ingredients/1/id"`},
+		},
+		{
+			name:    "json patch with unknown op",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`[{"op": "increment", "path": "/servings"}]`),
+			headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": `operation 0: invalid patch: unknown op "increment"`},
+		},
+		{
+			name:    "patch read-only field",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"version": 9}`),
+			headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": `patched recipe is invalid: json: unknown field "version"`},
+		},
+		{
+			name:    "patch into an invalid recipe",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"name": null}`),
+			headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "name cannot be blank"},
+		},
+		{
+			name:    "patch with stale if-match",
+			method:  http.MethodPatch,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:    strings.NewReader(`{"servings": 6}`),
+			headers: map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"2"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:      "patch with plain json",
+			method:    http.MethodPatch,
+			uri:       "/019a40de-02cd-7865-84ae-c038b75596f5",
+			data:      strings.NewReader(`{"servings": 6}`),
+			headers:   map[string]string{"Content-Type": "application/json"},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusUnsupportedMediaType,
+			wantBody:  util.Envelope{"error": "unsupported patch format"},
+		},
+		{
+			name:    "delete recipe with stale if-match",
+			method:  http.MethodDelete,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			headers: map[string]string{"If-Match": `"2"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("DeleteRecipe", "019a40de-02cd-7865-84ae-c038b75596f5", 2, false).Return(store.ErrVersionConflict)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:    "delete recipe matching one of several if-match tags",
+			method:  http.MethodDelete,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			headers: map[string]string{"If-Match": `"2", "3"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				pancakes.Version = 3
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("DeleteRecipe", pancakes.ID, 3, false).Return(nil)
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:    "delete recipe with weak if-match",
+			method:  http.MethodDelete,
+			uri:     "/019a40de-02cd-7865-84ae-c038b75596f5",
+			headers: map[string]string{"If-Match": `W/"2"`},
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("DeleteRecipe", "019a40de-02cd-7865-84ae-c038b75596f5", -1, false).Return(store.ErrVersionConflict)
+			},
+			wantCode: http.StatusPreconditionFailed,
+			wantBody: util.Envelope{"error": "recipe has changed since it was fetched"},
+		},
+		{
+			name:   "sync recipes",
+			method: http.MethodGet,
+			uri:    "/sync?since=41",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("SyncRecipes", int64(41)).Return(getSyncData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"recipes": getSyncData().Recipes,
+				"deleted": getSyncData().Deleted,
+				"cursor":  43,
+			},
+		},
+		{
+			name:   "sync everything",
+			method: http.MethodGet,
+			uri:    "/sync",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("SyncRecipes", int64(0)).Return(getSyncData(), nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "sync with invalid cursor",
+			method:    http.MethodGet,
+			uri:       "/sync?since=yesterday",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "invalid sync cursor"},
+		},
+		{
+			name:   "list trash",
+			method: http.MethodGet,
+			uri:    "/trash",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("ListDeletedRecipes").Return(getTrashData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipes": getTrashData(), "total": 1},
+		},
+		{
+			name:   "restore recipe from trash",
+			method: http.MethodPost,
+			uri:    "/trash/019a40de-02cd-7865-84ae-c038b75596f5/restore",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("RestoreRecipe", pancakes.ID).Return(nil)
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": getListRecipeData()[0]},
+		},
+		{
+			name:   "restore recipe using a trashed sub-recipe",
+			method: http.MethodPost,
+			uri:    "/trash/019a40de-02cd-7865-84ae-c038b75596f5/restore",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("RestoreRecipe", "019a40de-02cd-7865-84ae-c038b75596f5").Return(store.ErrDeletedSubRecipe)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "recipe uses a sub-recipe that is in the trash; restore it first"},
+		},
+		{
+			name:   "restore recipe not in trash",
+			method: http.MethodPost,
+			uri:    "/trash/019a40de-02cd-7865-84ae-c038b75596f5/restore",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("RestoreRecipe", "019a40de-02cd-7865-84ae-c038b75596f5").Return(sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "purge recipe",
+			method: http.MethodDelete,
+			uri:    "/trash/019a40de-02cd-7865-84ae-c038b75596f5",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("PurgeRecipe", "019a40de-02cd-7865-84ae-c038b75596f5").Return(nil)
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:   "purge recipe used by trashed recipes",
+			method: http.MethodDelete,
+			uri:    "/trash/019a40de-02cd-7865-84ae-c038b75596f5",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("PurgeRecipe", "019a40de-02cd-7865-84ae-c038b75596f5").Return(store.ErrRecipeInUse)
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "recipe is used as a sub-recipe by recipes in the trash; purge them first"},
+		},
+		{
+			name:   "list revisions",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/revisions",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				pancakes := getListRecipeData()[0]
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("ListRevisions", pancakes.ID).Return(getRevisionData(), nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"revisions": getRevisionData(), "total": 2},
+		},
+		{
+			name:   "get revision",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/revisions/1",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				revision := getRevisionData()[0]
+				m.On("GetRevision", revision.RecipeID, 1).Return(&revision, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"revision": getRevisionData()[0]},
+		},
+		{
+			name:   "get missing revision",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/revisions/3",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				m.On("GetRevision", "019a40de-02cd-7865-84ae-c038b75596f5", 3).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "get revision with invalid number",
+			method:    http.MethodGet,
+			uri:       "/019a40de-02cd-7865-84ae-c038b75596f5/revisions/latest",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {},
+			wantCode:  http.StatusBadRequest,
+			wantBody:  util.Envelope{"error": "invalid revision number"},
+		},
+		{
+			name:   "diff revisions",
+			method: http.MethodGet,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/revisions/diff?from=1&to=2",
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				revisions := getRevisionData()
+				m.On("GetRevision", revisions[0].RecipeID, 1).Return(&revisions[0], nil)
+				m.On("GetRevision", revisions[1].RecipeID, 2).Return(&revisions[1], nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"from": 1,
+				"to":   2,
+				"diff": model.RecipeDiff{
+					Fields:       []model.FieldChange{{Field: "name", From: "Pancakes", To: "Classic Pancakes"}},
+					Ingredients:  []model.IngredientChange{},
+					Instructions: []model.InstructionChange{},
+				},
+			},
+		},
+		{
+			name:   "restore revision",
+			method: http.MethodPost,
+			uri:    "/019a40de-02cd-7865-84ae-c038b75596f5/revisions/1/restore",
+			userID: userID,
+			setupMock: func(m *MockRecipeStore, _ *MockPriceStore, _ *MockNutritionStore, _ *MockUserStore) {
+				revision := getRevisionData()[0]
+				pancakes := getListRecipeData()[0]
+				m.On("GetRevision", pancakes.ID, 1).Return(&revision, nil)
+				m.On("GetRecipeByID", pancakes.ID).Return(&pancakes, nil)
+				m.On("UpdateRecipe", mock.MatchedBy(func(r *model.Recipe) bool {
+					return r.ID == pancakes.ID && r.Name == "Pancakes" && r.TimesCooked == pancakes.TimesCooked
+				}), userID).Return(&model.Recipe{Name: "Pancakes"}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"recipe": &model.Recipe{Name: "Pancakes"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -119,18 +1190,31 @@ func TestRecipeHandler(t *testing.T) {
 			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 
 			mockStore := &MockRecipeStore{}
-			tt.setupMock(mockStore)
+			priceStore := &MockPriceStore{}
+			nutritionStore := &MockNutritionStore{}
+			userStore := &MockUserStore{}
+			tt.setupMock(mockStore, priceStore, nutritionStore, userStore)
 
-			h := handler.NewRecipeHandler(logger, mockStore)
+			h := handler.NewRecipeHandler(logger, mockStore, priceStore, nutritionStore, userStore, nil)
 
 			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
 			r.Mount("/", h.Routes())
 
 			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
 			w := httptest.NewRecorder()
 			r.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantETag != "" {
+				assert.Regexp(t, tt.wantETag, w.Header().Get("ETag"))
+			}
 
 			if tt.wantBody != nil {
 				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
@@ -140,10 +1224,44 @@ func TestRecipeHandler(t *testing.T) {
 			}
 
 			mockStore.AssertExpectations(t)
+			priceStore.AssertExpectations(t)
+			nutritionStore.AssertExpectations(t)
+			userStore.AssertExpectations(t)
 		})
 	}
 }
 
+func getSyncData() *model.RecipeSync {
+	deletedAt, _ := time.Parse(time.RFC3339, "2025-11-02T08:00:00Z")
+
+	return &model.RecipeSync{
+		Recipes: getListRecipeData()[:1],
+		Deleted: []model.RecipeTombstone{{ID: "019a40de-02cd-7bc7-b171-710c99947f08", DeletedAt: deletedAt}},
+		Cursor:  43,
+	}
+}
+
+func getTrashData() []model.Recipe {
+	pancakes := getListRecipeData()[0]
+	deletedAt := pancakes.UpdatedAt.Add(time.Hour)
+	pancakes.DeletedAt = &deletedAt
+	return []model.Recipe{pancakes}
+}
+
+func getRevisionData() []model.RecipeRevision {
+	baseTime, _ := time.Parse(time.RFC3339, "2025-11-01T19:32:00Z")
+	authorID := "019a40de-02cd-7bc7-b171-710c99947f08"
+
+	original := getListRecipeData()[0]
+	original.Name = "Pancakes"
+	current := getListRecipeData()[0]
+
+	return []model.RecipeRevision{
+		{RecipeID: original.ID, Number: 1, CreatedAt: baseTime, Recipe: &original},
+		{RecipeID: current.ID, Number: 2, AuthorID: &authorID, CreatedAt: baseTime.Add(time.Hour), Recipe: &current},
+	}
+}
+
 func getListRecipeData() []model.Recipe {
 	baseTime, _ := time.Parse(time.RFC3339, "2025-11-01T19:32:00Z")
 
@@ -211,10 +1329,10 @@ func getNewRecipeData() io.Reader {
 		"prepTimeSeconds": 600,
 		"cookTimeSeconds": 900,
 		"ingredients": [
-			{"ingredientId": "i1", "quantity": 2, "unit": "cup", "note": "All-purpose"},
-			{"ingredientId": "i2", "quantity": 1, "unit": "cup", "note": "Whole"},
-			{"ingredientId": "i3", "quantity": 2, "unit": "", "note": "Large"},
-			{"ingredientId": "i4", "quantity": 2, "unit": "tbsp", "note": "Melted"}
+			{"id": "i1", "quantity": 2, "unit": "cup", "note": "All-purpose"},
+			{"id": "i2", "quantity": 1, "unit": "cup", "note": "Whole"},
+			{"id": "i3", "quantity": 2, "unit": "", "note": "Large"},
+			{"id": "i4", "quantity": 2, "unit": "tbsp", "note": "Melted"}
 		],
 		"instructions": [
 			{"stepNumber": 1, "description": "Whisk together flour, milk, and eggs in a large bowl."},
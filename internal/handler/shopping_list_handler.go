@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+type ShoppingListHandler struct {
+	logger            *slog.Logger
+	shoppingListStore store.ShoppingListStore
+}
+
+func NewShoppingListHandler(l *slog.Logger, ss store.ShoppingListStore) *ShoppingListHandler {
+	return &ShoppingListHandler{logger: l, shoppingListStore: ss}
+}
+
+func (h *ShoppingListHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Route("/active", func(r chi.Router) {
+		r.Get("/", h.GetActiveShoppingList)
+		r.Post("/complete", h.CompleteActiveShoppingList)
+		r.Post("/items", h.AddShoppingListItem)
+		r.Put("/items/{id}", h.UpdateShoppingListItem)
+		r.Delete("/items/{id}", h.DeleteShoppingListItem)
+	})
+
+	return r
+}
+
+func (h *ShoppingListHandler) GetActiveShoppingList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.shoppingListStore.GetActiveShoppingList()
+	if err != nil {
+		h.logger.Error("GetActiveShoppingList", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch shopping list"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"shoppingList": list})
+}
+
+// CompleteActiveShoppingList closes the active list. The next item added,
+// by hand or for low stock, starts a new list.
+func (h *ShoppingListHandler) CompleteActiveShoppingList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.shoppingListStore.CompleteActiveShoppingList()
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("CompleteActiveShoppingList", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to complete shopping list"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"shoppingList": list})
+}
+
+func (h *ShoppingListHandler) AddShoppingListItem(w http.ResponseWriter, r *http.Request) {
+	var item model.ShoppingListEntry
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		h.logger.Error("AddShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if item.IngredientID == "" {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient id cannot be blank"})
+		return
+	}
+	if item.Quantity < 0 {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "quantity cannot be a negative value"})
+		return
+	}
+	item.Source = store.ShoppingListItemSourceManual
+	item.Checked = false
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("AddShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	item.ID = id
+
+	createdItem, err := h.shoppingListStore.AddShoppingListItem(&item)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("AddShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to add shopping list item"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"item": createdItem})
+}
+
+func (h *ShoppingListHandler) UpdateShoppingListItem(w http.ResponseWriter, r *http.Request) {
+	itemID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("UpdateShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid shopping list item id"})
+		return
+	}
+
+	existingItem, err := h.shoppingListStore.GetShoppingListItemByID(itemID)
+	if err != nil {
+		h.logger.Error("UpdateShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch shopping list item"})
+		return
+	}
+	if existingItem == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var itemUpdateRequest struct {
+		Quantity *float64 `json:"quantity"`
+		Unit     *string  `json:"unit"`
+		Note     *string  `json:"note"`
+		Checked  *bool    `json:"checked"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&itemUpdateRequest)
+	if err != nil {
+		h.logger.Error("UpdateShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if itemUpdateRequest.Quantity != nil {
+		existingItem.Quantity = *itemUpdateRequest.Quantity
+	}
+	if itemUpdateRequest.Unit != nil {
+		existingItem.Unit = *itemUpdateRequest.Unit
+	}
+	if itemUpdateRequest.Note != nil {
+		existingItem.Note = *itemUpdateRequest.Note
+	}
+	if itemUpdateRequest.Checked != nil {
+		existingItem.Checked = *itemUpdateRequest.Checked
+	}
+
+	if existingItem.Quantity < 0 {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "quantity cannot be a negative value"})
+		return
+	}
+
+	updatedItem, err := h.shoppingListStore.UpdateShoppingListItem(existingItem)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("UpdateShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to update shopping list item"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"item": updatedItem})
+}
+
+func (h *ShoppingListHandler) DeleteShoppingListItem(w http.ResponseWriter, r *http.Request) {
+	itemID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid shopping list item id"})
+		return
+	}
+
+	err = h.shoppingListStore.DeleteShoppingListItem(itemID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteShoppingListItem", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete shopping list item"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,201 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockShoppingListStore struct {
+	mock.Mock
+}
+
+func (m *MockShoppingListStore) GetActiveShoppingList() (*model.ShoppingList, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ShoppingList), args.Error(1)
+}
+func (m *MockShoppingListStore) CompleteActiveShoppingList() (*model.ShoppingList, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ShoppingList), args.Error(1)
+}
+func (m *MockShoppingListStore) GetShoppingListItemByID(id string) (*model.ShoppingListEntry, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ShoppingListEntry), args.Error(1)
+}
+func (m *MockShoppingListStore) AddShoppingListItem(e *model.ShoppingListEntry) (*model.ShoppingListEntry, error) {
+	args := m.Called(e)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ShoppingListEntry), args.Error(1)
+}
+func (m *MockShoppingListStore) UpdateShoppingListItem(e *model.ShoppingListEntry) (*model.ShoppingListEntry, error) {
+	args := m.Called(e)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ShoppingListEntry), args.Error(1)
+}
+func (m *MockShoppingListStore) DeleteShoppingListItem(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// tests
+
+func TestShoppingListHandler(t *testing.T) {
+	itemID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	milk := &model.ShoppingListEntry{ID: itemID, IngredientID: "milk", Name: "Milk", Quantity: 1, Unit: "l", Source: store.ShoppingListItemSourceLowStock}
+	list := &model.ShoppingList{ID: "list", Status: "active", Items: []model.ShoppingListEntry{*milk}}
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		setupMock func(*MockShoppingListStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "get active list",
+			method: http.MethodGet,
+			uri:    "/active",
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("GetActiveShoppingList").Return(list, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"shoppingList": list},
+		},
+		{
+			name:   "complete without an active list",
+			method: http.MethodPost,
+			uri:    "/active/complete",
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("CompleteActiveShoppingList").Return(nil, sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "add item",
+			method: http.MethodPost,
+			uri:    "/active/items",
+			data:   strings.NewReader(`{"ingredientId": "milk", "quantity": 1, "unit": "l", "source": "low stock", "checked": true}`),
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("AddShoppingListItem", mock.MatchedBy(func(e *model.ShoppingListEntry) bool {
+					return e.ID != "" && e.Source == store.ShoppingListItemSourceManual && !e.Checked
+				})).Return(milk, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"item": milk},
+		},
+		{
+			name:   "add item with unknown ingredient",
+			method: http.MethodPost,
+			uri:    "/active/items",
+			data:   strings.NewReader(`{"ingredientId": "nope", "quantity": 1}`),
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("AddShoppingListItem", mock.AnythingOfType("*model.ShoppingListEntry")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:     "add item without ingredient",
+			method:   http.MethodPost,
+			uri:      "/active/items",
+			data:     strings.NewReader(`{"quantity": 1}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient id cannot be blank"},
+		},
+		{
+			name:   "check item",
+			method: http.MethodPut,
+			uri:    "/active/items/" + itemID,
+			data:   strings.NewReader(`{"checked": true}`),
+			setupMock: func(m *MockShoppingListStore) {
+				existing := *milk
+				m.On("GetShoppingListItemByID", itemID).Return(&existing, nil)
+				m.On("UpdateShoppingListItem", mock.MatchedBy(func(e *model.ShoppingListEntry) bool {
+					return e.Checked && e.Quantity == 1
+				})).Return(&model.ShoppingListEntry{ID: itemID, Checked: true}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"item": &model.ShoppingListEntry{ID: itemID, Checked: true}},
+		},
+		{
+			name:   "update missing item",
+			method: http.MethodPut,
+			uri:    "/active/items/" + itemID,
+			data:   strings.NewReader(`{"checked": true}`),
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("GetShoppingListItemByID", itemID).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "delete item",
+			method: http.MethodDelete,
+			uri:    "/active/items/" + itemID,
+			setupMock: func(m *MockShoppingListStore) {
+				m.On("DeleteShoppingListItem", itemID).Return(nil)
+			},
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			shoppingListStore := &MockShoppingListStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(shoppingListStore)
+			}
+
+			h := handler.NewShoppingListHandler(logger, shoppingListStore)
+
+			r := chi.NewRouter()
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			shoppingListStore.AssertExpectations(t)
+		})
+	}
+}
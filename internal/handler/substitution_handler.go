@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
+	"github.com/stevmwhitfield/recipe-api/internal/substitution"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+type SubstitutionHandler struct {
+	logger            *slog.Logger
+	substitutionStore store.SubstitutionStore
+	recipeStore       store.RecipeStore
+	pantryStore       store.PantryStore
+	ingredientStore   store.IngredientStore
+	userStore         store.UserStore
+}
+
+func NewSubstitutionHandler(l *slog.Logger, ss store.SubstitutionStore, rs store.RecipeStore, ps store.PantryStore, is store.IngredientStore, us store.UserStore) *SubstitutionHandler {
+	return &SubstitutionHandler{
+		logger:            l,
+		substitutionStore: ss,
+		recipeStore:       rs,
+		pantryStore:       ps,
+		ingredientStore:   is,
+		userStore:         us,
+	}
+}
+
+func (h *SubstitutionHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListSubstitutions)
+	r.Post("/", h.CreateSubstitution)
+
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", h.GetSubstitutionByID)
+		r.Delete("/", h.DeleteSubstitution)
+	})
+
+	return r
+}
+
+func (h *SubstitutionHandler) ListSubstitutions(w http.ResponseWriter, r *http.Request) {
+	ingredientID := r.URL.Query().Get("ingredientId")
+	if ingredientID != "" {
+		if _, err := uuid.Parse(ingredientID); err != nil {
+			util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid id filter"})
+			return
+		}
+	}
+
+	substitutions, err := h.substitutionStore.ListSubstitutions(ingredientID)
+	if err != nil {
+		h.logger.Error("ListSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch substitutions"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"substitutions": substitutions, "total": len(substitutions)})
+}
+
+func (h *SubstitutionHandler) CreateSubstitution(w http.ResponseWriter, r *http.Request) {
+	var sub model.Substitution
+	err := json.NewDecoder(r.Body).Decode(&sub)
+	if err != nil {
+		h.logger.Error("CreateSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if err := validateSubstitution(&sub); err != nil {
+		h.logger.Error("CreateSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	sub.ID = id
+
+	createdSubstitution, err := h.substitutionStore.CreateSubstitution(&sub)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("CreateSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create substitution"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"substitution": createdSubstitution})
+}
+
+func (h *SubstitutionHandler) GetSubstitutionByID(w http.ResponseWriter, r *http.Request) {
+	substitutionID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("GetSubstitutionByID", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid substitution id"})
+		return
+	}
+
+	sub, err := h.substitutionStore.GetSubstitutionByID(substitutionID)
+	if err != nil {
+		h.logger.Error("GetSubstitutionByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch substitution"})
+		return
+	}
+	if sub == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"substitution": sub})
+}
+
+func (h *SubstitutionHandler) DeleteSubstitution(w http.ResponseWriter, r *http.Request) {
+	substitutionID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("DeleteSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid substitution id"})
+		return
+	}
+
+	err = h.substitutionStore.DeleteSubstitution(substitutionID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("DeleteSubstitution", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to delete substitution"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SuggestSubstitutions previews the recipe in the url with substitutions
+// for ingredients missing from the pantry and, for identified callers,
+// ingredients that conflict with their dietary profile unless
+// applyProfile=false. Nothing is saved.
+func (h *SubstitutionHandler) SuggestSubstitutions(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid recipe id"})
+		return
+	}
+
+	applyProfile, err := readApplyProfile(r)
+	if err != nil {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	recipe, err := h.recipeStore.GetRecipeByID(recipeID)
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch recipe"})
+		return
+	}
+	if recipe == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sub-recipes are substituted in line by line.
+	recipe, err = subrecipe.Expand(recipe, h.recipeStore.GetRecipeByID)
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch sub-recipes"})
+		return
+	}
+
+	var profile *model.DietaryProfile
+	if applyProfile {
+		profile, err = readDietaryProfile(r, h.userStore)
+		if err != nil {
+			h.logger.Error("SuggestSubstitutions", "error", err)
+			util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch dietary profile"})
+			return
+		}
+	}
+
+	stock, err := h.pantryStore.ListStockedIngredients()
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch pantry"})
+		return
+	}
+
+	rules, err := h.substitutionStore.ListSubstitutions("")
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch substitutions"})
+		return
+	}
+
+	catalog, err := h.ingredientStore.ListIngredients()
+	if err != nil {
+		h.logger.Error("SuggestSubstitutions", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch ingredients"})
+		return
+	}
+
+	preview := substitution.Suggest(recipe, rules, catalog, stock, profile)
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"preview": preview.Recipe, "suggestions": preview.Suggestions, "total": len(preview.Suggestions)})
+}
+
+func validateSubstitution(s *model.Substitution) error {
+	if s.IngredientID == "" {
+		return errors.New("ingredient id cannot be blank")
+	}
+
+	if len(s.Components) == 0 {
+		return errors.New("substitution needs at least one component")
+	}
+
+	seen := map[string]bool{}
+	for _, c := range s.Components {
+		if c.IngredientID == "" {
+			return errors.New("component ingredient id cannot be blank")
+		}
+		if c.IngredientID == s.IngredientID {
+			return errors.New("an ingredient cannot substitute itself")
+		}
+		if seen[c.IngredientID] {
+			return errors.New("components must be different ingredients")
+		}
+		seen[c.IngredientID] = true
+
+		if c.Ratio <= 0 {
+			return errors.New("ratio must be greater than zero")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockSubstitutionStore struct {
+	mock.Mock
+}
+
+func (m *MockSubstitutionStore) ListSubstitutions(ingredientID string) ([]model.Substitution, error) {
+	args := m.Called(ingredientID)
+	return args.Get(0).([]model.Substitution), args.Error(1)
+}
+func (m *MockSubstitutionStore) GetSubstitutionByID(id string) (*model.Substitution, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Substitution), args.Error(1)
+}
+func (m *MockSubstitutionStore) CreateSubstitution(s *model.Substitution) (*model.Substitution, error) {
+	args := m.Called(s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Substitution), args.Error(1)
+}
+func (m *MockSubstitutionStore) DeleteSubstitution(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// tests
+
+func TestSubstitutionHandler(t *testing.T) {
+	substitutionID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	recipeID := "019a40de-02cd-7865-84ae-c038b75596f5"
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+	butterID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+	oilID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d91"
+	butter := &model.Substitution{
+		ID:           substitutionID,
+		IngredientID: butterID,
+		Name:         "Butter",
+		Components:   []model.SubstitutionComponent{{IngredientID: oilID, Name: "Olive Oil", Ratio: 0.75}},
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		userID    string
+		setupMock func(*MockSubstitutionStore, *MockRecipeStore, *MockPantryStore, *MockIngredientStore, *MockUserStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list substitutions for ingredient",
+			method: http.MethodGet,
+			uri:    "/substitutions?ingredientId=" + butterID,
+			setupMock: func(m *MockSubstitutionStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockIngredientStore, _ *MockUserStore) {
+				m.On("ListSubstitutions", butterID).Return([]model.Substitution{*butter}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"substitutions": []model.Substitution{*butter}, "total": 1},
+		},
+		{
+			name:     "list substitutions with invalid filter",
+			method:   http.MethodGet,
+			uri:      "/substitutions?ingredientId=butter",
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "invalid id filter"},
+		},
+		{
+			name:   "create substitution",
+			method: http.MethodPost,
+			uri:    "/substitutions",
+			data:   strings.NewReader(`{"ingredientId": "` + butterID + `", "components": [{"ingredientId": "` + oilID + `", "ratio": 0.75}]}`),
+			setupMock: func(m *MockSubstitutionStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockIngredientStore, _ *MockUserStore) {
+				m.On("CreateSubstitution", mock.MatchedBy(func(s *model.Substitution) bool {
+					return s.ID != "" && s.IngredientID == butterID && len(s.Components) == 1 && s.Components[0].Ratio == 0.75
+				})).Return(butter, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"substitution": butter},
+		},
+		{
+			name:     "create substitution without components",
+			method:   http.MethodPost,
+			uri:      "/substitutions",
+			data:     strings.NewReader(`{"ingredientId": "` + butterID + `", "components": []}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "substitution needs at least one component"},
+		},
+		{
+			name:     "create substitution of itself",
+			method:   http.MethodPost,
+			uri:      "/substitutions",
+			data:     strings.NewReader(`{"ingredientId": "` + butterID + `", "components": [{"ingredientId": "` + butterID + `", "ratio": 1}]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "an ingredient cannot substitute itself"},
+		},
+		{
+			name:     "create substitution with zero ratio",
+			method:   http.MethodPost,
+			uri:      "/substitutions",
+			data:     strings.NewReader(`{"ingredientId": "` + butterID + `", "components": [{"ingredientId": "` + oilID + `", "ratio": 0}]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ratio must be greater than zero"},
+		},
+		{
+			name:   "create substitution with unknown ingredient",
+			method: http.MethodPost,
+			uri:    "/substitutions",
+			data:   strings.NewReader(`{"ingredientId": "` + butterID + `", "components": [{"ingredientId": "` + oilID + `", "ratio": 0.75}]}`),
+			setupMock: func(m *MockSubstitutionStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockIngredientStore, _ *MockUserStore) {
+				m.On("CreateSubstitution", mock.AnythingOfType("*model.Substitution")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+		{
+			name:   "delete missing substitution",
+			method: http.MethodDelete,
+			uri:    "/substitutions/" + substitutionID,
+			setupMock: func(m *MockSubstitutionStore, _ *MockRecipeStore, _ *MockPantryStore, _ *MockIngredientStore, _ *MockUserStore) {
+				m.On("DeleteSubstitution", substitutionID).Return(sql.ErrNoRows)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "suggest substitutions",
+			method: http.MethodGet,
+			uri:    "/recipes/" + recipeID + "/substitutions",
+			userID: userID,
+			setupMock: func(m *MockSubstitutionStore, rs *MockRecipeStore, ps *MockPantryStore, is *MockIngredientStore, us *MockUserStore) {
+				recipe := getListRecipeData()[0]
+				recipe.Ingredients = []model.Ingredient{{ID: butterID, Name: "Butter", Quantity: 100, Unit: "g"}}
+				rs.On("GetRecipeByID", recipeID).Return(&recipe, nil)
+				us.On("GetDietaryProfile", userID).Return(&model.DietaryProfile{UserID: userID, Allergies: []string{"dairy"}}, nil)
+				ps.On("ListStockedIngredients").Return([]model.StockedIngredient{
+					{ID: "s1", IngredientID: butterID, Quantity: 250, Unit: "g"},
+				}, nil)
+				m.On("ListSubstitutions", "").Return([]model.Substitution{*butter}, nil)
+				is.On("ListIngredients").Return([]model.CatalogIngredient{
+					{ID: butterID, Name: "Butter", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+					{ID: oilID, Name: "Olive Oil", Allergens: []string{}, Diets: []string{"vegan", "vegetarian"}},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{
+				"preview": func() model.Recipe {
+					recipe := getListRecipeData()[0]
+					recipe.Ingredients = []model.Ingredient{{ID: oilID, Name: "Olive Oil", Quantity: 75, Unit: "g"}}
+					recipe.Sections = []model.Section{}
+					recipe.Allergens = []string{}
+					recipe.Diets = []string{"vegan", "vegetarian"}
+					return recipe
+				}(),
+				"suggestions": []model.SubstitutionSuggestion{{
+					IngredientID:   butterID,
+					Name:           "Butter",
+					Quantity:       100,
+					Unit:           "g",
+					Reasons:        []string{"contains dairy"},
+					SubstitutionID: substitutionID,
+					Replacement:    []model.Ingredient{{ID: oilID, Name: "Olive Oil", Quantity: 75, Unit: "g"}},
+				}},
+				"total": 1,
+			},
+		},
+		{
+			name:   "suggest substitutions for missing recipe",
+			method: http.MethodGet,
+			uri:    "/recipes/" + recipeID + "/substitutions",
+			setupMock: func(_ *MockSubstitutionStore, rs *MockRecipeStore, _ *MockPantryStore, _ *MockIngredientStore, _ *MockUserStore) {
+				rs.On("GetRecipeByID", recipeID).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			substitutionStore := &MockSubstitutionStore{}
+			recipeStore := &MockRecipeStore{}
+			pantryStore := &MockPantryStore{}
+			ingredientStore := &MockIngredientStore{}
+			userStore := &MockUserStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(substitutionStore, recipeStore, pantryStore, ingredientStore, userStore)
+			}
+
+			h := handler.NewSubstitutionHandler(logger, substitutionStore, recipeStore, pantryStore, ingredientStore, userStore)
+
+			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
+			r.Mount("/substitutions", h.Routes())
+			r.Mount("/recipes", handler.NewRecipeHandler(logger, recipeStore, nil, nil, userStore, h).Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			substitutionStore.AssertExpectations(t)
+			recipeStore.AssertExpectations(t)
+			pantryStore.AssertExpectations(t)
+			ingredientStore.AssertExpectations(t)
+			userStore.AssertExpectations(t)
+		})
+	}
+}
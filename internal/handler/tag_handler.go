@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stevmwhitfield/recipe-api/internal/model"
@@ -30,6 +31,9 @@ func (th *TagHandler) Routes() chi.Router {
 	return r
 }
 
+// ListTags supports conditional requests; its Last-Modified is when the
+// newest tag was created. Tags are the same for everyone, so shared caches
+// may keep them, revalidating each time.
 func (th *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
 	tags, err := th.tagStore.ListTags()
 	if err != nil {
@@ -38,7 +42,17 @@ func (th *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	util.WriteJSON(w, http.StatusOK, util.Envelope{"tags": tags, "total": len(tags)})
+	var lastModified time.Time
+	for _, tag := range tags {
+		if tag.CreatedAt.After(lastModified) {
+			lastModified = tag.CreatedAt
+		}
+	}
+
+	util.WriteJSONConditional(w, r, util.Envelope{"tags": tags, "total": len(tags)}, util.CacheOptions{
+		CacheControl: "public, no-cache",
+		LastModified: lastModified,
+	})
 }
 
 func (th *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stevmwhitfield/recipe-api/internal/handler"
@@ -47,6 +48,7 @@ func TestTagHandler(t *testing.T) {
 		method    string
 		uri       string
 		data      io.Reader           // optional
+		headers   map[string]string   // optional
 		setupMock func(*MockTagStore) // optional
 		wantCode  int
 		wantBody  interface{}
@@ -64,6 +66,46 @@ func TestTagHandler(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantBody: util.Envelope{"tags": []model.Tag{{ID: "1", Name: "t1"}, {ID: "2", Name: "t2"}}, "total": 2},
 		},
+		{
+			name:    "list tags not modified",
+			method:  http.MethodGet,
+			uri:     "/",
+			headers: map[string]string{"If-None-Match": "*"},
+			setupMock: func(m *MockTagStore) {
+				m.On("ListTags").Return([]model.Tag{{ID: "1", Name: "t1"}}, nil)
+			},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:    "list tags not modified since the newest tag",
+			method:  http.MethodGet,
+			uri:     "/",
+			headers: map[string]string{"If-Modified-Since": "Sat, 01 Nov 2025 19:32:00 GMT"},
+			setupMock: func(m *MockTagStore) {
+				m.On("ListTags").Return([]model.Tag{
+					{ID: "1", Name: "t1", CreatedAt: time.Date(2025, 10, 31, 8, 0, 0, 0, time.UTC)},
+					{ID: "2", Name: "t2", CreatedAt: time.Date(2025, 11, 1, 19, 32, 0, 0, time.UTC)},
+				}, nil)
+			},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:    "list tags modified since",
+			method:  http.MethodGet,
+			uri:     "/",
+			headers: map[string]string{"If-Modified-Since": "Sat, 01 Nov 2025 08:00:00 GMT"},
+			setupMock: func(m *MockTagStore) {
+				m.On("ListTags").Return([]model.Tag{
+					{ID: "1", Name: "t1", CreatedAt: time.Date(2025, 10, 31, 8, 0, 0, 0, time.UTC)},
+					{ID: "2", Name: "t2", CreatedAt: time.Date(2025, 11, 1, 19, 32, 0, 0, time.UTC)},
+				}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"tags": []model.Tag{
+				{ID: "1", Name: "t1", CreatedAt: time.Date(2025, 10, 31, 8, 0, 0, 0, time.UTC)},
+				{ID: "2", Name: "t2", CreatedAt: time.Date(2025, 11, 1, 19, 32, 0, 0, time.UTC)},
+			}, "total": 2},
+		},
 		{
 			name:   "list tags with error",
 			method: http.MethodGet,
@@ -156,6 +198,9 @@ func TestTagHandler(t *testing.T) {
 			r.Mount("/", h.Routes())
 
 			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
 			w := httptest.NewRecorder()
 			r.ServeHTTP(w, req)
 
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+type UserHandler struct {
+	logger    *slog.Logger
+	userStore store.UserStore
+}
+
+func NewUserHandler(l *slog.Logger, us store.UserStore) *UserHandler {
+	return &UserHandler{logger: l, userStore: us}
+}
+
+func (h *UserHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(middleware.RequireUserID).Get("/", h.ListUsers)
+	r.Post("/", h.CreateUser)
+	r.Get("/{id}", h.GetUserByID)
+	r.With(middleware.RequireUserID).Get("/{id}/dietary-profile", h.GetDietaryProfile)
+	r.With(middleware.RequireUserID).Put("/{id}/dietary-profile", h.SetDietaryProfile)
+
+	return r
+}
+
+// ListUsers is only for identified callers. Ids alone no longer identify
+// anyone, but there is no reason to hand them to strangers.
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userStore.ListUsers()
+	if err != nil {
+		h.logger.Error("ListUsers", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch users"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"users": users, "total": len(users)})
+}
+
+// CreateUser issues the user's token, which is returned only here. Requests
+// send it as a bearer token to act as the user.
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var user model.User
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		h.logger.Error("CreateUser", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(user.Name) == "" {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "name cannot be blank"})
+		return
+	}
+
+	id, err := util.GenerateUUID()
+	if err != nil {
+		h.logger.Error("CreateUser", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate uuid"})
+		return
+	}
+	user.ID = id
+
+	token, err := util.GenerateToken()
+	if err != nil {
+		h.logger.Error("CreateUser", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to generate token"})
+		return
+	}
+	user.Token = token
+
+	createdUser, err := h.userStore.CreateUser(&user)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			h.logger.Error("CreateUser", "error", err)
+			util.WriteJSON(w, http.StatusConflict, util.Envelope{"error": "user with that name already exists"})
+			return
+		}
+		h.logger.Error("CreateUser", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to create user"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusCreated, util.Envelope{"user": createdUser})
+}
+
+func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("GetUserByID", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(userID)
+	if err != nil {
+		h.logger.Error("GetUserByID", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+		return
+	}
+	if user == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"user": user})
+}
+
+// GetDietaryProfile only shows callers their own profile.
+func (h *UserHandler) GetDietaryProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("GetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid user id"})
+		return
+	}
+	if userID != middleware.UserIDFromContext(r.Context()) {
+		util.WriteJSON(w, http.StatusForbidden, util.Envelope{"error": "cannot access another user's dietary profile"})
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(userID)
+	if err != nil {
+		h.logger.Error("GetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+		return
+	}
+	if user == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	profile, err := h.userStore.GetDietaryProfile(userID)
+	if err != nil {
+		h.logger.Error("GetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch dietary profile"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"profile": profile})
+}
+
+// SetDietaryProfile replaces the caller's whole profile; omitted lists are
+// cleared.
+func (h *UserHandler) SetDietaryProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.ReadIDParam(r)
+	if err != nil {
+		h.logger.Error("SetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid user id"})
+		return
+	}
+	if userID != middleware.UserIDFromContext(r.Context()) {
+		util.WriteJSON(w, http.StatusForbidden, util.Envelope{"error": "cannot change another user's dietary profile"})
+		return
+	}
+
+	var profile model.DietaryProfile
+	err = json.NewDecoder(r.Body).Decode(&profile)
+	if err != nil {
+		h.logger.Error("SetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	profile.UserID = userID
+	profile.Allergies = normalizeFlags(profile.Allergies)
+	profile.Diets = normalizeFlags(profile.Diets)
+	profile.DislikedIngredientIDs = normalizeFlags(profile.DislikedIngredientIDs)
+	if err := validateDietaryProfile(&profile); err != nil {
+		h.logger.Error("SetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(userID)
+	if err != nil {
+		h.logger.Error("SetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to fetch user"})
+		return
+	}
+	if user == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	updatedProfile, err := h.userStore.SetDietaryProfile(&profile)
+	if errors.Is(err, store.ErrUnknownIngredient) {
+		util.WriteJSON(w, http.StatusBadRequest, util.Envelope{"error": "ingredient does not exist"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("SetDietaryProfile", "error", err)
+		util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to update dietary profile"})
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, util.Envelope{"profile": updatedProfile})
+}
+
+func validateDietaryProfile(p *model.DietaryProfile) error {
+	for _, a := range p.Allergies {
+		if !validAllergens[a] {
+			return fmt.Errorf("unknown allergen %q", a)
+		}
+	}
+
+	for _, d := range p.Diets {
+		if !validDiets[d] {
+			return fmt.Errorf("unknown diet %q", d)
+		}
+	}
+
+	for _, id := range p.DislikedIngredientIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("invalid disliked ingredient id")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stevmwhitfield/recipe-api/internal/handler"
+	"github.com/stevmwhitfield/recipe-api/internal/middleware"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+
+type MockUserStore struct {
+	mock.Mock
+}
+
+func (m *MockUserStore) ListUsers() ([]model.User, error) {
+	args := m.Called()
+	return args.Get(0).([]model.User), args.Error(1)
+}
+func (m *MockUserStore) CreateUser(u *model.User) (*model.User, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+func (m *MockUserStore) GetUserByID(id string) (*model.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+func (m *MockUserStore) AuthenticateUser(token string) (string, error) {
+	args := m.Called(token)
+	return args.String(0), args.Error(1)
+}
+func (m *MockUserStore) GetDietaryProfile(userID string) (*model.DietaryProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DietaryProfile), args.Error(1)
+}
+func (m *MockUserStore) SetDietaryProfile(p *model.DietaryProfile) (*model.DietaryProfile, error) {
+	args := m.Called(p)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DietaryProfile), args.Error(1)
+}
+
+// tokenIsUserID lets handler tests send a user's id as their token.
+func tokenIsUserID(token string) (string, error) {
+	return token, nil
+}
+
+// tests
+
+func TestUserHandler(t *testing.T) {
+	userID := "019a40de-02cd-7bc7-b171-710c99947f08"
+	ingredientID := "019a40de-4a21-7d3c-9a0e-5b2f1c7e8d90"
+
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		data      io.Reader
+		userID    string // optional
+		setupMock func(*MockUserStore)
+		wantCode  int
+		wantBody  util.Envelope
+	}{
+		{
+			name:   "list users",
+			method: http.MethodGet,
+			uri:    "/",
+			userID: userID,
+			setupMock: func(m *MockUserStore) {
+				m.On("ListUsers").Return([]model.User{{ID: userID, Name: "sam"}}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"users": []model.User{{ID: userID, Name: "sam"}}, "total": 1},
+		},
+		{
+			name:     "list users anonymously",
+			method:   http.MethodGet,
+			uri:      "/",
+			wantCode: http.StatusUnauthorized,
+			wantBody: util.Envelope{"error": "a user token is required"},
+		},
+		{
+			name:   "create user",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "sam"}`),
+			setupMock: func(m *MockUserStore) {
+				m.On("CreateUser", mock.MatchedBy(func(u *model.User) bool {
+					return u.Name == "sam" && len(u.Token) == 64
+				})).Return(&model.User{ID: userID, Name: "sam", Token: "secret"}, nil)
+			},
+			wantCode: http.StatusCreated,
+			wantBody: util.Envelope{"user": &model.User{ID: userID, Name: "sam", Token: "secret"}},
+		},
+		{
+			name:   "create user with duplicate name",
+			method: http.MethodPost,
+			uri:    "/",
+			data:   strings.NewReader(`{"name": "sam"}`),
+			setupMock: func(m *MockUserStore) {
+				m.On("CreateUser", mock.AnythingOfType("*model.User")).Return(nil, errors.New("UNIQUE constraint failed: users.name"))
+			},
+			wantCode: http.StatusConflict,
+			wantBody: util.Envelope{"error": "user with that name already exists"},
+		},
+		{
+			name:     "create user with blank name",
+			method:   http.MethodPost,
+			uri:      "/",
+			data:     strings.NewReader(`{"name": ""}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "name cannot be blank"},
+		},
+		{
+			name:   "get missing user",
+			method: http.MethodGet,
+			uri:    "/" + userID,
+			setupMock: func(m *MockUserStore) {
+				m.On("GetUserByID", userID).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:   "get dietary profile",
+			method: http.MethodGet,
+			uri:    "/" + userID + "/dietary-profile",
+			userID: userID,
+			setupMock: func(m *MockUserStore) {
+				m.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+				m.On("GetDietaryProfile", userID).Return(&model.DietaryProfile{UserID: userID, Allergies: []string{"peanut"}, Diets: []string{}, DislikedIngredientIDs: []string{}}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"profile": &model.DietaryProfile{UserID: userID, Allergies: []string{"peanut"}, Diets: []string{}, DislikedIngredientIDs: []string{}}},
+		},
+		{
+			name:   "get dietary profile of missing user",
+			method: http.MethodGet,
+			uri:    "/" + userID + "/dietary-profile",
+			userID: userID,
+			setupMock: func(m *MockUserStore) {
+				m.On("GetUserByID", userID).Return(nil, nil)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "get another user's dietary profile",
+			method:   http.MethodGet,
+			uri:      "/" + ingredientID + "/dietary-profile",
+			userID:   userID,
+			wantCode: http.StatusForbidden,
+			wantBody: util.Envelope{"error": "cannot access another user's dietary profile"},
+		},
+		{
+			name:     "get dietary profile anonymously",
+			method:   http.MethodGet,
+			uri:      "/" + userID + "/dietary-profile",
+			wantCode: http.StatusUnauthorized,
+			wantBody: util.Envelope{"error": "a user token is required"},
+		},
+		{
+			name:     "set another user's dietary profile",
+			method:   http.MethodPut,
+			uri:      "/" + ingredientID + "/dietary-profile",
+			data:     strings.NewReader(`{"allergies": ["peanut"]}`),
+			userID:   userID,
+			wantCode: http.StatusForbidden,
+			wantBody: util.Envelope{"error": "cannot change another user's dietary profile"},
+		},
+		{
+			name:   "set dietary profile",
+			method: http.MethodPut,
+			uri:    "/" + userID + "/dietary-profile",
+			userID: userID,
+			data:   strings.NewReader(`{"allergies": ["Peanut", "dairy"], "diets": ["vegetarian"], "dislikedIngredientIds": ["` + ingredientID + `"]}`),
+			setupMock: func(m *MockUserStore) {
+				profile := &model.DietaryProfile{UserID: userID, Allergies: []string{"dairy", "peanut"}, Diets: []string{"vegetarian"}, DislikedIngredientIDs: []string{ingredientID}}
+				m.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+				m.On("SetDietaryProfile", profile).Return(profile, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: util.Envelope{"profile": &model.DietaryProfile{UserID: userID, Allergies: []string{"dairy", "peanut"}, Diets: []string{"vegetarian"}, DislikedIngredientIDs: []string{ingredientID}}},
+		},
+		{
+			name:     "set dietary profile with unknown allergen",
+			method:   http.MethodPut,
+			uri:      "/" + userID + "/dietary-profile",
+			userID:   userID,
+			data:     strings.NewReader(`{"allergies": ["celery"]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": `unknown allergen "celery"`},
+		},
+		{
+			name:     "set dietary profile with invalid ingredient id",
+			method:   http.MethodPut,
+			uri:      "/" + userID + "/dietary-profile",
+			userID:   userID,
+			data:     strings.NewReader(`{"dislikedIngredientIds": ["olives"]}`),
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "invalid disliked ingredient id"},
+		},
+		{
+			name:   "set dietary profile with unknown ingredient",
+			method: http.MethodPut,
+			uri:    "/" + userID + "/dietary-profile",
+			userID: userID,
+			data:   strings.NewReader(`{"dislikedIngredientIds": ["` + ingredientID + `"]}`),
+			setupMock: func(m *MockUserStore) {
+				m.On("GetUserByID", userID).Return(&model.User{ID: userID, Name: "sam"}, nil)
+				m.On("SetDietaryProfile", mock.AnythingOfType("*model.DietaryProfile")).Return(nil, store.ErrUnknownIngredient)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: util.Envelope{"error": "ingredient does not exist"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+			mockStore := &MockUserStore{}
+			if tt.setupMock != nil {
+				tt.setupMock(mockStore)
+			}
+
+			h := handler.NewUserHandler(logger, mockStore)
+
+			r := chi.NewRouter()
+			r.Use(middleware.UserIDCtx(tokenIsUserID))
+			r.Mount("/", h.Routes())
+
+			req := httptest.NewRequest(tt.method, tt.uri, tt.data)
+			if tt.userID != "" {
+				req.Header.Set(middleware.AuthorizationHeader, "Bearer "+tt.userID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != nil {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				assert.JSONEq(t, string(wantJSON), w.Body.String())
+			}
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
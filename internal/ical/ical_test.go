@@ -0,0 +1,59 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	stamp := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	events := []ical.Event{
+		{
+			UID:         "m1@recipe-api",
+			Start:       time.Date(2025, 11, 3, 7, 35, 0, 0, time.UTC),
+			End:         time.Date(2025, 11, 3, 8, 0, 0, 0, time.UTC),
+			Summary:     "Pancakes, classic; fluffy",
+			Description: "Servings: 4\nPrep time: 10 min",
+			URL:         "http://localhost/api/v1/recipes/r1",
+		},
+	}
+
+	var b strings.Builder
+	err := ical.Write(&b, "Kitchen", stamp, events)
+
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//recipe-api//meal plan//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Kitchen",
+		"BEGIN:VEVENT",
+		"UID:m1@recipe-api",
+		"DTSTAMP:20251101T120000Z",
+		"DTSTART:20251103T073500",
+		"DTEND:20251103T080000",
+		`SUMMARY:Pancakes\, classic\; fluffy`,
+		`DESCRIPTION:Servings: 4\nPrep time: 10 min`,
+		"URL:http://localhost/api/v1/recipes/r1",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), b.String())
+}
+
+func TestWrite_FoldsLongLines(t *testing.T) {
+	var b strings.Builder
+	err := ical.Write(&b, strings.Repeat("é", 80), time.Now(), nil)
+
+	assert.NoError(t, err)
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Contains(t, strings.ReplaceAll(b.String(), "\r\n ", ""), "X-WR-CALNAME:"+strings.Repeat("é", 80))
+}
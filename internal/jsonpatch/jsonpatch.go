@@ -0,0 +1,400 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for a patch document that is not well
+	// formed, such as an unknown op or a pointer without a leading slash.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrConflict is returned when a well-formed patch cannot be applied to
+	// the document, because a path does not exist or a test op failed.
+	ErrConflict = errors.New("patch does not apply")
+)
+
+// Operation is one step of an RFC 6902 JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to doc. Objects are
+// merged key by key and a null removes the key; anything else, including
+// an array, replaces the target outright.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc. The operations run in order
+// and the patch is applied in full or not at all.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for n, op := range ops {
+		target, err = apply(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", n, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := opValue(op)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "replace":
+		value, err := opValue(op)
+		if err != nil {
+			return nil, err
+		}
+		// The root always exists and cannot be removed, only replaced.
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, _, err = remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move %q into itself", ErrInvalidPatch, op.From)
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+
+	case "test":
+		value, err := opValue(op)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, value) {
+			return nil, fmt.Errorf("%w: test failed at %q", ErrConflict, op.Path)
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func opValue(op Operation) (any, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, op.Op)
+	}
+	value, err := decode(op.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for n, t := range tokens {
+		tokens[n] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch v := doc.(type) {
+		case map[string]any:
+			child, ok := v[token]
+			if !ok {
+				return nil, notFound(token)
+			}
+			doc = child
+		case []any:
+			n, err := arrayIndex(token, len(v)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[n]
+		default:
+			return nil, notFound(token)
+		}
+	}
+	return doc, nil
+}
+
+// add returns doc with value added at path. The path's parent must exist.
+// Adding to an array inserts before the index, and "-" appends.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch v := parent.(type) {
+	case map[string]any:
+		v[token] = value
+		return doc, nil
+	case []any:
+		n := len(v)
+		if token != "-" {
+			n, err = arrayIndex(token, len(v))
+			if err != nil {
+				return nil, err
+			}
+		}
+		v = append(v[:n], append([]any{value}, v[n:]...)...)
+		return set(doc, path[:len(path)-1], v)
+	default:
+		return nil, notFound(token)
+	}
+}
+
+// remove returns doc without the value at path, and that value.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch v := parent.(type) {
+	case map[string]any:
+		value, ok := v[token]
+		if !ok {
+			return nil, nil, notFound(token)
+		}
+		delete(v, token)
+		return doc, value, nil
+	case []any:
+		n, err := arrayIndex(token, len(v)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := v[n]
+		v = append(v[:n:n], v[n+1:]...)
+		doc, err = set(doc, path[:len(path)-1], v)
+		return doc, value, err
+	default:
+		return nil, nil, notFound(token)
+	}
+}
+
+// set replaces the value at an existing path. Arrays are values in Go, so
+// a grown or shrunk array has to be stored back in its parent.
+func set(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch v := parent.(type) {
+	case map[string]any:
+		v[token] = value
+	case []any:
+		n, err := arrayIndex(token, len(v)-1)
+		if err != nil {
+			return nil, err
+		}
+		v[n] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, which must be a plain decimal
+// number no greater than max.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	n, err := strconv.Atoi(token)
+	if err != nil || n > max {
+		return 0, fmt.Errorf("%w: array index %s out of range", ErrConflict, token)
+	}
+	return n, nil
+}
+
+func notFound(token string) error {
+	return fmt.Errorf("%w: path %q not found", ErrConflict, token)
+}
+
+// decode keeps numbers as json.Number so they survive the round trip
+// exactly.
+func decode(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, child := range v {
+			c[k] = deepCopy(child)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for n, child := range v {
+			c[n] = deepCopy(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// equal compares JSON values, treating numbers as equal when they have the
+// same value however they are written.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for n := range a {
+			if !equal(a[n], b[n]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,153 @@
+package jsonpatch_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/jsonpatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "replaces and removes keys",
+			doc:   `{"name":"Pancakes","servings":4,"note":"x"}`,
+			patch: `{"servings":6,"note":null}`,
+			want:  `{"name":"Pancakes","servings":6}`,
+		},
+		{
+			name:  "merges nested objects",
+			doc:   `{"a":{"b":1,"c":2}}`,
+			patch: `{"a":{"c":3,"d":4}}`,
+			want:  `{"a":{"b":1,"c":3,"d":4}}`,
+		},
+		{
+			name:  "replaces arrays",
+			doc:   `{"tags":[{"id":"1"},{"id":"2"}]}`,
+			patch: `{"tags":[{"id":"3"}]}`,
+			want:  `{"tags":[{"id":"3"}]}`,
+		},
+		{
+			name:  "keeps large numbers exact",
+			doc:   `{"n":12345678901234567890}`,
+			patch: `{}`,
+			want:  `{"n":12345678901234567890}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.MergePatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+
+	_, err := jsonpatch.MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"name":"Pancakes","ingredients":[{"id":"flour","quantity":200},{"id":"milk","quantity":300}],"a/b":{"~c":1}}`
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "replaces a nested value",
+			patch: `[{"op":"replace","path":"/ingredients/1/quantity","value":250}]`,
+			want:  `{"name":"Pancakes","ingredients":[{"id":"flour","quantity":200},{"id":"milk","quantity":250}],"a/b":{"~c":1}}`,
+		},
+		{
+			name:  "adds before an index and appends",
+			patch: `[{"op":"add","path":"/ingredients/0","value":{"id":"egg"}},{"op":"add","path":"/ingredients/-","value":{"id":"salt"}}]`,
+			want:  `{"name":"Pancakes","ingredients":[{"id":"egg"},{"id":"flour","quantity":200},{"id":"milk","quantity":300},{"id":"salt"}],"a/b":{"~c":1}}`,
+		},
+		{
+			name:  "removes an array element",
+			patch: `[{"op":"remove","path":"/ingredients/0"}]`,
+			want:  `{"name":"Pancakes","ingredients":[{"id":"milk","quantity":300}],"a/b":{"~c":1}}`,
+		},
+		{
+			name:  "moves and copies",
+			patch: `[{"op":"move","from":"/ingredients/1","path":"/ingredients/0"},{"op":"copy","from":"/name","path":"/title"}]`,
+			want:  `{"name":"Pancakes","title":"Pancakes","ingredients":[{"id":"milk","quantity":300},{"id":"flour","quantity":200}],"a/b":{"~c":1}}`,
+		},
+		{
+			name:  "unescapes pointers",
+			patch: `[{"op":"replace","path":"/a~1b/~0c","value":2}]`,
+			want:  `{"name":"Pancakes","ingredients":[{"id":"flour","quantity":200},{"id":"milk","quantity":300}],"a/b":{"~c":2}}`,
+		},
+		{
+			name:  "passes a test",
+			patch: `[{"op":"test","path":"/ingredients/0/quantity","value":200.0},{"op":"remove","path":"/a~1b"}]`,
+			want:  `{"name":"Pancakes","ingredients":[{"id":"flour","quantity":200},{"id":"milk","quantity":300}]}`,
+		},
+		{
+			name:  "replaces the whole document",
+			patch: `[{"op":"replace","path":"","value":{"name":"Waffles"}}]`,
+			want:  `{"name":"Waffles"}`,
+		},
+		{
+			name:    "fails a test",
+			patch:   `[{"op":"remove","path":"/name"},{"op":"test","path":"/ingredients/0/id","value":"milk"}]`,
+			wantErr: jsonpatch.ErrConflict,
+		},
+		{
+			name:    "replaces a missing path",
+			patch:   `[{"op":"replace","path":"/servings","value":4}]`,
+			wantErr: jsonpatch.ErrConflict,
+		},
+		{
+			name:    "indexes past the end",
+			patch:   `[{"op":"remove","path":"/ingredients/2"}]`,
+			wantErr: jsonpatch.ErrConflict,
+		},
+		{
+			name:    "uses an unknown op",
+			patch:   `[{"op":"merge","path":"/name","value":"x"}]`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+		{
+			name:    "omits the value",
+			patch:   `[{"op":"add","path":"/servings"}]`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+		{
+			name:    "uses a relative pointer",
+			patch:   `[{"op":"remove","path":"name"}]`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+		{
+			name:    "moves into itself",
+			patch:   `[{"op":"move","from":"/ingredients","path":"/ingredients/0"}]`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+		{
+			name:    "is not an array",
+			patch:   `{"op":"remove","path":"/name"}`,
+			wantErr: jsonpatch.ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
@@ -2,12 +2,26 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"strings"
+
+	"github.com/stevmwhitfield/recipe-api/internal/util"
 )
 
 type contextKey string
 
-const APIVersionKey contextKey = contextKey("api.version")
+const (
+	APIVersionKey contextKey = contextKey("api.version")
+	UserIDKey     contextKey = contextKey("user.id")
+)
+
+// AuthorizationHeader carries the caller's user token as a bearer token.
+const AuthorizationHeader = "Authorization"
+
+// UserAuthenticator returns the id of the user a token was issued to, or ""
+// when it was issued to no one.
+type UserAuthenticator func(token string) (string, error)
 
 func APIVersionCtx(version string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -17,3 +31,54 @@ func APIVersionCtx(version string) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// UserIDCtx identifies the caller from the bearer token in the
+// Authorization header, which is issued when the user is created. Requests
+// without the header are anonymous; a token issued to no one is rejected.
+func UserIDCtx(authenticate UserAuthenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(AuthorizationHeader)
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || strings.TrimSpace(token) == "" {
+				util.WriteJSON(w, http.StatusUnauthorized, util.Envelope{"error": "invalid authorization header"})
+				return
+			}
+
+			userID, err := authenticate(strings.TrimSpace(token))
+			if err != nil {
+				slog.Error("UserIDCtx", "error", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.Envelope{"error": "failed to authenticate user"})
+				return
+			}
+			if userID == "" {
+				util.WriteJSON(w, http.StatusUnauthorized, util.Envelope{"error": "invalid user token"})
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUserID rejects anonymous requests.
+func RequireUserID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if UserIDFromContext(r.Context()) == "" {
+			util.WriteJSON(w, http.StatusUnauthorized, util.Envelope{"error": "a user token is required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(UserIDKey).(string)
+	return userID
+}
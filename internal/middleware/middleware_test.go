@@ -1,6 +1,7 @@
 package middleware_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -26,3 +27,73 @@ func TestAPIVersionCtx(t *testing.T) {
 
 	assert.Equal(t, "v1", w.Body.String())
 }
+
+func TestUserIDCtx(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(middleware.UserIDFromContext(r.Context())))
+	})
+
+	handler := middleware.UserIDCtx(func(token string) (string, error) {
+		switch token {
+		case "secret":
+			return "019a40de-02cd-7865-84ae-c038b75596f5", nil
+		case "broken":
+			return "", errors.New("database error")
+		}
+		return "", nil
+	})(nextHandler)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantBody string
+	}{
+		{"with user token", "Bearer secret", http.StatusOK, "019a40de-02cd-7865-84ae-c038b75596f5"},
+		{"anonymous", "", http.StatusOK, ""},
+		{"with unknown token", "Bearer 019a40de-02cd-7865-84ae-c038b75596f5", http.StatusUnauthorized, `{"error": "invalid user token"}`},
+		{"without bearer scheme", "secret", http.StatusUnauthorized, `{"error": "invalid authorization header"}`},
+		{"with empty token", "Bearer ", http.StatusUnauthorized, `{"error": "invalid authorization header"}`},
+		{"with failing lookup", "Bearer broken", http.StatusInternalServerError, `{"error": "failed to authenticate user"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(middleware.AuthorizationHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			} else {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireUserID(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	handler := middleware.UserIDCtx(func(token string) (string, error) {
+		return token, nil
+	})(middleware.RequireUserID(nextHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error": "a user token is required"}`, w.Body.String())
+
+	req.Header.Set(middleware.AuthorizationHeader, "Bearer 019a40de-02cd-7865-84ae-c038b75596f5")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// CalendarFeed is a user's subscription to their meal plan. The token is
+// the only credential for the feed, so it and the url built from it are
+// returned once, when the feed is created. Households are not supported
+// yet, so a feed shows one user's plan.
+type CalendarFeed struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	Token     string    `json:"token,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// CatalogIngredient is an entry in the ingredient catalog that recipes,
+// the pantry and prices refer to. Density is in grams per milliliter and
+// piece weight in grams; either is nil when unknown. Diets lists the diets
+// the ingredient is suitable for. Aliases are other names the ingredient is
+// found by.
+type CatalogIngredient struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Density     *float64 `json:"density"`
+	PieceWeight *float64 `json:"pieceWeight"`
+	Allergens   []string `json:"allergens"`
+	Diets       []string `json:"diets"`
+	Aliases     []string `json:"aliases"`
+}
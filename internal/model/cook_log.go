@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type CookLog struct {
+	ID         string    `json:"id"`
+	RecipeID   string    `json:"recipeId"`
+	RecipeName string    `json:"recipeName"`
+	UserID     string    `json:"userId"`
+	CookedOn   string    `json:"cookedOn"`
+	Servings   int       `json:"servings"`
+	Notes      string    `json:"notes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
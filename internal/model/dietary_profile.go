@@ -0,0 +1,17 @@
+package model
+
+type DietaryProfile struct {
+	UserID                string   `json:"userId"`
+	Allergies             []string `json:"allergies"`
+	Diets                 []string `json:"diets"`
+	DislikedIngredientIDs []string `json:"dislikedIngredientIds"`
+}
+
+// DietaryWarning describes one way a recipe conflicts with a profile.
+// Type is allergen, diet or disliked, and Value the allergen, diet or
+// ingredient id concerned.
+type DietaryWarning struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
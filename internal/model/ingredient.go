@@ -1,9 +1,21 @@
 package model
 
+import "github.com/stevmwhitfield/recipe-api/internal/units"
+
+// Ingredient is a line in a recipe. A recipe may use the same ingredient on
+// several lines, so each line has its own LineID. SectionID is set when the
+// line belongs to one of the recipe's sections.
+//
+// A line uses another recipe instead of an ingredient when RecipeID is set.
+// Its Quantity is then in servings of that recipe and ID is empty.
 type Ingredient struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Quantity float64 `json:"quantity"`
-	Unit     string  `json:"unit"`
-	Note     string  `json:"note"`
+	LineID    string        `json:"lineId"`
+	ID        string        `json:"id"`
+	RecipeID  string        `json:"recipeId,omitempty"`
+	Name      string        `json:"name"`
+	Quantity  float64       `json:"quantity"`
+	Unit      string        `json:"unit"`
+	Note      string        `json:"note"`
+	SectionID string        `json:"sectionId,omitempty"`
+	Density   units.Density `json:"-"`
 }
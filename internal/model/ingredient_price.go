@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type IngredientPrice struct {
+	ID           string    `json:"id"`
+	IngredientID string    `json:"ingredientId"`
+	Name         string    `json:"name"`
+	Amount       float64   `json:"amount"`
+	Unit         string    `json:"unit"`
+	Price        float64   `json:"price"`
+	Store        string    `json:"store"`
+	PricedOn     string    `json:"pricedOn"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -4,4 +4,5 @@ type Instruction struct {
 	ID          string `json:"id"`
 	StepNumber  int    `json:"stepNumber"`
 	Description string `json:"description"`
+	SectionID   string `json:"sectionId,omitempty"`
 }
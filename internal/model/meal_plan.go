@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type MealPlanEntry struct {
+	ID         string    `json:"id"`
+	RecipeID   string    `json:"recipeId"`
+	RecipeName string    `json:"recipeName"`
+	UserID     string    `json:"userId"`
+	Date       string    `json:"date"`
+	Meal       string    `json:"meal"`
+	Servings   int       `json:"servings"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// MissingReference is a reference in a request to a record that does not
+// exist. Field is a JSON Pointer to the reference within the recipe as the
+// API returns it, e.g. /ingredients/2/id, and ID is the value it held.
+type MissingReference struct {
+	Field string `json:"field"`
+	ID    string `json:"id"`
+}
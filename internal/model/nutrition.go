@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// NutritionFacts holds energy in kcal, macronutrients in grams and
+// minerals and vitamins in milligrams.
+type NutritionFacts struct {
+	Calories      float64 `json:"calories"`
+	Protein       float64 `json:"protein"`
+	Fat           float64 `json:"fat"`
+	SaturatedFat  float64 `json:"saturatedFat"`
+	Carbohydrates float64 `json:"carbohydrates"`
+	Fiber         float64 `json:"fiber"`
+	Sugar         float64 `json:"sugar"`
+	Sodium        float64 `json:"sodium"`
+	Calcium       float64 `json:"calcium"`
+	Iron          float64 `json:"iron"`
+	Potassium     float64 `json:"potassium"`
+	VitaminC      float64 `json:"vitaminC"`
+}
+
+type IngredientNutrition struct {
+	IngredientID string         `json:"ingredientId"`
+	Name         string         `json:"name"`
+	Per100g      NutritionFacts `json:"per100g"`
+	Source       string         `json:"source"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+// RecipeNutrition leaves unresolved ingredients out of the totals.
+type RecipeNutrition struct {
+	Total      NutritionFacts        `json:"total"`
+	PerServing NutritionFacts        `json:"perServing"`
+	Unresolved []UnmatchedIngredient `json:"unresolved"`
+}
@@ -0,0 +1,24 @@
+package model
+
+type PantryDeduction struct {
+	StockID      string  `json:"stockId"`
+	IngredientID string  `json:"ingredientId"`
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	Remaining    float64 `json:"remaining"`
+}
+
+type UnmatchedIngredient struct {
+	IngredientID string  `json:"ingredientId"`
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	Reason       string  `json:"reason"`
+}
+
+type PantryDeductionReport struct {
+	Deductions []PantryDeduction     `json:"deductions"`
+	Depleted   []PantryDeduction     `json:"depleted"`
+	Unmatched  []UnmatchedIngredient `json:"unmatched"`
+}
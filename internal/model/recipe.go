@@ -2,16 +2,72 @@ package model
 
 import "time"
 
+// Recipe lists every ingredient and instruction in its flat Ingredients
+// and Instructions. Sections group the same items for recipes with parts,
+// like the sponge and frosting of a layer cake. A variation forked from
+// another recipe has its ParentID set. Version goes up with every change.
+// DeletedAt is set while the recipe is in the trash.
 type Recipe struct {
 	ID              string        `json:"id"`
 	Slug            string        `json:"slug"`
+	Version         int           `json:"version"`
+	ParentID        *string       `json:"parentId"`
 	Name            string        `json:"name"`
 	Servings        int           `json:"servings"`
 	PrepTimeSeconds int           `json:"prepTimeSeconds"`
 	CookTimeSeconds int           `json:"cookTimeSeconds"`
 	Ingredients     []Ingredient  `json:"ingredients"`
 	Instructions    []Instruction `json:"instructions"`
+	Sections        []Section     `json:"sections"`
+	UsedIn          []RecipeRef   `json:"usedIn"`
+	Variations      []RecipeRef   `json:"variations"`
 	Tags            []Tag         `json:"tags"`
+	Allergens       []string      `json:"allergens"`
+	Diets           []string      `json:"diets"`
+	LastCookedAt    *string       `json:"lastCookedAt"`
+	TimesCooked     int           `json:"timesCooked"`
 	CreatedAt       time.Time     `json:"createdAt"`
 	UpdatedAt       time.Time     `json:"updatedAt"`
+	DeletedAt       *time.Time    `json:"deletedAt"`
+}
+
+// RecipeRef identifies another recipe, such as one that uses this recipe
+// as a sub-recipe or was forked from it.
+type RecipeRef struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Section is a named, ordered group of a recipe's ingredients and
+// instructions.
+type Section struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Ingredients  []Ingredient  `json:"ingredients"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// GroupSections fills each section's items from the recipe's flat lists,
+// matching on SectionID and keeping their order.
+func (r *Recipe) GroupSections() {
+	sections := make([]Section, len(r.Sections))
+	index := map[string]int{}
+	for n, s := range r.Sections {
+		sections[n] = Section{ID: s.ID, Name: s.Name, Ingredients: []Ingredient{}, Instructions: []Instruction{}}
+		index[s.ID] = n
+	}
+
+	for _, i := range r.Ingredients {
+		if n, ok := index[i.SectionID]; ok {
+			sections[n].Ingredients = append(sections[n].Ingredients, i)
+		}
+	}
+	for _, i := range r.Instructions {
+		if n, ok := index[i.SectionID]; ok {
+			sections[n].Instructions = append(sections[n].Instructions, i)
+		}
+	}
+
+	r.Sections = sections
 }
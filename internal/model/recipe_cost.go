@@ -0,0 +1,21 @@
+package model
+
+// RecipeCost is an estimate from the latest recorded prices. Complete is
+// false when some ingredients could not be priced and are left out of
+// the total.
+type RecipeCost struct {
+	Total      float64          `json:"total"`
+	PerServing float64          `json:"perServing"`
+	Complete   bool             `json:"complete"`
+	Lines      []IngredientCost `json:"lines"`
+}
+
+type IngredientCost struct {
+	IngredientID string  `json:"ingredientId"`
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	Cost         float64 `json:"cost"`
+	PriceID      string  `json:"priceId,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
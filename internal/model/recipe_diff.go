@@ -0,0 +1,30 @@
+package model
+
+// RecipeDiff lists what changed from one version of a recipe to another,
+// such as from a parent to its variation.
+type RecipeDiff struct {
+	Fields       []FieldChange       `json:"fields"`
+	Ingredients  []IngredientChange  `json:"ingredients"`
+	Instructions []InstructionChange `json:"instructions"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// IngredientChange has From for removed lines, To for added lines and
+// both for changed ones.
+type IngredientChange struct {
+	Change string      `json:"change"`
+	From   *Ingredient `json:"from,omitempty"`
+	To     *Ingredient `json:"to,omitempty"`
+}
+
+// InstructionChange compares the steps at the same position.
+type InstructionChange struct {
+	Change string       `json:"change"`
+	From   *Instruction `json:"from,omitempty"`
+	To     *Instruction `json:"to,omitempty"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RecipeRevision is an immutable snapshot of a recipe's contents after a
+// change. Recipe is left out when listing revisions.
+type RecipeRevision struct {
+	RecipeID  string    `json:"recipeId"`
+	Number    int       `json:"number"`
+	AuthorID  *string   `json:"authorId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Recipe    *Recipe   `json:"recipe,omitempty"`
+}
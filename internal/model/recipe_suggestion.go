@@ -0,0 +1,8 @@
+package model
+
+type RecipeSuggestion struct {
+	RecipeID            string              `json:"recipeId"`
+	RecipeName          string              `json:"recipeName"`
+	Slug                string              `json:"slug"`
+	ExpiringIngredients []StockedIngredient `json:"expiringIngredients"`
+}
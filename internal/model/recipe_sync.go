@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RecipeSync holds the recipe changes after a sync cursor. Clients pass
+// Cursor back to get the changes after this sync.
+type RecipeSync struct {
+	Recipes []Recipe          `json:"recipes"`
+	Deleted []RecipeTombstone `json:"deleted"`
+	Cursor  int64             `json:"cursor"`
+}
+
+// RecipeTombstone marks a recipe deleted since the last sync, whether it
+// is in the trash or already purged.
+type RecipeTombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
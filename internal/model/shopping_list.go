@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+type ShoppingListItem struct {
+	IngredientID string   `json:"ingredientId"`
+	Name         string   `json:"name"`
+	Quantity     float64  `json:"quantity"`
+	Unit         string   `json:"unit"`
+	Needed       float64  `json:"needed"`
+	InStock      float64  `json:"inStock"`
+	RecipeIDs    []string `json:"recipeIds"`
+}
+
+type ShoppingList struct {
+	ID          string              `json:"id"`
+	Status      string              `json:"status"`
+	Items       []ShoppingListEntry `json:"items"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	CompletedAt *time.Time          `json:"completedAt"`
+}
+
+type ShoppingListEntry struct {
+	ID           string    `json:"id"`
+	IngredientID string    `json:"ingredientId"`
+	Name         string    `json:"name"`
+	Quantity     float64   `json:"quantity"`
+	Unit         string    `json:"unit"`
+	Note         string    `json:"note"`
+	Source       string    `json:"source"`
+	Checked      bool      `json:"checked"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type StockThreshold struct {
+	IngredientID string    `json:"ingredientId"`
+	Name         string    `json:"name"`
+	MinQuantity  float64   `json:"minQuantity"`
+	Unit         string    `json:"unit"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type LowStockItem struct {
+	IngredientID string  `json:"ingredientId"`
+	Name         string  `json:"name"`
+	MinQuantity  float64 `json:"minQuantity"`
+	InStock      float64 `json:"inStock"`
+	Shortfall    float64 `json:"shortfall"`
+	Unit         string  `json:"unit"`
+}
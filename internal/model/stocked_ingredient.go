@@ -1,4 +1,22 @@
 package model
 
+import (
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
 type StockedIngredient struct {
+	ID           string        `json:"id"`
+	IngredientID string        `json:"ingredientId"`
+	Name         string        `json:"name"`
+	Quantity     float64       `json:"quantity"`
+	Unit         string        `json:"unit"`
+	Note         string        `json:"note"`
+	BestBefore   *string       `json:"bestBefore"`
+	OpenedOn     *string       `json:"openedOn"`
+	Location     string        `json:"location"`
+	Density      units.Density `json:"-"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
 }
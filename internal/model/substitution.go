@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Substitution replaces one ingredient with one or more others. Each
+// component's ratio is how much of it to use per unit of the replaced
+// ingredient, in the same unit.
+type Substitution struct {
+	ID           string                  `json:"id"`
+	IngredientID string                  `json:"ingredientId"`
+	Name         string                  `json:"name"`
+	Components   []SubstitutionComponent `json:"components"`
+	Notes        string                  `json:"notes"`
+	CreatedAt    time.Time               `json:"createdAt"`
+}
+
+type SubstitutionComponent struct {
+	IngredientID string  `json:"ingredientId"`
+	Name         string  `json:"name"`
+	Ratio        float64 `json:"ratio"`
+}
+
+// SubstitutionSuggestion is a recipe ingredient that should be replaced.
+// SubstitutionID and Replacement are empty when no rule fits.
+type SubstitutionSuggestion struct {
+	IngredientID   string       `json:"ingredientId"`
+	Name           string       `json:"name"`
+	Quantity       float64      `json:"quantity"`
+	Unit           string       `json:"unit"`
+	Reasons        []string     `json:"reasons"`
+	SubstitutionID string       `json:"substitutionId,omitempty"`
+	Replacement    []Ingredient `json:"replacement"`
+	Notes          string       `json:"notes,omitempty"`
+}
+
+// SubstitutionPreview is the recipe as it would be with every suggested
+// replacement applied.
+type SubstitutionPreview struct {
+	Recipe      Recipe                   `json:"recipe"`
+	Suggestions []SubstitutionSuggestion `json:"suggestions"`
+}
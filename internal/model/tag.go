@@ -1,6 +1,10 @@
 package model
 
+import "time"
+
 type Tag struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// CreatedAt is only set by the tag endpoints, not on a recipe's tags.
+	CreatedAt time.Time `json:"createdAt,omitzero"`
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// User is someone who plans, cooks and keeps a profile. The token is their
+// only credential, so it is returned once, when the user is created; only
+// its hash is stored.
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
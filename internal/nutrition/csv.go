@@ -0,0 +1,100 @@
+package nutrition
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+// columns maps CSV headers to the field they fill. Amounts are per 100 g.
+var columns = map[string]func(*model.NutritionFacts) *float64{
+	"calories":        func(f *model.NutritionFacts) *float64 { return &f.Calories },
+	"protein_g":       func(f *model.NutritionFacts) *float64 { return &f.Protein },
+	"fat_g":           func(f *model.NutritionFacts) *float64 { return &f.Fat },
+	"saturated_fat_g": func(f *model.NutritionFacts) *float64 { return &f.SaturatedFat },
+	"carbs_g":         func(f *model.NutritionFacts) *float64 { return &f.Carbohydrates },
+	"fiber_g":         func(f *model.NutritionFacts) *float64 { return &f.Fiber },
+	"sugar_g":         func(f *model.NutritionFacts) *float64 { return &f.Sugar },
+	"sodium_mg":       func(f *model.NutritionFacts) *float64 { return &f.Sodium },
+	"calcium_mg":      func(f *model.NutritionFacts) *float64 { return &f.Calcium },
+	"iron_mg":         func(f *model.NutritionFacts) *float64 { return &f.Iron },
+	"potassium_mg":    func(f *model.NutritionFacts) *float64 { return &f.Potassium },
+	"vitamin_c_mg":    func(f *model.NutritionFacts) *float64 { return &f.VitaminC },
+}
+
+// ReadCSV parses a nutrition dataset with a header row. Each row needs a
+// name, which is matched against the ingredient catalog, or an
+// ingredient_id. Unknown columns are ignored and empty cells read as zero.
+func ReadCSV(r io.Reader, source string) ([]model.IngredientNutrition, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("nutrition csv is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	_, hasName := index["name"]
+	_, hasID := index["ingredient_id"]
+	if !hasName && !hasID {
+		return nil, errors.New("nutrition csv needs a name or ingredient_id column")
+	}
+
+	cell := func(record []string, column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	data := []model.IngredientNutrition{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		n := model.IngredientNutrition{
+			IngredientID: cell(record, "ingredient_id"),
+			Name:         cell(record, "name"),
+			Source:       source,
+		}
+		if n.IngredientID == "" && n.Name == "" {
+			return nil, fmt.Errorf("line %d: name cannot be blank", line)
+		}
+
+		for column, field := range columns {
+			v := cell(record, column)
+			if v == "" {
+				continue
+			}
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil || amount < 0 {
+				return nil, fmt.Errorf("line %d: %s must be a non-negative number", line, column)
+			}
+			*field(&n.Per100g) = amount
+		}
+
+		data = append(data, n)
+	}
+
+	return data, nil
+}
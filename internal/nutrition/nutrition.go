@@ -0,0 +1,93 @@
+package nutrition
+
+import (
+	"math"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+const (
+	ReasonNoNutritionData = "no nutrition data"
+	ReasonNoWeight        = "no weight"
+)
+
+// Compute adds up the nutrition of every recipe ingredient whose quantity
+// can be resolved to a weight, directly or through the ingredient's
+// density, and that has nutrition data. The rest are listed as unresolved.
+func Compute(recipe *model.Recipe, data []model.IngredientNutrition) model.RecipeNutrition {
+	per100g := map[string]model.NutritionFacts{}
+	for _, d := range data {
+		per100g[d.IngredientID] = d.Per100g
+	}
+
+	result := model.RecipeNutrition{Unresolved: []model.UnmatchedIngredient{}}
+	for _, i := range recipe.Ingredients {
+		unresolved := model.UnmatchedIngredient{IngredientID: i.ID, Name: i.Name, Quantity: i.Quantity, Unit: i.Unit}
+
+		facts, ok := per100g[i.ID]
+		if !ok {
+			unresolved.Reason = ReasonNoNutritionData
+			result.Unresolved = append(result.Unresolved, unresolved)
+			continue
+		}
+
+		grams, err := units.ToGrams(i.Quantity, i.Unit, i.Density)
+		if err != nil {
+			unresolved.Reason = ReasonNoWeight
+			result.Unresolved = append(result.Unresolved, unresolved)
+			continue
+		}
+
+		result.Total = add(result.Total, scale(facts, grams/100))
+	}
+
+	servings := float64(recipe.Servings)
+	if servings < 1 {
+		servings = 1
+	}
+	result.PerServing = scale(result.Total, 1/servings)
+
+	// Summing rounded amounts can leave float noise behind.
+	result.Total = scale(result.Total, 1)
+	return result
+}
+
+func add(a, b model.NutritionFacts) model.NutritionFacts {
+	return model.NutritionFacts{
+		Calories:      a.Calories + b.Calories,
+		Protein:       a.Protein + b.Protein,
+		Fat:           a.Fat + b.Fat,
+		SaturatedFat:  a.SaturatedFat + b.SaturatedFat,
+		Carbohydrates: a.Carbohydrates + b.Carbohydrates,
+		Fiber:         a.Fiber + b.Fiber,
+		Sugar:         a.Sugar + b.Sugar,
+		Sodium:        a.Sodium + b.Sodium,
+		Calcium:       a.Calcium + b.Calcium,
+		Iron:          a.Iron + b.Iron,
+		Potassium:     a.Potassium + b.Potassium,
+		VitaminC:      a.VitaminC + b.VitaminC,
+	}
+}
+
+// scale multiplies every amount by factor and rounds to one decimal.
+func scale(f model.NutritionFacts, factor float64) model.NutritionFacts {
+	return model.NutritionFacts{
+		Calories:      round(f.Calories * factor),
+		Protein:       round(f.Protein * factor),
+		Fat:           round(f.Fat * factor),
+		SaturatedFat:  round(f.SaturatedFat * factor),
+		Carbohydrates: round(f.Carbohydrates * factor),
+		Fiber:         round(f.Fiber * factor),
+		Sugar:         round(f.Sugar * factor),
+		Sodium:        round(f.Sodium * factor),
+		Calcium:       round(f.Calcium * factor),
+		Iron:          round(f.Iron * factor),
+		Potassium:     round(f.Potassium * factor),
+		VitaminC:      round(f.VitaminC * factor),
+	}
+}
+
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
@@ -0,0 +1,91 @@
+package nutrition_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/nutrition"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompute(t *testing.T) {
+	recipe := &model.Recipe{
+		Servings: 2,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Name: "Flour", Quantity: 200, Unit: "g"},
+			{ID: "butter", Name: "Butter", Quantity: 0.1, Unit: "kg"},
+			{ID: "milk", Name: "Milk", Quantity: 1, Unit: "cup"},
+			{ID: "salt", Name: "Salt", Quantity: 1, Unit: "g"},
+		},
+	}
+
+	data := []model.IngredientNutrition{
+		{IngredientID: "flour", Per100g: model.NutritionFacts{Calories: 364, Protein: 10.3, Carbohydrates: 76.3}},
+		{IngredientID: "butter", Per100g: model.NutritionFacts{Calories: 717, Fat: 81.1, Sodium: 11}},
+		{IngredientID: "milk", Per100g: model.NutritionFacts{Calories: 61}},
+	}
+
+	result := nutrition.Compute(recipe, data)
+
+	assert.Equal(t, model.NutritionFacts{Calories: 1445, Protein: 20.6, Fat: 81.1, Carbohydrates: 152.6, Sodium: 11}, result.Total)
+	assert.Equal(t, model.NutritionFacts{Calories: 722.5, Protein: 10.3, Fat: 40.6, Carbohydrates: 76.3, Sodium: 5.5}, result.PerServing)
+	assert.Equal(t, []model.UnmatchedIngredient{
+		{IngredientID: "milk", Name: "Milk", Quantity: 1, Unit: "cup", Reason: nutrition.ReasonNoWeight},
+		{IngredientID: "salt", Name: "Salt", Quantity: 1, Unit: "g", Reason: nutrition.ReasonNoNutritionData},
+	}, result.Unresolved)
+}
+
+func TestCompute_Density(t *testing.T) {
+	recipe := &model.Recipe{
+		Servings: 1,
+		Ingredients: []model.Ingredient{
+			{ID: "milk", Quantity: 1, Unit: "cup", Density: units.Density{GramsPerMilliliter: 1.03}},
+			{ID: "egg", Quantity: 2, Unit: "", Density: units.Density{GramsPerPiece: 50}},
+		},
+	}
+	data := []model.IngredientNutrition{
+		{IngredientID: "milk", Per100g: model.NutritionFacts{Calories: 61}},
+		{IngredientID: "egg", Per100g: model.NutritionFacts{Calories: 143}},
+	}
+
+	result := nutrition.Compute(recipe, data)
+
+	assert.Equal(t, 291.6, result.Total.Calories)
+	assert.Empty(t, result.Unresolved)
+}
+
+func TestReadCSV(t *testing.T) {
+	csv := "name, calories, protein_g, sodium_mg, notes\n" +
+		"Flour, 364, 10.3, 2, bleached\n" +
+		"Butter, 717, , 11,\n"
+
+	data, err := nutrition.ReadCSV(strings.NewReader(csv), "usda.csv")
+	require.NoError(t, err)
+	assert.Equal(t, []model.IngredientNutrition{
+		{Name: "Flour", Per100g: model.NutritionFacts{Calories: 364, Protein: 10.3, Sodium: 2}, Source: "usda.csv"},
+		{Name: "Butter", Per100g: model.NutritionFacts{Calories: 717, Sodium: 11}, Source: "usda.csv"},
+	}, data)
+}
+
+func TestReadCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{name: "empty", csv: "", wantErr: "nutrition csv is empty"},
+		{name: "no name column", csv: "calories\n100\n", wantErr: "nutrition csv needs a name or ingredient_id column"},
+		{name: "blank name", csv: "name,calories\n,100\n", wantErr: "line 2: name cannot be blank"},
+		{name: "bad number", csv: "name,calories\nFlour,lots\n", wantErr: "line 2: calories must be a non-negative number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := nutrition.ReadCSV(strings.NewReader(tt.csv), "")
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package pantry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/shopping"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+const epsilon = 1e-9
+
+const (
+	ReasonNotStocked        = "not stocked"
+	ReasonIncompatibleUnits = "incompatible units"
+	ReasonInsufficientStock = "insufficient stock"
+)
+
+// PlanDeduction works out how much of each stocked ingredient cooking the
+// recipe at the given servings uses up. Stock that expires soonest is used
+// first, then stock without a best-before date, oldest first. Nothing is
+// written; the report is applied by the store.
+func PlanDeduction(recipe *model.Recipe, servings int, stock []model.StockedIngredient) model.PantryDeductionReport {
+	report := model.PantryDeductionReport{
+		Deductions: []model.PantryDeduction{},
+		Depleted:   []model.PantryDeduction{},
+		Unmatched:  []model.UnmatchedIngredient{},
+	}
+
+	sorted := make([]model.StockedIngredient, len(stock))
+	copy(sorted, stock)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		x, y := sorted[a].BestBefore, sorted[b].BestBefore
+		switch {
+		case x != nil && y != nil && *x != *y:
+			return *x < *y
+		case (x == nil) != (y == nil):
+			return x != nil
+		}
+		return sorted[a].CreatedAt.Before(sorted[b].CreatedAt)
+	})
+
+	available := map[string]float64{}
+	deductions := map[string]*model.PantryDeduction{}
+	order := []string{}
+
+	scale := shopping.ScaleFactor(servings, recipe.Servings)
+	for _, i := range recipe.Ingredients {
+		needed := i.Quantity * scale
+		stocked, compatible := false, false
+
+		for _, s := range sorted {
+			if s.IngredientID != i.ID {
+				continue
+			}
+			stocked = true
+
+			if _, ok := available[s.ID]; !ok {
+				available[s.ID] = s.Quantity
+			}
+			left, err := units.ConvertWith(available[s.ID], s.Unit, i.Unit, i.Density)
+			if err != nil {
+				continue
+			}
+			compatible = true
+			if needed <= epsilon || left <= epsilon {
+				continue
+			}
+
+			take := math.Min(left, needed)
+			taken, _ := units.ConvertWith(take, i.Unit, s.Unit, i.Density)
+			available[s.ID] -= taken
+			needed -= take
+
+			d, ok := deductions[s.ID]
+			if !ok {
+				d = &model.PantryDeduction{StockID: s.ID, IngredientID: s.IngredientID, Name: s.Name, Unit: s.Unit}
+				deductions[s.ID] = d
+				order = append(order, s.ID)
+			}
+			d.Quantity += taken
+			d.Remaining = math.Max(available[s.ID], 0)
+		}
+
+		unmatched := model.UnmatchedIngredient{IngredientID: i.ID, Name: i.Name, Quantity: round(needed), Unit: i.Unit}
+		switch {
+		case !stocked:
+			unmatched.Reason = ReasonNotStocked
+		case !compatible:
+			unmatched.Reason = ReasonIncompatibleUnits
+		case needed > epsilon:
+			unmatched.Reason = ReasonInsufficientStock
+		default:
+			continue
+		}
+		report.Unmatched = append(report.Unmatched, unmatched)
+	}
+
+	for _, id := range order {
+		d := *deductions[id]
+		d.Quantity = round(d.Quantity)
+		d.Remaining = round(d.Remaining)
+		report.Deductions = append(report.Deductions, d)
+		if d.Remaining <= epsilon {
+			report.Depleted = append(report.Depleted, d)
+		}
+	}
+
+	return report
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,70 @@
+package pantry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDeduction(t *testing.T) {
+	now := time.Now()
+	recipe := &model.Recipe{
+		ID:       "r1",
+		Servings: 2,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Name: "Flour", Quantity: 300, Unit: "g"},
+			{ID: "milk", Name: "Milk", Quantity: 1, Unit: "cup"},
+			{ID: "salt", Name: "Salt", Quantity: 1, Unit: "pinch"},
+			{ID: "eggs", Name: "Eggs", Quantity: 2, Unit: ""},
+		},
+	}
+	stock := []model.StockedIngredient{
+		{ID: "s2", IngredientID: "flour", Name: "Flour", Quantity: 1, Unit: "kg", CreatedAt: now},
+		{ID: "s1", IngredientID: "flour", Name: "Flour", Quantity: 200, Unit: "g", CreatedAt: now.Add(-time.Hour)},
+		{ID: "s3", IngredientID: "milk", Name: "Milk", Quantity: 2, Unit: "pack", CreatedAt: now},
+		{ID: "s4", IngredientID: "eggs", Name: "Eggs", Quantity: 3, Unit: "", CreatedAt: now},
+	}
+
+	report := pantry.PlanDeduction(recipe, 4, stock)
+
+	assert.Equal(t, []model.PantryDeduction{
+		{StockID: "s1", IngredientID: "flour", Name: "Flour", Quantity: 200, Unit: "g", Remaining: 0},
+		{StockID: "s2", IngredientID: "flour", Name: "Flour", Quantity: 0.4, Unit: "kg", Remaining: 0.6},
+		{StockID: "s4", IngredientID: "eggs", Name: "Eggs", Quantity: 3, Unit: "", Remaining: 0},
+	}, report.Deductions)
+	assert.Equal(t, []model.PantryDeduction{
+		{StockID: "s1", IngredientID: "flour", Name: "Flour", Quantity: 200, Unit: "g", Remaining: 0},
+		{StockID: "s4", IngredientID: "eggs", Name: "Eggs", Quantity: 3, Unit: "", Remaining: 0},
+	}, report.Depleted)
+	assert.Equal(t, []model.UnmatchedIngredient{
+		{IngredientID: "milk", Name: "Milk", Quantity: 2, Unit: "cup", Reason: pantry.ReasonIncompatibleUnits},
+		{IngredientID: "salt", Name: "Salt", Quantity: 2, Unit: "pinch", Reason: pantry.ReasonNotStocked},
+		{IngredientID: "eggs", Name: "Eggs", Quantity: 1, Unit: "", Reason: pantry.ReasonInsufficientStock},
+	}, report.Unmatched)
+}
+
+func TestPlanDeduction_SoonestExpiringFirst(t *testing.T) {
+	now := time.Now()
+	soon, later := "2025-11-03", "2025-11-10"
+	recipe := &model.Recipe{
+		ID:          "r1",
+		Servings:    1,
+		Ingredients: []model.Ingredient{{ID: "milk", Name: "Milk", Quantity: 1200, Unit: "ml"}},
+	}
+	stock := []model.StockedIngredient{
+		{ID: "old", IngredientID: "milk", Name: "Milk", Quantity: 500, Unit: "ml", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "later", IngredientID: "milk", Name: "Milk", Quantity: 500, Unit: "ml", BestBefore: &later, CreatedAt: now.Add(-time.Hour)},
+		{ID: "soon", IngredientID: "milk", Name: "Milk", Quantity: 500, Unit: "ml", BestBefore: &soon, CreatedAt: now},
+	}
+
+	report := pantry.PlanDeduction(recipe, 1, stock)
+
+	assert.Equal(t, []model.PantryDeduction{
+		{StockID: "soon", IngredientID: "milk", Name: "Milk", Quantity: 500, Unit: "ml", Remaining: 0},
+		{StockID: "later", IngredientID: "milk", Name: "Milk", Quantity: 500, Unit: "ml", Remaining: 0},
+		{StockID: "old", IngredientID: "milk", Name: "Milk", Quantity: 200, Unit: "ml", Remaining: 300},
+	}, report.Deductions)
+}
@@ -0,0 +1,66 @@
+package pantry
+
+import (
+	"sort"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+// SuggestRecipes ranks the recipes that use up expiring stock: recipes
+// using more expiring ingredients come first, then those using the
+// ingredient that expires soonest. Recipes using none are left out.
+func SuggestRecipes(recipes []model.Recipe, expiring []model.StockedIngredient) []model.RecipeSuggestion {
+	byIngredient := map[string][]model.StockedIngredient{}
+	for _, s := range expiring {
+		if s.BestBefore == nil {
+			continue
+		}
+		byIngredient[s.IngredientID] = append(byIngredient[s.IngredientID], s)
+	}
+
+	suggestions := []model.RecipeSuggestion{}
+	earliest := map[string]string{}
+	counts := map[string]int{}
+	for _, r := range recipes {
+		used := []model.StockedIngredient{}
+		seen := map[string]bool{}
+		for _, i := range r.Ingredients {
+			if seen[i.ID] {
+				continue
+			}
+			seen[i.ID] = true
+			if stock, ok := byIngredient[i.ID]; ok {
+				used = append(used, stock...)
+				counts[r.ID]++
+			}
+		}
+		if len(used) == 0 {
+			continue
+		}
+
+		sort.SliceStable(used, func(a, b int) bool {
+			return *used[a].BestBefore < *used[b].BestBefore
+		})
+		earliest[r.ID] = *used[0].BestBefore
+
+		suggestions = append(suggestions, model.RecipeSuggestion{
+			RecipeID:            r.ID,
+			RecipeName:          r.Name,
+			Slug:                r.Slug,
+			ExpiringIngredients: used,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(a, b int) bool {
+		sa, sb := suggestions[a], suggestions[b]
+		if counts[sa.RecipeID] != counts[sb.RecipeID] {
+			return counts[sa.RecipeID] > counts[sb.RecipeID]
+		}
+		if earliest[sa.RecipeID] != earliest[sb.RecipeID] {
+			return earliest[sa.RecipeID] < earliest[sb.RecipeID]
+		}
+		return sa.RecipeName < sb.RecipeName
+	})
+
+	return suggestions
+}
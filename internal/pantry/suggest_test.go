@@ -0,0 +1,31 @@
+package pantry_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestRecipes(t *testing.T) {
+	soon, later := "2025-11-02", "2025-11-06"
+	milk := model.StockedIngredient{ID: "s1", IngredientID: "milk", BestBefore: &later}
+	spinach := model.StockedIngredient{ID: "s2", IngredientID: "spinach", BestBefore: &soon}
+	cream := model.StockedIngredient{ID: "s3", IngredientID: "cream", BestBefore: &later}
+
+	recipes := []model.Recipe{
+		{ID: "r1", Name: "Pancakes", Ingredients: []model.Ingredient{{ID: "milk"}, {ID: "flour"}}},
+		{ID: "r2", Name: "Salad", Ingredients: []model.Ingredient{{ID: "spinach"}}},
+		{ID: "r3", Name: "Toast", Ingredients: []model.Ingredient{{ID: "bread"}}},
+		{ID: "r4", Name: "Quiche", Ingredients: []model.Ingredient{{ID: "cream"}, {ID: "milk"}}},
+	}
+
+	suggestions := pantry.SuggestRecipes(recipes, []model.StockedIngredient{spinach, milk, cream})
+
+	assert.Len(t, suggestions, 3)
+	assert.Equal(t, "r4", suggestions[0].RecipeID)
+	assert.Equal(t, "r2", suggestions[1].RecipeID)
+	assert.Equal(t, "r1", suggestions[2].RecipeID)
+	assert.Equal(t, []model.StockedIngredient{cream, milk}, suggestions[0].ExpiringIngredients)
+}
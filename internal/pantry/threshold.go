@@ -0,0 +1,49 @@
+package pantry
+
+import (
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+// Shortfall returns how much of the threshold's ingredient is stocked and
+// how much is missing to reach the threshold, both in the threshold's
+// unit. Stock in units that cannot be converted is not counted.
+func Shortfall(threshold model.StockThreshold, stock []model.StockedIngredient) (float64, float64) {
+	inStock := 0.0
+	for _, s := range stock {
+		if s.IngredientID != threshold.IngredientID {
+			continue
+		}
+		quantity, err := units.ConvertWith(s.Quantity, s.Unit, threshold.Unit, s.Density)
+		if err != nil {
+			continue
+		}
+		inStock += quantity
+	}
+
+	shortfall := threshold.MinQuantity - inStock
+	if shortfall <= epsilon {
+		shortfall = 0
+	}
+	return round(inStock), round(shortfall)
+}
+
+// LowStock lists every threshold the current stock falls short of.
+func LowStock(thresholds []model.StockThreshold, stock []model.StockedIngredient) []model.LowStockItem {
+	items := []model.LowStockItem{}
+	for _, t := range thresholds {
+		inStock, shortfall := Shortfall(t, stock)
+		if shortfall == 0 {
+			continue
+		}
+		items = append(items, model.LowStockItem{
+			IngredientID: t.IngredientID,
+			Name:         t.Name,
+			MinQuantity:  t.MinQuantity,
+			InStock:      inStock,
+			Shortfall:    shortfall,
+			Unit:         t.Unit,
+		})
+	}
+	return items
+}
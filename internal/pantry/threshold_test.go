@@ -0,0 +1,30 @@
+package pantry_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowStock(t *testing.T) {
+	thresholds := []model.StockThreshold{
+		{IngredientID: "flour", Name: "Flour", MinQuantity: 2, Unit: "kg"},
+		{IngredientID: "eggs", Name: "Eggs", MinQuantity: 6, Unit: ""},
+		{IngredientID: "milk", Name: "Milk", MinQuantity: 1, Unit: "l"},
+	}
+	stock := []model.StockedIngredient{
+		{IngredientID: "flour", Quantity: 1, Unit: "kg"},
+		{IngredientID: "flour", Quantity: 500, Unit: "g"},
+		{IngredientID: "eggs", Quantity: 1, Unit: "dozen"},
+		{IngredientID: "milk", Quantity: 2, Unit: "carton"},
+	}
+
+	items := pantry.LowStock(thresholds, stock)
+
+	assert.Equal(t, []model.LowStockItem{
+		{IngredientID: "flour", Name: "Flour", MinQuantity: 2, InStock: 1.5, Shortfall: 0.5, Unit: "kg"},
+		{IngredientID: "milk", Name: "Milk", MinQuantity: 1, InStock: 0, Shortfall: 1, Unit: "l"},
+	}, items)
+}
@@ -0,0 +1,85 @@
+package recipediff
+
+import "github.com/stevmwhitfield/recipe-api/internal/model"
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Compare lists the changes from base to other. Ingredient lines are
+// matched by the ingredient or sub-recipe they use, in order, so using
+// more butter shows as a changed line rather than one removed and one
+// added. Steps are matched by position.
+func Compare(base, other *model.Recipe) model.RecipeDiff {
+	diff := model.RecipeDiff{
+		Fields:       []model.FieldChange{},
+		Ingredients:  []model.IngredientChange{},
+		Instructions: []model.InstructionChange{},
+	}
+
+	if base.Name != other.Name {
+		diff.Fields = append(diff.Fields, model.FieldChange{Field: "name", From: base.Name, To: other.Name})
+	}
+	if base.Servings != other.Servings {
+		diff.Fields = append(diff.Fields, model.FieldChange{Field: "servings", From: base.Servings, To: other.Servings})
+	}
+	if base.PrepTimeSeconds != other.PrepTimeSeconds {
+		diff.Fields = append(diff.Fields, model.FieldChange{Field: "prepTimeSeconds", From: base.PrepTimeSeconds, To: other.PrepTimeSeconds})
+	}
+	if base.CookTimeSeconds != other.CookTimeSeconds {
+		diff.Fields = append(diff.Fields, model.FieldChange{Field: "cookTimeSeconds", From: base.CookTimeSeconds, To: other.CookTimeSeconds})
+	}
+
+	unmatched := map[string][]int{}
+	for n, i := range base.Ingredients {
+		unmatched[lineKey(i)] = append(unmatched[lineKey(i)], n)
+	}
+	matched := map[int]bool{}
+	for _, i := range other.Ingredients {
+		to := i
+		candidates := unmatched[lineKey(i)]
+		if len(candidates) == 0 {
+			diff.Ingredients = append(diff.Ingredients, model.IngredientChange{Change: ChangeAdded, To: &to})
+			continue
+		}
+		n := candidates[0]
+		unmatched[lineKey(i)] = candidates[1:]
+		matched[n] = true
+
+		from := base.Ingredients[n]
+		if from.Quantity != i.Quantity || from.Unit != i.Unit || from.Note != i.Note {
+			diff.Ingredients = append(diff.Ingredients, model.IngredientChange{Change: ChangeChanged, From: &from, To: &to})
+		}
+	}
+	for n, i := range base.Ingredients {
+		if !matched[n] {
+			from := i
+			diff.Ingredients = append(diff.Ingredients, model.IngredientChange{Change: ChangeRemoved, From: &from})
+		}
+	}
+
+	for n := 0; n < max(len(base.Instructions), len(other.Instructions)); n++ {
+		switch {
+		case n >= len(base.Instructions):
+			to := other.Instructions[n]
+			diff.Instructions = append(diff.Instructions, model.InstructionChange{Change: ChangeAdded, To: &to})
+		case n >= len(other.Instructions):
+			from := base.Instructions[n]
+			diff.Instructions = append(diff.Instructions, model.InstructionChange{Change: ChangeRemoved, From: &from})
+		case base.Instructions[n].Description != other.Instructions[n].Description:
+			from, to := base.Instructions[n], other.Instructions[n]
+			diff.Instructions = append(diff.Instructions, model.InstructionChange{Change: ChangeChanged, From: &from, To: &to})
+		}
+	}
+
+	return diff
+}
+
+func lineKey(i model.Ingredient) string {
+	if i.RecipeID != "" {
+		return "recipe:" + i.RecipeID
+	}
+	return "ingredient:" + i.ID
+}
@@ -0,0 +1,61 @@
+package recipediff_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/recipediff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	base := &model.Recipe{
+		Name: "Pancakes", Servings: 4, CookTimeSeconds: 900,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Quantity: 200, Unit: "g"},
+			{ID: "milk", Quantity: 300, Unit: "ml"},
+			{ID: "butter", Quantity: 20, Unit: "g", Note: "for the pan"},
+			{ID: "butter", Quantity: 30, Unit: "g", Note: "melted"},
+		},
+		Instructions: []model.Instruction{
+			{StepNumber: 1, Description: "Whisk."},
+			{StepNumber: 2, Description: "Fry."},
+		},
+	}
+	other := &model.Recipe{
+		Name: "Oat Pancakes", Servings: 4, CookTimeSeconds: 600,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Quantity: 200, Unit: "g"},
+			{ID: "oat-milk", Quantity: 300, Unit: "ml"},
+			{ID: "butter", Quantity: 20, Unit: "g", Note: "for the pan"},
+			{ID: "butter", Quantity: 40, Unit: "g", Note: "melted"},
+		},
+		Instructions: []model.Instruction{
+			{StepNumber: 1, Description: "Whisk."},
+			{StepNumber: 2, Description: "Rest for 10 minutes."},
+			{StepNumber: 3, Description: "Fry."},
+		},
+	}
+
+	diff := recipediff.Compare(base, other)
+
+	assert.Equal(t, []model.FieldChange{
+		{Field: "name", From: "Pancakes", To: "Oat Pancakes"},
+		{Field: "cookTimeSeconds", From: 900, To: 600},
+	}, diff.Fields)
+	assert.Equal(t, []model.IngredientChange{
+		{Change: recipediff.ChangeAdded, To: &other.Ingredients[1]},
+		{Change: recipediff.ChangeChanged, From: &base.Ingredients[3], To: &other.Ingredients[3]},
+		{Change: recipediff.ChangeRemoved, From: &base.Ingredients[1]},
+	}, diff.Ingredients)
+	assert.Equal(t, []model.InstructionChange{
+		{Change: recipediff.ChangeChanged, From: &base.Instructions[1], To: &other.Instructions[1]},
+		{Change: recipediff.ChangeAdded, To: &other.Instructions[2]},
+	}, diff.Instructions)
+
+	assert.Equal(t, model.RecipeDiff{
+		Fields:       []model.FieldChange{},
+		Ingredients:  []model.IngredientChange{},
+		Instructions: []model.InstructionChange{},
+	}, recipediff.Compare(base, base))
+}
@@ -25,9 +25,9 @@ func InitRoutes(app *app.Application) *chi.Mux {
 	r.Use(cors.Handler(cors.Options{
 		// AllowedOrigins:   []string{"https://example.com"},
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match", "If-Modified-Since"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -38,8 +38,19 @@ func InitRoutes(app *app.Application) *chi.Mux {
 
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Use(customMiddleware.APIVersionCtx("v1"))
+		r.Use(customMiddleware.UserIDCtx(app.UserStore.AuthenticateUser))
 		r.Mount("/recipes", app.RecipeHandler.Routes())
 		r.Mount("/tags", app.TagHandler.Routes())
+		r.Mount("/meal-plans", app.MealPlanHandler.Routes())
+		r.Mount("/calendar-feeds", app.CalendarHandler.Routes())
+		r.Get("/calendar/{token}", app.CalendarHandler.ServeFeed)
+		r.Mount("/users", app.UserHandler.Routes())
+		r.Mount("/cook-logs", app.CookLogHandler.Routes())
+		r.Mount("/pantry", app.PantryHandler.Routes())
+		r.Mount("/shopping-lists", app.ShoppingListHandler.Routes())
+		r.Mount("/prices", app.PriceHandler.Routes())
+		r.Mount("/ingredients", app.IngredientHandler.Routes())
+		r.Mount("/substitutions", app.SubstitutionHandler.Routes())
 	})
 
 	return r
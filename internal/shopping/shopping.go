@@ -0,0 +1,140 @@
+package shopping
+
+import (
+	"math"
+	"sort"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+// Quantities are merged in the base unit of their dimension, or by weight
+// when the ingredient's density allows it. Units that cannot be converted
+// (e.g. "clove") are only merged with the same unit.
+type key struct {
+	ingredientID string
+	dimension    units.Dimension
+	unit         string
+}
+
+type accumulator struct {
+	item      model.ShoppingListItem
+	needed    float64
+	inStock   float64
+	unit      string
+	sameUnit  bool
+	density   units.Density
+	recipeIDs map[string]bool
+}
+
+// ScaleFactor returns the multiplier for cooking a recipe written for
+// recipeServings at plannedServings.
+func ScaleFactor(plannedServings, recipeServings int) float64 {
+	if plannedServings < 1 || recipeServings < 1 {
+		return 1
+	}
+	return float64(plannedServings) / float64(recipeServings)
+}
+
+// Build merges the ingredients of every planned recipe, scaled to the
+// planned servings, and subtracts what is already stocked. Ingredients
+// fully covered by stock are left out of the list.
+func Build(entries []model.MealPlanEntry, recipes map[string]*model.Recipe, stock []model.StockedIngredient) []model.ShoppingListItem {
+	lines := map[key]*accumulator{}
+
+	for _, e := range entries {
+		recipe, ok := recipes[e.RecipeID]
+		if !ok || recipe == nil {
+			continue
+		}
+		scale := ScaleFactor(e.Servings, recipe.Servings)
+
+		for _, i := range recipe.Ingredients {
+			k, quantity := keyFor(i.ID, i.Quantity*scale, i.Unit, i.Density)
+			acc, ok := lines[k]
+			if !ok {
+				acc = &accumulator{
+					item:      model.ShoppingListItem{IngredientID: i.ID, Name: i.Name},
+					unit:      units.Normalize(i.Unit),
+					sameUnit:  true,
+					density:   i.Density,
+					recipeIDs: map[string]bool{},
+				}
+				lines[k] = acc
+			}
+			if units.Normalize(i.Unit) != acc.unit {
+				acc.sameUnit = false
+			}
+			acc.needed += quantity
+			if !acc.recipeIDs[recipe.ID] {
+				acc.recipeIDs[recipe.ID] = true
+				acc.item.RecipeIDs = append(acc.item.RecipeIDs, recipe.ID)
+			}
+		}
+	}
+
+	for _, s := range stock {
+		k, quantity := keyFor(s.IngredientID, s.Quantity, s.Unit, s.Density)
+		if acc, ok := lines[k]; ok {
+			acc.inStock += quantity
+		}
+	}
+
+	items := []model.ShoppingListItem{}
+	for k, acc := range lines {
+		toBuy := acc.needed - acc.inStock
+		if toBuy <= 1e-9 {
+			continue
+		}
+
+		unit := k.unit
+		if k.dimension != "" {
+			unit = units.BaseUnit(k.dimension)
+			if acc.sameUnit {
+				unit = acc.unit
+			}
+		}
+
+		item := acc.item
+		item.Unit = unit
+		item.Needed = round(fromBase(acc.needed, k, unit, acc.density))
+		item.InStock = round(fromBase(acc.inStock, k, unit, acc.density))
+		item.Quantity = round(fromBase(toBuy, k, unit, acc.density))
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(a, b int) bool {
+		if items[a].Name != items[b].Name {
+			return items[a].Name < items[b].Name
+		}
+		return items[a].Unit < items[b].Unit
+	})
+
+	return items
+}
+
+func keyFor(ingredientID string, quantity float64, unit string, density units.Density) (key, float64) {
+	if grams, err := units.ToGrams(quantity, unit, density); err == nil {
+		return key{ingredientID: ingredientID, dimension: units.Mass}, grams
+	}
+	base, dimension, err := units.ToBase(quantity, unit)
+	if err != nil {
+		return key{ingredientID: ingredientID, unit: units.Normalize(unit)}, quantity
+	}
+	return key{ingredientID: ingredientID, dimension: dimension}, base
+}
+
+func fromBase(quantity float64, k key, unit string, density units.Density) float64 {
+	if k.dimension == "" {
+		return quantity
+	}
+	converted, err := units.ConvertWith(quantity, units.BaseUnit(k.dimension), unit, density)
+	if err != nil {
+		return quantity
+	}
+	return converted
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
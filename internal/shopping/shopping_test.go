@@ -0,0 +1,89 @@
+package shopping_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/shopping"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleFactor(t *testing.T) {
+	assert.Equal(t, 2.0, shopping.ScaleFactor(8, 4))
+	assert.Equal(t, 0.5, shopping.ScaleFactor(2, 4))
+	assert.Equal(t, 1.0, shopping.ScaleFactor(0, 4))
+	assert.Equal(t, 1.0, shopping.ScaleFactor(4, 0))
+}
+
+func TestBuild(t *testing.T) {
+	recipes := map[string]*model.Recipe{
+		"r1": {
+			ID:       "r1",
+			Servings: 4,
+			Ingredients: []model.Ingredient{
+				{ID: "flour", Name: "Flour", Quantity: 200, Unit: "g"},
+				{ID: "milk", Name: "Milk", Quantity: 1, Unit: "cup"},
+				{ID: "garlic", Name: "Garlic", Quantity: 2, Unit: "cloves"},
+			},
+		},
+		"r2": {
+			ID:       "r2",
+			Servings: 2,
+			Ingredients: []model.Ingredient{
+				{ID: "flour", Name: "Flour", Quantity: 0.5, Unit: "kg"},
+				{ID: "milk", Name: "Milk", Quantity: 250, Unit: "ml"},
+				{ID: "garlic", Name: "Garlic", Quantity: 1, Unit: "clove"},
+			},
+		},
+	}
+	entries := []model.MealPlanEntry{
+		{RecipeID: "r1", Servings: 8},
+		{RecipeID: "r2", Servings: 2},
+		{RecipeID: "missing", Servings: 2},
+	}
+	stock := []model.StockedIngredient{
+		{IngredientID: "flour", Quantity: 1, Unit: "kg"},
+		{IngredientID: "milk", Quantity: 100, Unit: "ml"},
+	}
+
+	items := shopping.Build(entries, recipes, stock)
+
+	assert.Equal(t, []model.ShoppingListItem{
+		{IngredientID: "garlic", Name: "Garlic", Quantity: 5, Unit: "clove", Needed: 5, RecipeIDs: []string{"r1", "r2"}},
+		{IngredientID: "milk", Name: "Milk", Quantity: 623.18, Unit: "ml", Needed: 723.18, InStock: 100, RecipeIDs: []string{"r1", "r2"}},
+	}, items)
+}
+
+func TestBuild_Density(t *testing.T) {
+	flourDensity := units.Density{GramsPerMilliliter: 0.53}
+	eggDensity := units.Density{GramsPerPiece: 50}
+
+	recipes := map[string]*model.Recipe{
+		"r1": {
+			ID:       "r1",
+			Servings: 4,
+			Ingredients: []model.Ingredient{
+				{ID: "flour", Name: "Flour", Quantity: 1, Unit: "cup", Density: flourDensity},
+				{ID: "eggs", Name: "Eggs", Quantity: 3, Unit: "", Density: eggDensity},
+			},
+		},
+		"r2": {
+			ID:          "r2",
+			Servings:    4,
+			Ingredients: []model.Ingredient{{ID: "flour", Name: "Flour", Quantity: 200, Unit: "g", Density: flourDensity}},
+		},
+	}
+	entries := []model.MealPlanEntry{{RecipeID: "r1", Servings: 4}, {RecipeID: "r2", Servings: 4}}
+	stock := []model.StockedIngredient{
+		{IngredientID: "flour", Quantity: 100, Unit: "g", Density: flourDensity},
+		{IngredientID: "eggs", Quantity: 50, Unit: "g", Density: eggDensity},
+	}
+
+	items := shopping.Build(entries, recipes, stock)
+
+	assert.Equal(t, []model.ShoppingListItem{
+		{IngredientID: "eggs", Name: "Eggs", Quantity: 2, Unit: "", Needed: 3, InStock: 1, RecipeIDs: []string{"r1"}},
+		{IngredientID: "flour", Name: "Flour", Quantity: 225.39, Unit: "g", Needed: 325.39, InStock: 100, RecipeIDs: []string{"r1", "r2"}},
+	}, items)
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLiteCalendarFeedStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteCalendarFeedStore(db *sql.DB) *SQLiteCalendarFeedStore {
+	return &SQLiteCalendarFeedStore{db: db}
+}
+
+type CalendarFeedStore interface {
+	ListFeeds(userID string) ([]model.CalendarFeed, error)
+	CreateFeed(*model.CalendarFeed) (*model.CalendarFeed, error)
+	GetFeedByToken(token string) (*model.CalendarFeed, error)
+	DeleteFeed(id, userID string) error
+}
+
+// ListFeeds returns the user's feeds without their tokens.
+func (s *SQLiteCalendarFeedStore) ListFeeds(userID string) ([]model.CalendarFeed, error) {
+	query := `
+		SELECT id, user_id, name, created_at
+		FROM calendar_feeds
+		WHERE user_id = ?
+		ORDER BY created_at ASC;
+	`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []model.CalendarFeed{}
+	for rows.Next() {
+		var f model.CalendarFeed
+		err = rows.Scan(&f.ID, &f.UserID, &f.Name, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
+func (s *SQLiteCalendarFeedStore) CreateFeed(f *model.CalendarFeed) (*model.CalendarFeed, error) {
+	query := `
+		INSERT INTO calendar_feeds (id, user_id, name, token)
+		VALUES (?, ?, ?, ?);
+	`
+
+	_, err := s.db.Exec(query, f.ID, f.UserID, f.Name, f.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (s *SQLiteCalendarFeedStore) GetFeedByToken(token string) (*model.CalendarFeed, error) {
+	f := &model.CalendarFeed{}
+	query := `
+		SELECT id, user_id, name, token, created_at
+		FROM calendar_feeds
+		WHERE token = ?;
+	`
+
+	err := s.db.QueryRow(query, token).Scan(&f.ID, &f.UserID, &f.Name, &f.Token, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// DeleteFeed returns sql.ErrNoRows when the user has no feed with the id.
+func (s *SQLiteCalendarFeedStore) DeleteFeed(id, userID string) error {
+	query := `
+		DELETE FROM calendar_feeds
+		WHERE id = ? AND user_id = ?;
+	`
+
+	result, err := s.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+)
+
+type SQLiteCookLogStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteCookLogStore(db *sql.DB) *SQLiteCookLogStore {
+	return &SQLiteCookLogStore{db: db}
+}
+
+type CookLogFilter struct {
+	RecipeID string
+	UserID   string
+}
+
+type CookLogStore interface {
+	ListCookLogs(CookLogFilter) ([]model.CookLog, error)
+	CreateCookLog(*model.CookLog, *model.Recipe) (*model.CookLog, model.PantryDeductionReport, error)
+	DeleteCookLog(id string) error
+}
+
+func (s *SQLiteCookLogStore) ListCookLogs(f CookLogFilter) ([]model.CookLog, error) {
+	conditions := []string{"1 = 1"}
+	args := []any{}
+	if f.RecipeID != "" {
+		conditions = append(conditions, "c.recipe_id = ?")
+		args = append(args, f.RecipeID)
+	}
+	if f.UserID != "" {
+		conditions = append(conditions, "c.user_id = ?")
+		args = append(args, f.UserID)
+	}
+
+	query := `
+		SELECT c.id, c.recipe_id, r.name, COALESCE(c.user_id, ''), c.cooked_on, c.servings, COALESCE(c.notes, ''), c.created_at
+		FROM cook_logs c
+		JOIN recipes r ON r.id = c.recipe_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY c.cooked_on DESC, c.created_at DESC;
+	`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []model.CookLog{}
+	for rows.Next() {
+		var l model.CookLog
+		err = rows.Scan(&l.ID, &l.RecipeID, &l.RecipeName, &l.UserID, &l.CookedOn, &l.Servings, &l.Notes, &l.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// CreateCookLog records the cook log and, given the recipe with its
+// sub-recipes expanded, deducts the stock it uses in the same transaction.
+// The cook log is written first so the transaction holds the write lock
+// before the stock is read, and the deduction is planned from that read.
+// A nil recipe deducts nothing. Stock never drops below zero, and
+// ingredients that fall below their threshold are added to the active
+// shopping list.
+func (s *SQLiteCookLogStore) CreateCookLog(l *model.CookLog, recipe *model.Recipe) (*model.CookLog, model.PantryDeductionReport, error) {
+	report := model.PantryDeductionReport{
+		Deductions: []model.PantryDeduction{},
+		Depleted:   []model.PantryDeduction{},
+		Unmatched:  []model.UnmatchedIngredient{},
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, report, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO cook_logs (id, recipe_id, user_id, cooked_on, servings, notes)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?);
+	`
+
+	_, err = tx.Exec(query, l.ID, l.RecipeID, l.UserID, l.CookedOn, l.Servings, l.Notes)
+	if err != nil {
+		return nil, report, err
+	}
+
+	if recipe != nil {
+		query := `
+			SELECT ` + stockedIngredientColumns + `
+			FROM stocked_ingredients s
+			JOIN ingredients i ON i.id = s.ingredient_id;
+		`
+
+		stock, err := queryStockedIngredients(tx, query)
+		if err != nil {
+			return nil, report, err
+		}
+		report = pantry.PlanDeduction(recipe, l.Servings, stock)
+	}
+
+	for _, d := range report.Deductions {
+		query := `
+			UPDATE stocked_ingredients
+			SET quantity = MAX(quantity - ?, 0), updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?;
+		`
+
+		_, err = tx.Exec(query, d.Quantity, d.StockID)
+		if err != nil {
+			return nil, report, err
+		}
+	}
+
+	restocked := map[string]bool{}
+	for _, d := range report.Deductions {
+		if restocked[d.IngredientID] {
+			continue
+		}
+		restocked[d.IngredientID] = true
+		if err := restockIfLow(tx, d.IngredientID); err != nil {
+			return nil, report, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, report, err
+	}
+
+	return l, report, nil
+}
+
+func (s *SQLiteCookLogStore) DeleteCookLog(id string) error {
+	query := `
+		DELETE FROM cook_logs
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookLogs_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO recipes (id, slug, name, servings, prep_time_seconds, cook_time_seconds)
+		VALUES ("r1", "pancakes", "Pancakes", 4, 600, 900), ("r2", "waffles", "Waffles", 2, 600, 600);
+		INSERT INTO users (id, name) VALUES ("u1", "sam");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	cookLogStore := store.NewSQLiteCookLogStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, l := range []model.CookLog{
+		{ID: "c1", RecipeID: "r1", UserID: "u1", CookedOn: "2025-10-01", Servings: 4},
+		{ID: "c2", RecipeID: "r1", CookedOn: "2025-11-01", Servings: 2},
+		{ID: "c3", RecipeID: "r2", UserID: "u1", CookedOn: "2025-09-01", Servings: 2},
+	} {
+		_, _, err := cookLogStore.CreateCookLog(&l, nil)
+		require.NoError(t, err)
+	}
+
+	logs, err := cookLogStore.ListCookLogs(store.CookLogFilter{UserID: "u1"})
+	assert.NoError(t, err)
+	assert.Len(t, logs, 2)
+	assert.Equal(t, "c1", logs[0].ID)
+	assert.Equal(t, "Pancakes", logs[0].RecipeName)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-11-01", *recipe.LastCookedAt)
+	assert.Equal(t, 2, recipe.TimesCooked)
+
+	recipes, err := recipeStore.ListRecipes(store.RecipeFilter{NotCookedSince: "2025-10-15"})
+	assert.NoError(t, err)
+	assert.Len(t, recipes, 1)
+	assert.Equal(t, "r2", recipes[0].ID)
+}
+
+func TestCreateCookLog_DeductsPantry_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO recipes (id, slug, name, servings, prep_time_seconds, cook_time_seconds)
+		VALUES ("r1", "pancakes", "Pancakes", 4, 600, 900);
+		INSERT INTO ingredients (id, name, category) VALUES ("flour", "Flour", "baking");
+		INSERT INTO stocked_ingredients (id, ingredient_id, quantity, unit, best_before) VALUES
+			("s1", "flour", 1, "kg", NULL),
+			("s2", "flour", 100, "g", "2025-11-05");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	cookLogStore := store.NewSQLiteCookLogStore(db)
+	recipe := &model.Recipe{ID: "r1", Servings: 4, Ingredients: []model.Ingredient{{ID: "flour", Name: "Flour", Quantity: 350, Unit: "g"}}}
+
+	_, report, err := cookLogStore.CreateCookLog(&model.CookLog{ID: "c1", RecipeID: "r1", CookedOn: "2025-11-01", Servings: 4}, recipe)
+	require.NoError(t, err)
+
+	// The stock expiring soonest goes first.
+	assert.Equal(t, []model.PantryDeduction{
+		{StockID: "s2", IngredientID: "flour", Name: "Flour", Quantity: 100, Unit: "g", Remaining: 0},
+		{StockID: "s1", IngredientID: "flour", Name: "Flour", Quantity: 0.25, Unit: "kg", Remaining: 0.75},
+	}, report.Deductions)
+
+	var quantity float64
+	err = db.QueryRow(`SELECT quantity FROM stocked_ingredients WHERE id = "s1"`).Scan(&quantity)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.75, quantity)
+
+	// A second cook plans from what the first one left.
+	_, report, err = cookLogStore.CreateCookLog(&model.CookLog{ID: "c2", RecipeID: "r1", CookedOn: "2025-11-02", Servings: 12}, recipe)
+	require.NoError(t, err)
+	assert.Equal(t, []model.PantryDeduction{
+		{StockID: "s1", IngredientID: "flour", Name: "Flour", Quantity: 0.75, Unit: "kg", Remaining: 0},
+	}, report.Deductions)
+	require.Len(t, report.Unmatched, 1)
+	assert.Equal(t, 300.0, report.Unmatched[0].Quantity)
+}
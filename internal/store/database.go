@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose/v3"
@@ -16,7 +17,7 @@ func Open() (*sql.DB, error) {
 		path = "./internal/data/recipes.db"
 	}
 
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", DSN(path))
 	if err != nil {
 		return nil, fmt.Errorf("db: open %w", err)
 	}
@@ -29,6 +30,18 @@ func Open() (*sql.DB, error) {
 	return db, nil
 }
 
+// DSN adds the options every connection needs to a database path or URI.
+// SQLite leaves foreign keys unenforced unless each connection turns them
+// on, so without this ON DELETE CASCADE never fires and rows can point at
+// records that do not exist.
+func DSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_foreign_keys=on"
+}
+
 func MigrateFS(db *sql.DB, migrationsFS fs.FS, dir string) error {
 	goose.SetBaseFS(migrationsFS)
 	defer func() {
@@ -37,15 +50,34 @@ func MigrateFS(db *sql.DB, migrationsFS fs.FS, dir string) error {
 	return Migrate(db, dir)
 }
 
+// Migrate runs with foreign keys off. Databases written before they were
+// enforced can hold orphaned rows, and migrations that rebuild a table
+// copy those rows into it, which would otherwise fail. SQLite ignores the
+// pragma inside a transaction, and goose runs each migration in one, so
+// the pool is held to a single connection on which it is turned off
+// beforehand and back on afterwards. Use CountOrphans to find out whether
+// the migrated database needs cleaning.
 func Migrate(db *sql.DB, dir string) error {
 	err := goose.SetDialect("sqlite3")
 	if err != nil {
 		return fmt.Errorf("migrate: %w", err)
 	}
 
+	db.SetMaxOpenConns(1)
+	defer db.SetMaxOpenConns(0)
+
+	if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
 	err = goose.Up(db, dir)
 	if err != nil {
+		db.Exec(`PRAGMA foreign_keys = ON`)
 		return fmt.Errorf("goose up: %w", err)
 	}
+
+	if _, err := db.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,380 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+var ErrAliasTaken = errors.New("alias already in use")
+
+// ingredientIDByName resolves a name to an ingredient id, trying catalog
+// names before aliases. Both comparisons ignore case. It takes the name
+// twice as arguments.
+const ingredientIDByName = `COALESCE(
+	(SELECT id FROM ingredients WHERE LOWER(name) = LOWER(?) ORDER BY id LIMIT 1),
+	(SELECT ingredient_id FROM ingredient_aliases WHERE alias = ?))`
+
+type SQLiteIngredientStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteIngredientStore(db *sql.DB) *SQLiteIngredientStore {
+	return &SQLiteIngredientStore{db: db}
+}
+
+type IngredientStore interface {
+	ListIngredients() ([]model.CatalogIngredient, error)
+	GetIngredientByID(id string) (*model.CatalogIngredient, error)
+	CreateIngredient(*model.CatalogIngredient) (*model.CatalogIngredient, error)
+	UpdateIngredient(*model.CatalogIngredient) (*model.CatalogIngredient, error)
+	FindIngredientByName(name string) (*model.CatalogIngredient, error)
+	MergeIngredients(survivorID string, duplicateIDs []string) (*model.CatalogIngredient, error)
+}
+
+func (s *SQLiteIngredientStore) ListIngredients() ([]model.CatalogIngredient, error) {
+	query := `
+		SELECT id, name, category, density_g_per_ml, piece_weight_g
+		FROM ingredients
+		ORDER BY name ASC;
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ingredients := []model.CatalogIngredient{}
+	for rows.Next() {
+		var i model.CatalogIngredient
+		err = rows.Scan(&i.ID, &i.Name, &i.Category, &i.Density, &i.PieceWeight)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.getFlagsForIngredient(&i); err != nil {
+			return nil, err
+		}
+		if i.Aliases, err = s.getAliasesForIngredient(i.ID); err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ingredients, nil
+}
+
+func (s *SQLiteIngredientStore) GetIngredientByID(id string) (*model.CatalogIngredient, error) {
+	i := &model.CatalogIngredient{}
+	query := `
+		SELECT id, name, category, density_g_per_ml, piece_weight_g
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	err := s.db.QueryRow(query, id).Scan(&i.ID, &i.Name, &i.Category, &i.Density, &i.PieceWeight)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.getFlagsForIngredient(i); err != nil {
+		return nil, err
+	}
+	if i.Aliases, err = s.getAliasesForIngredient(i.ID); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (s *SQLiteIngredientStore) CreateIngredient(i *model.CatalogIngredient) (*model.CatalogIngredient, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ingredients (id, name, category, density_g_per_ml, piece_weight_g)
+		VALUES (?, ?, ?, ?, ?);
+	`
+
+	_, err = tx.Exec(query, i.ID, i.Name, i.Category, i.Density, i.PieceWeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setIngredientFlags(tx, i); err != nil {
+		return nil, err
+	}
+	if err := setIngredientAliases(tx, i); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (s *SQLiteIngredientStore) UpdateIngredient(i *model.CatalogIngredient) (*model.CatalogIngredient, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE ingredients
+		SET name = ?, category = ?, density_g_per_ml = ?, piece_weight_g = ?
+		WHERE id = ?;
+	`
+
+	result, err := tx.Exec(query, i.Name, i.Category, i.Density, i.PieceWeight, i.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := setIngredientFlags(tx, i); err != nil {
+		return nil, err
+	}
+	if err := setIngredientAliases(tx, i); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// FindIngredientByName looks an ingredient up by its name or one of its
+// aliases, ignoring case.
+func (s *SQLiteIngredientStore) FindIngredientByName(name string) (*model.CatalogIngredient, error) {
+	var id sql.NullString
+	err := s.db.QueryRow(`SELECT `+ingredientIDByName, name, name).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	if !id.Valid {
+		return nil, nil
+	}
+
+	return s.GetIngredientByID(id.String)
+}
+
+// MergeIngredients folds the duplicates into the survivor: every reference
+// to a duplicate is pointed at the survivor, the duplicates' names and
+// aliases become aliases of the survivor, and the duplicates are deleted.
+// It returns sql.ErrNoRows when the survivor does not exist and
+// ErrUnknownIngredient when a duplicate does not.
+func (s *SQLiteIngredientStore) MergeIngredients(survivorID string, duplicateIDs []string) (*model.CatalogIngredient, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var survivorName string
+	err = tx.QueryRow(`SELECT name FROM ingredients WHERE id = ?`, survivorID).Scan(&survivorName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, duplicateID := range duplicateIDs {
+		var duplicateName string
+		err := tx.QueryRow(`SELECT name FROM ingredients WHERE id = ?`, duplicateID).Scan(&duplicateName)
+		if err == sql.ErrNoRows {
+			return nil, ErrUnknownIngredient
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Tables where an ingredient may appear any number of times.
+		for _, table := range []string{"recipe_ingredient", "stocked_ingredients", "shopping_list_items", "ingredient_prices", "substitutions", "ingredient_aliases"} {
+			_, err := tx.Exec(`UPDATE `+table+` SET ingredient_id = ? WHERE ingredient_id = ?`, survivorID, duplicateID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Tables keyed by ingredient, where the survivor's row wins.
+		for _, table := range []string{"stock_thresholds", "ingredient_nutrition", "ingredient_allergens", "ingredient_diets", "user_disliked_ingredients", "substitution_components"} {
+			_, err := tx.Exec(`UPDATE OR IGNORE `+table+` SET ingredient_id = ? WHERE ingredient_id = ?`, survivorID, duplicateID)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE ingredient_id = ?`, duplicateID); err != nil {
+				return nil, err
+			}
+		}
+
+		if !strings.EqualFold(duplicateName, survivorName) {
+			_, err := tx.Exec(`INSERT OR IGNORE INTO ingredient_aliases (alias, ingredient_id) VALUES (?, ?)`, duplicateName, survivorID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM ingredients WHERE id = ?`, duplicateID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Recipes using the survivor, directly or through sub-recipes, may now
+	// have other lines and flags, so they get a new version.
+	query := `
+		WITH RECURSIVE affected(recipe_id) AS (
+			SELECT recipe_id FROM recipe_ingredient WHERE ingredient_id = ?
+			UNION
+			SELECT ri.recipe_id
+			FROM affected a
+			JOIN recipe_ingredient ri ON ri.sub_recipe_id = a.recipe_id
+		)
+		UPDATE recipes
+		SET updated_at = CURRENT_TIMESTAMP, version = version + 1, change_seq = ` + nextChangeSeq + `
+		WHERE id IN (SELECT recipe_id FROM affected);
+	`
+	if _, err := tx.Exec(query, survivorID); err != nil {
+		return nil, err
+	}
+
+	// The survivor's own name is not an alias, and it cannot substitute
+	// for itself.
+	if _, err := tx.Exec(`DELETE FROM ingredient_aliases WHERE ingredient_id = ? AND alias = ?`, survivorID, survivorName); err != nil {
+		return nil, err
+	}
+	query = `
+		DELETE FROM substitution_components
+		WHERE ingredient_id = ? AND substitution_id IN (SELECT id FROM substitutions WHERE ingredient_id = ?);
+	`
+	if _, err := tx.Exec(query, survivorID, survivorID); err != nil {
+		return nil, err
+	}
+	query = `
+		DELETE FROM substitutions
+		WHERE ingredient_id = ? AND NOT EXISTS (SELECT 1 FROM substitution_components c WHERE c.substitution_id = substitutions.id);
+	`
+	if _, err := tx.Exec(query, survivorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetIngredientByID(survivorID)
+}
+
+func (s *SQLiteIngredientStore) getFlagsForIngredient(i *model.CatalogIngredient) error {
+	query := `
+		SELECT allergen
+		FROM ingredient_allergens
+		WHERE ingredient_id = ?
+		ORDER BY allergen ASC;
+	`
+
+	var err error
+	if i.Allergens, err = queryStrings(s.db, query, i.ID); err != nil {
+		return err
+	}
+
+	query = `
+		SELECT diet
+		FROM ingredient_diets
+		WHERE ingredient_id = ?
+		ORDER BY diet ASC;
+	`
+
+	i.Diets, err = queryStrings(s.db, query, i.ID)
+	return err
+}
+
+func (s *SQLiteIngredientStore) getAliasesForIngredient(ingredientID string) ([]string, error) {
+	query := `
+		SELECT alias
+		FROM ingredient_aliases
+		WHERE ingredient_id = ?
+		ORDER BY alias COLLATE NOCASE ASC;
+	`
+
+	return queryStrings(s.db, query, ingredientID)
+}
+
+// setIngredientAliases replaces the ingredient's aliases. An alias equal
+// to the ingredient's own name is skipped. It returns ErrAliasTaken when an
+// alias belongs to, or is the name of, another ingredient.
+func setIngredientAliases(tx *sql.Tx, i *model.CatalogIngredient) error {
+	if _, err := tx.Exec(`DELETE FROM ingredient_aliases WHERE ingredient_id = ?`, i.ID); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM ingredient_aliases WHERE alias = ?)
+			OR EXISTS (SELECT 1 FROM ingredients WHERE LOWER(name) = LOWER(?) AND id <> ?);
+	`
+
+	for _, a := range i.Aliases {
+		if strings.EqualFold(a, i.Name) {
+			continue
+		}
+
+		var taken bool
+		if err := tx.QueryRow(query, a, a, i.ID).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return ErrAliasTaken
+		}
+
+		if _, err := tx.Exec(`INSERT INTO ingredient_aliases (alias, ingredient_id) VALUES (?, ?)`, a, i.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setIngredientFlags replaces the ingredient's allergens and diets.
+func setIngredientFlags(tx *sql.Tx, i *model.CatalogIngredient) error {
+	if _, err := tx.Exec(`DELETE FROM ingredient_allergens WHERE ingredient_id = ?`, i.ID); err != nil {
+		return err
+	}
+	for _, a := range i.Allergens {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO ingredient_allergens (ingredient_id, allergen) VALUES (?, ?)`, i.ID, a)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM ingredient_diets WHERE ingredient_id = ?`, i.ID); err != nil {
+		return err
+	}
+	for _, d := range i.Diets {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO ingredient_diets (ingredient_id, diet) VALUES (?, ?)`, i.ID, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package store_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngredientStore_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	density := 0.53
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour", Category: "baking", Density: &density})
+	require.NoError(t, err)
+	_, err = ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "egg", Name: "Egg", Category: "dairy"})
+	require.NoError(t, err)
+
+	egg, err := ingredientStore.GetIngredientByID("egg")
+	require.NoError(t, err)
+	assert.Nil(t, egg.Density)
+	assert.Nil(t, egg.PieceWeight)
+
+	pieceWeight := 50.0
+	egg.PieceWeight = &pieceWeight
+	_, err = ingredientStore.UpdateIngredient(egg)
+	require.NoError(t, err)
+
+	ingredients, err := ingredientStore.ListIngredients()
+	require.NoError(t, err)
+	require.Len(t, ingredients, 2)
+	assert.Equal(t, 50.0, *ingredients[0].PieceWeight)
+	assert.Equal(t, 0.53, *ingredients[1].Density)
+
+	_, err = recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "bread", Name: "Bread", Servings: 1,
+		Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 2, Unit: "cup"}, {LineID: "l2", ID: "egg", Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, recipe.Ingredients, 2)
+	for _, i := range recipe.Ingredients {
+		switch i.ID {
+		case "flour":
+			assert.Equal(t, 0.53, i.Density.GramsPerMilliliter)
+		case "egg":
+			assert.Equal(t, 50.0, i.Density.GramsPerPiece)
+		}
+	}
+
+	_, err = ingredientStore.UpdateIngredient(&model.CatalogIngredient{ID: "nope", Name: "Nope"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRecipeFlags_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "flour", Name: "Flour", Allergens: []string{"gluten"}, Diets: []string{"vegan", "vegetarian"}},
+		{ID: "butter", Name: "Butter", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+		{ID: "oil", Name: "Oil", Diets: []string{"vegan", "vegetarian"}},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	for _, r := range []model.Recipe{
+		{ID: "r1", Slug: "shortbread", Name: "Shortbread", Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 1}, {LineID: "l2", ID: "butter", Quantity: 1}}},
+		{ID: "r2", Slug: "flatbread", Name: "Flatbread", Ingredients: []model.Ingredient{{LineID: "l3", ID: "flour", Quantity: 1}, {LineID: "l4", ID: "oil", Quantity: 1}}},
+		{ID: "r3", Slug: "water", Name: "Water"},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+
+	shortbread, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dairy", "gluten"}, shortbread.Allergens)
+	assert.Equal(t, []string{"vegetarian"}, shortbread.Diets)
+
+	names := func(f store.RecipeFilter) []string {
+		recipes, err := recipeStore.ListRecipes(f)
+		require.NoError(t, err)
+		names := []string{}
+		for _, r := range recipes {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Flatbread"}, names(store.RecipeFilter{Diets: []string{"vegan"}}))
+	assert.Equal(t, []string{"Flatbread", "Shortbread"}, names(store.RecipeFilter{Diets: []string{"vegetarian"}}))
+	assert.Equal(t, []string{"Flatbread", "Water"}, names(store.RecipeFilter{ExcludeAllergens: []string{"dairy", "nut"}}))
+
+	// Flags follow changes to the catalog.
+	butter, err := ingredientStore.GetIngredientByID("butter")
+	require.NoError(t, err)
+	butter.Allergens = []string{}
+	butter.Diets = []string{"vegan", "vegetarian"}
+	_, err = ingredientStore.UpdateIngredient(butter)
+	require.NoError(t, err)
+
+	shortbread, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gluten"}, shortbread.Allergens)
+	assert.Equal(t, []string{"vegan", "vegetarian"}, shortbread.Diets)
+	assert.Equal(t, []string{"Flatbread", "Shortbread"}, names(store.RecipeFilter{Diets: []string{"vegan"}}))
+}
+
+func TestIngredientAliases_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	nutritionStore := store.NewSQLiteNutritionStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "scallion", Name: "Scallion", Aliases: []string{"green onion"}})
+	require.NoError(t, err)
+	_, err = ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "leek", Name: "Leek", Aliases: []string{"Green Onion"}})
+	assert.ErrorIs(t, err, store.ErrAliasTaken)
+	_, err = ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "leek", Name: "Leek", Aliases: []string{"scallion"}})
+	assert.ErrorIs(t, err, store.ErrAliasTaken)
+
+	found, err := ingredientStore.FindIngredientByName("GREEN ONION")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "scallion", found.ID)
+	assert.Equal(t, []string{"green onion"}, found.Aliases)
+
+	found, err = ingredientStore.FindIngredientByName("scallion")
+	require.NoError(t, err)
+	assert.Equal(t, "scallion", found.ID)
+
+	found, err = ingredientStore.FindIngredientByName("leek")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	_, err = ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "chive", Name: "Chive", Aliases: []string{"CHIVE", "chives"}})
+	require.NoError(t, err)
+	chive, err := ingredientStore.GetIngredientByID("chive")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chives"}, chive.Aliases)
+
+	unmatched, err := nutritionStore.ImportNutrition([]model.IngredientNutrition{{Name: "Green onion", Per100g: model.NutritionFacts{Calories: 32}}})
+	require.NoError(t, err)
+	assert.Empty(t, unmatched)
+}
+
+func TestMergeIngredients_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+	pantryStore := store.NewSQLitePantryStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "scallion", Name: "Scallion", Allergens: []string{}, Diets: []string{"vegan", "vegetarian"}},
+		{ID: "green-onion", Name: "Green Onion", Aliases: []string{"salad onion"}},
+		{ID: "spring-onion", Name: "Spring Onion"},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	for _, r := range []model.Recipe{
+		{ID: "r1", Slug: "pancakes", Name: "Scallion Pancakes", Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "scallion", Quantity: 2, Unit: "tbsp", Note: "sliced"},
+			{LineID: "l2", ID: "green-onion", Quantity: 1, Unit: "tbsp"},
+			{LineID: "l3", ID: "spring-onion", Quantity: 1, Unit: "bunch"},
+		}},
+		{ID: "r2", Slug: "salad", Name: "Salad", Ingredients: []model.Ingredient{{LineID: "l4", ID: "green-onion", Quantity: 3, Unit: ""}}},
+		{ID: "r3", Slug: "salad-bowl", Name: "Salad Bowl", Ingredients: []model.Ingredient{{LineID: "l5", RecipeID: "r2", Quantity: 1}}},
+		{ID: "r4", Slug: "toast", Name: "Toast"},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+	before, err := recipeStore.SyncRecipes(0)
+	require.NoError(t, err)
+
+	_, err = pantryStore.CreateStockedIngredient(&model.StockedIngredient{ID: "s1", IngredientID: "spring-onion", Quantity: 1, Unit: "bunch"})
+	require.NoError(t, err)
+
+	_, err = ingredientStore.MergeIngredients("scallion", []string{"green-onion", "nope"})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+	_, err = ingredientStore.MergeIngredients("nope", []string{"green-onion"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	merged, err := ingredientStore.MergeIngredients("scallion", []string{"green-onion", "spring-onion"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Green Onion", "salad onion", "Spring Onion"}, merged.Aliases)
+	assert.Equal(t, []string{"vegan", "vegetarian"}, merged.Diets)
+
+	pancakes, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, pancakes.Ingredients, 3)
+	for n, line := range pancakes.Ingredients {
+		assert.Equal(t, fmt.Sprintf("l%d", n+1), line.LineID)
+		assert.Equal(t, "scallion", line.ID)
+	}
+	assert.Equal(t, "bunch", pancakes.Ingredients[2].Unit)
+
+	salad, err := recipeStore.GetRecipeByID("r2")
+	require.NoError(t, err)
+	require.Len(t, salad.Ingredients, 1)
+	assert.Equal(t, "scallion", salad.Ingredients[0].ID)
+
+	// Recipes using the merged ingredients, even through a sub-recipe, are
+	// a new version that sync clients hear about.
+	changes, err := recipeStore.SyncRecipes(before.Cursor)
+	require.NoError(t, err)
+	changed := map[string]int{}
+	for _, r := range changes.Recipes {
+		changed[r.ID] = r.Version
+	}
+	assert.Equal(t, map[string]int{"r1": 2, "r2": 2, "r3": 2}, changed)
+	toast, err := recipeStore.GetRecipeByID("r4")
+	require.NoError(t, err)
+	assert.Equal(t, 1, toast.Version)
+
+	stock, err := pantryStore.ListStockedIngredients()
+	require.NoError(t, err)
+	require.Len(t, stock, 1)
+	assert.Equal(t, "scallion", stock[0].IngredientID)
+
+	gone, err := ingredientStore.GetIngredientByID("green-onion")
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+
+	found, err := ingredientStore.FindIngredientByName("spring onion")
+	require.NoError(t, err)
+	assert.Equal(t, "scallion", found.ID)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLiteMealPlanStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteMealPlanStore(db *sql.DB) *SQLiteMealPlanStore {
+	return &SQLiteMealPlanStore{db: db}
+}
+
+type MealPlanStore interface {
+	ListEntries(userID, from, to string) ([]model.MealPlanEntry, error)
+	CreateEntry(*model.MealPlanEntry) (*model.MealPlanEntry, error)
+	DeleteEntry(id, userID string) error
+}
+
+// ListEntries returns the user's entries planned between from and to,
+// inclusive, leaving out recipes in the trash. An empty user id lists the
+// entries planned before plans had owners. Dates are compared as YYYY-MM-DD
+// strings.
+func (s *SQLiteMealPlanStore) ListEntries(userID, from, to string) ([]model.MealPlanEntry, error) {
+	query := `
+		SELECT m.id, m.recipe_id, r.name, COALESCE(m.user_id, ''), m.planned_for, m.meal, m.servings, COALESCE(m.note, ''), m.created_at
+		FROM meal_plan_entries m
+		JOIN recipes r ON r.id = m.recipe_id
+		WHERE m.user_id IS NULLIF(?, '') AND m.planned_for BETWEEN ? AND ? AND r.deleted_at IS NULL
+		ORDER BY m.planned_for ASC, m.created_at ASC;
+	`
+
+	rows, err := s.db.Query(query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.MealPlanEntry{}
+	for rows.Next() {
+		var e model.MealPlanEntry
+		err = rows.Scan(&e.ID, &e.RecipeID, &e.RecipeName, &e.UserID, &e.Date, &e.Meal, &e.Servings, &e.Note, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *SQLiteMealPlanStore) CreateEntry(e *model.MealPlanEntry) (*model.MealPlanEntry, error) {
+	query := `
+		INSERT INTO meal_plan_entries (id, recipe_id, user_id, planned_for, meal, servings, note)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, ?);
+	`
+
+	_, err := s.db.Exec(query, e.ID, e.RecipeID, e.UserID, e.Date, e.Meal, e.Servings, e.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// DeleteEntry returns sql.ErrNoRows when the user's plan, or the unowned
+// entries for an empty user id, has no entry with the id.
+func (s *SQLiteMealPlanStore) DeleteEntry(id, userID string) error {
+	query := `
+		DELETE FROM meal_plan_entries
+		WHERE id = ? AND user_id IS NULLIF(?, '');
+	`
+
+	result, err := s.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
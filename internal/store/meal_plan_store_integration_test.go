@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMealPlanEntries_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO recipes (id, slug, name, servings, prep_time_seconds, cook_time_seconds)
+		VALUES ("r1", "pancakes", "Pancakes", 4, 600, 900);
+		INSERT INTO users (id, name) VALUES ("u1", "sam");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	mealPlanStore := store.NewSQLiteMealPlanStore(db)
+
+	for _, e := range []model.MealPlanEntry{
+		{ID: "m1", RecipeID: "r1", Date: "2025-11-02", Servings: 2},
+		{ID: "m2", RecipeID: "r1", Date: "2025-11-03", Meal: "breakfast", Servings: 4},
+		{ID: "m3", RecipeID: "r1", Date: "2025-11-10", Servings: 4},
+		{ID: "m4", RecipeID: "r1", UserID: "u1", Date: "2025-11-04", Servings: 2},
+	} {
+		_, err := mealPlanStore.CreateEntry(&e)
+		require.NoError(t, err)
+	}
+
+	entries, err := mealPlanStore.ListEntries("", "2025-11-03", "2025-11-09")
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "m2", entries[0].ID)
+	assert.Equal(t, "Pancakes", entries[0].RecipeName)
+	assert.Equal(t, "breakfast", entries[0].Meal)
+
+	// Each user has their own plan, apart from the shared one.
+	entries, err = mealPlanStore.ListEntries("u1", "2025-11-03", "2025-11-09")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "m4", entries[0].ID)
+	assert.Equal(t, "u1", entries[0].UserID)
+
+	assert.ErrorIs(t, mealPlanStore.DeleteEntry("m4", ""), sql.ErrNoRows)
+	assert.ErrorIs(t, mealPlanStore.DeleteEntry("m2", "u1"), sql.ErrNoRows)
+	assert.NoError(t, mealPlanStore.DeleteEntry("m4", "u1"))
+	assert.NoError(t, mealPlanStore.DeleteEntry("m2", ""))
+	assert.ErrorIs(t, mealPlanStore.DeleteEntry("m2", ""), sql.ErrNoRows)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLiteNutritionStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteNutritionStore(db *sql.DB) *SQLiteNutritionStore {
+	return &SQLiteNutritionStore{db: db}
+}
+
+type NutritionStore interface {
+	ListNutrition() ([]model.IngredientNutrition, error)
+	ImportNutrition([]model.IngredientNutrition) ([]model.IngredientNutrition, error)
+}
+
+func (s *SQLiteNutritionStore) ListNutrition() ([]model.IngredientNutrition, error) {
+	query := `
+		SELECT n.ingredient_id, i.name, n.calories, n.protein_g, n.fat_g, n.saturated_fat_g, n.carbs_g, n.fiber_g,
+			n.sugar_g, n.sodium_mg, n.calcium_mg, n.iron_mg, n.potassium_mg, n.vitamin_c_mg, COALESCE(n.source, ''), n.updated_at
+		FROM ingredient_nutrition n
+		JOIN ingredients i ON i.id = n.ingredient_id
+		ORDER BY i.name ASC;
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []model.IngredientNutrition{}
+	for rows.Next() {
+		var n model.IngredientNutrition
+		f := &n.Per100g
+		err = rows.Scan(&n.IngredientID, &n.Name, &f.Calories, &f.Protein, &f.Fat, &f.SaturatedFat, &f.Carbohydrates, &f.Fiber,
+			&f.Sugar, &f.Sodium, &f.Calcium, &f.Iron, &f.Potassium, &f.VitaminC, &n.Source, &n.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ImportNutrition links each row to the catalog by ingredient id, or by
+// case-insensitive name or alias when the row has no id, and replaces any nutrition
+// already stored for the ingredient. Rows that match no ingredient are
+// returned; the import is all or nothing otherwise.
+func (s *SQLiteNutritionStore) ImportNutrition(data []model.IngredientNutrition) ([]model.IngredientNutrition, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ingredient_nutrition (ingredient_id, calories, protein_g, fat_g, saturated_fat_g, carbs_g, fiber_g,
+			sugar_g, sodium_mg, calcium_mg, iron_mg, potassium_mg, vitamin_c_mg, source)
+		SELECT id, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		FROM ingredients
+		WHERE (? <> '' AND id = ?) OR (? = '' AND id = ` + ingredientIDByName + `)
+		ON CONFLICT (ingredient_id) DO UPDATE
+		SET calories = excluded.calories, protein_g = excluded.protein_g, fat_g = excluded.fat_g,
+			saturated_fat_g = excluded.saturated_fat_g, carbs_g = excluded.carbs_g, fiber_g = excluded.fiber_g,
+			sugar_g = excluded.sugar_g, sodium_mg = excluded.sodium_mg, calcium_mg = excluded.calcium_mg,
+			iron_mg = excluded.iron_mg, potassium_mg = excluded.potassium_mg, vitamin_c_mg = excluded.vitamin_c_mg,
+			source = excluded.source, updated_at = CURRENT_TIMESTAMP;
+	`
+
+	unmatched := []model.IngredientNutrition{}
+	for _, n := range data {
+		f := n.Per100g
+		result, err := tx.Exec(query, f.Calories, f.Protein, f.Fat, f.SaturatedFat, f.Carbohydrates, f.Fiber,
+			f.Sugar, f.Sodium, f.Calcium, f.Iron, f.Potassium, f.VitaminC, n.Source,
+			n.IngredientID, n.IngredientID, n.IngredientID, n.Name, n.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected == 0 {
+			unmatched = append(unmatched, n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return unmatched, nil
+}
@@ -0,0 +1,47 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNutritionStore_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO ingredients (id, name, category)
+		VALUES ("flour", "Flour", "baking"), ("butter", "Butter", "dairy");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	nutritionStore := store.NewSQLiteNutritionStore(db)
+
+	unmatched, err := nutritionStore.ImportNutrition([]model.IngredientNutrition{
+		{Name: "flour", Per100g: model.NutritionFacts{Calories: 364}, Source: "v1"},
+		{IngredientID: "butter", Per100g: model.NutritionFacts{Calories: 717}, Source: "v1"},
+		{Name: "Saffron", Per100g: model.NutritionFacts{Calories: 310}, Source: "v1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, unmatched, 1)
+	assert.Equal(t, "Saffron", unmatched[0].Name)
+
+	_, err = nutritionStore.ImportNutrition([]model.IngredientNutrition{
+		{Name: "Flour", Per100g: model.NutritionFacts{Calories: 360, Protein: 10}, Source: "v2"},
+	})
+	require.NoError(t, err)
+
+	data, err := nutritionStore.ListNutrition()
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	assert.Equal(t, "Butter", data[0].Name)
+	assert.Equal(t, "flour", data[1].IngredientID)
+	assert.Equal(t, model.NutritionFacts{Calories: 360, Protein: 10}, data[1].Per100g)
+	assert.Equal(t, "v2", data[1].Source)
+}
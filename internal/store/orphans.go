@@ -0,0 +1,162 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const (
+	OrphanDeleted = "deleted"
+	OrphanCleared = "cleared"
+)
+
+// OrphanCleanup counts the rows fixed for one kind of broken reference.
+type OrphanCleanup struct {
+	Table  string
+	Column string
+	Parent string
+	Action string
+	Rows   int64
+}
+
+// CleanOrphans fixes rows that refer to records which no longer exist, as
+// databases written before foreign keys were enforced may have. Each row
+// is fixed the way its foreign key would have done on delete: references
+// declared ON DELETE SET NULL are cleared and any other row is deleted.
+// It makes all fixes in one transaction and reports what it did.
+func CleanOrphans(db *sql.DB) ([]OrphanCleanup, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	orphans, err := findOrphans(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanups := []OrphanCleanup{}
+	index := map[OrphanCleanup]int{}
+	keys := map[string]map[int]foreignKey{}
+	for _, o := range orphans {
+		if keys[o.table] == nil {
+			keys[o.table], err = foreignKeys(tx, o.table)
+			if err != nil {
+				return nil, err
+			}
+		}
+		fk := keys[o.table][o.fkid]
+
+		action := OrphanDeleted
+		query := fmt.Sprintf(`DELETE FROM %q WHERE rowid = ?`, o.table)
+		if fk.onDelete == "SET NULL" {
+			action = OrphanCleared
+			query = fmt.Sprintf(`UPDATE %q SET %q = NULL WHERE rowid = ?`, o.table, fk.column)
+		}
+
+		result, err := tx.Exec(query, o.rowid)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		// The row may be gone already, deleted for another broken key or
+		// by a cascade.
+		if rows == 0 {
+			continue
+		}
+
+		key := OrphanCleanup{Table: o.table, Column: fk.column, Parent: o.parent, Action: action}
+		if _, ok := index[key]; !ok {
+			index[key] = len(cleanups)
+			cleanups = append(cleanups, key)
+		}
+		cleanups[index[key]].Rows += rows
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cleanups, nil
+}
+
+// CountOrphans returns how many rows violate a declared foreign key. A
+// row with several broken keys counts once.
+func CountOrphans(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	orphans, err := findOrphans(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := map[orphan]bool{}
+	for _, o := range orphans {
+		rows[orphan{table: o.table, rowid: o.rowid}] = true
+	}
+	return len(rows), nil
+}
+
+type orphan struct {
+	table  string
+	rowid  int64
+	parent string
+	fkid   int
+}
+
+// findOrphans lists every row violating a declared foreign key.
+func findOrphans(tx *sql.Tx) ([]orphan, error) {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orphans := []orphan{}
+	for rows.Next() {
+		var o orphan
+		if err := rows.Scan(&o.table, &o.rowid, &o.parent, &o.fkid); err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, o)
+	}
+
+	return orphans, rows.Err()
+}
+
+type foreignKey struct {
+	column   string
+	onDelete string
+}
+
+// foreignKeys returns the table's foreign keys by id. Every key in this
+// schema has a single column.
+func foreignKeys(tx *sql.Tx, table string) (map[int]foreignKey, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA foreign_key_list(%q)`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[int]foreignKey{}
+	for rows.Next() {
+		var id, seq int
+		var parent, from string
+		var to, onUpdate, onDelete, match sql.NullString
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		keys[id] = foreignKey{column: from, onDelete: onDelete.String}
+	}
+
+	return keys, rows.Err()
+}
@@ -0,0 +1,119 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stevmwhitfield/recipe-api/internal/data/migrations"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanOrphans_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour"})
+	require.NoError(t, err)
+	_, err = recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "bread", Name: "Bread",
+		Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 500, Unit: "g"}},
+	})
+	require.NoError(t, err)
+
+	// Write the kind of rows a database could collect before foreign keys
+	// were enforced.
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	for _, q := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO recipe_ingredient (id, recipe_id, ingredient_id, position, quantity, unit) VALUES ('l2', 'r1', 'yeast', 1, 7, 'g')`,
+		`INSERT INTO instructions (id, recipe_id, step_number, description) VALUES ('s1', 'gone', 1, 'Knead.')`,
+		`INSERT INTO recipe_tag (recipe_id, tag_id) VALUES ('r1', 'gone')`,
+		`INSERT INTO recipe_tag (recipe_id, tag_id) VALUES ('gone', 'gone')`,
+		`INSERT INTO cook_logs (id, recipe_id, user_id, cooked_on, servings) VALUES ('c1', 'r1', 'ghost', '2025-11-01', 2)`,
+		`UPDATE recipe_ingredient SET section_id = 'gone' WHERE id = 'l1'`,
+		`UPDATE recipes SET parent_id = 'gone' WHERE id = 'r1'`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		_, err := conn.ExecContext(ctx, q)
+		require.NoError(t, err, q)
+	}
+	require.NoError(t, conn.Close())
+
+	cleanups, err := store.CleanOrphans(db)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.OrphanCleanup{
+		{Table: "recipe_ingredient", Column: "ingredient_id", Parent: "ingredients", Action: store.OrphanDeleted, Rows: 1},
+		{Table: "instructions", Column: "recipe_id", Parent: "recipes", Action: store.OrphanDeleted, Rows: 1},
+		{Table: "recipe_tag", Column: "tag_id", Parent: "tags", Action: store.OrphanDeleted, Rows: 2},
+		{Table: "cook_logs", Column: "user_id", Parent: "users", Action: store.OrphanCleared, Rows: 1},
+		{Table: "recipe_ingredient", Column: "section_id", Parent: "recipe_sections", Action: store.OrphanCleared, Rows: 1},
+		{Table: "recipes", Column: "parent_id", Parent: "recipes", Action: store.OrphanCleared, Rows: 1},
+	}, cleanups)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, recipe.Ingredients, 1)
+	assert.Equal(t, "l1", recipe.Ingredients[0].LineID)
+	assert.Empty(t, recipe.Ingredients[0].SectionID)
+	assert.Nil(t, recipe.ParentID)
+	assert.Empty(t, recipe.Tags)
+	assert.Equal(t, 1, recipe.TimesCooked)
+
+	// A second run finds nothing left to fix.
+	cleanups, err = store.CleanOrphans(db)
+	require.NoError(t, err)
+	assert.Empty(t, cleanups)
+}
+
+func TestMigrateWithOrphans_Integration(t *testing.T) {
+	db, err := sql.Open("sqlite3", store.DSN(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// An old database, from before deleting a recipe removed its lines.
+	goose.SetBaseFS(migrations.FS)
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	require.NoError(t, goose.UpTo(db, ".", 13))
+	goose.SetBaseFS(nil)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	for _, q := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO ingredients (id, name, category) VALUES ('flour', 'Flour', 'baking')`,
+		`INSERT INTO recipe_ingredient (recipe_id, ingredient_id, quantity, unit) VALUES ('gone', 'flour', 500, 'g')`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		_, err := conn.ExecContext(ctx, q)
+		require.NoError(t, err, q)
+	}
+	require.NoError(t, conn.Close())
+
+	require.NoError(t, store.MigrateFS(db, migrations.FS, "."))
+
+	var foreignKeys int
+	require.NoError(t, db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys))
+	assert.Equal(t, 1, foreignKeys)
+
+	orphans, err := store.CountOrphans(db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, orphans)
+
+	_, err = store.CleanOrphans(db)
+	require.NoError(t, err)
+	orphans, err = store.CountOrphans(db)
+	require.NoError(t, err)
+	assert.Zero(t, orphans)
+}
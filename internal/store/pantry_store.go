@@ -0,0 +1,292 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+var ErrUnknownIngredient = errors.New("unknown ingredient")
+
+type SQLitePantryStore struct {
+	db *sql.DB
+}
+
+func NewSQLitePantryStore(db *sql.DB) *SQLitePantryStore {
+	return &SQLitePantryStore{db: db}
+}
+
+type PantryStore interface {
+	ListStockedIngredients() ([]model.StockedIngredient, error)
+	ListExpiringIngredients(before string) ([]model.StockedIngredient, error)
+	GetStockedIngredientByID(id string) (*model.StockedIngredient, error)
+	CreateStockedIngredient(*model.StockedIngredient) (*model.StockedIngredient, error)
+	UpdateStockedIngredient(*model.StockedIngredient) (*model.StockedIngredient, error)
+	DeleteStockedIngredient(id string) error
+	ListThresholds() ([]model.StockThreshold, error)
+	SetThreshold(*model.StockThreshold) (*model.StockThreshold, error)
+	DeleteThreshold(ingredientID string) error
+}
+
+const stockedIngredientColumns = `
+	s.id, s.ingredient_id, i.name, s.quantity, s.unit, COALESCE(s.note, ''),
+	s.best_before, s.opened_on, s.location, s.created_at, s.updated_at,
+	COALESCE(i.density_g_per_ml, 0), COALESCE(i.piece_weight_g, 0)
+`
+
+func (s *SQLitePantryStore) ListStockedIngredients() ([]model.StockedIngredient, error) {
+	query := `
+		SELECT ` + stockedIngredientColumns + `
+		FROM stocked_ingredients s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		ORDER BY i.name ASC;
+	`
+
+	return queryStockedIngredients(s.db, query)
+}
+
+// ListExpiringIngredients returns stock that is not used up and has a
+// best-before date on or before the given date, soonest first.
+func (s *SQLitePantryStore) ListExpiringIngredients(before string) ([]model.StockedIngredient, error) {
+	query := `
+		SELECT ` + stockedIngredientColumns + `
+		FROM stocked_ingredients s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		WHERE s.best_before IS NOT NULL AND s.best_before <= ? AND s.quantity > 0
+		ORDER BY s.best_before ASC, i.name ASC;
+	`
+
+	return queryStockedIngredients(s.db, query, before)
+}
+
+func (s *SQLitePantryStore) GetStockedIngredientByID(id string) (*model.StockedIngredient, error) {
+	query := `
+		SELECT ` + stockedIngredientColumns + `
+		FROM stocked_ingredients s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		WHERE s.id = ?;
+	`
+
+	stock, err := queryStockedIngredients(s.db, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(stock) == 0 {
+		return nil, nil
+	}
+
+	return &stock[0], nil
+}
+
+// CreateStockedIngredient returns ErrUnknownIngredient when the ingredient
+// is not in the catalog.
+func (s *SQLitePantryStore) CreateStockedIngredient(si *model.StockedIngredient) (*model.StockedIngredient, error) {
+	query := `
+		INSERT INTO stocked_ingredients (id, ingredient_id, quantity, unit, note, best_before, opened_on, location)
+		SELECT ?, id, ?, ?, ?, ?, ?, ?
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, si.ID, si.Quantity, si.Unit, si.Note, si.BestBefore, si.OpenedOn, si.Location, si.IngredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUnknownIngredient
+	}
+
+	return s.GetStockedIngredientByID(si.ID)
+}
+
+// UpdateStockedIngredient adds the ingredient to the active shopping list
+// when the new quantity drops it below its threshold.
+func (s *SQLitePantryStore) UpdateStockedIngredient(si *model.StockedIngredient) (*model.StockedIngredient, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE stocked_ingredients
+		SET quantity = ?, unit = ?, note = ?, best_before = ?, opened_on = ?, location = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?;
+	`
+
+	result, err := tx.Exec(query, si.Quantity, si.Unit, si.Note, si.BestBefore, si.OpenedOn, si.Location, si.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := restockIfLow(tx, si.IngredientID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetStockedIngredientByID(si.ID)
+}
+
+// DeleteStockedIngredient adds the ingredient to the active shopping list
+// when removing the stock drops it below its threshold.
+func (s *SQLitePantryStore) DeleteStockedIngredient(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM stocked_ingredients
+		WHERE id = ?
+		RETURNING ingredient_id;
+	`
+
+	var ingredientID string
+	err = tx.QueryRow(query, id).Scan(&ingredientID)
+	if err != nil {
+		return err
+	}
+
+	if err := restockIfLow(tx, ingredientID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLitePantryStore) ListThresholds() ([]model.StockThreshold, error) {
+	query := `
+		SELECT t.ingredient_id, i.name, t.min_quantity, t.unit, t.updated_at
+		FROM stock_thresholds t
+		JOIN ingredients i ON i.id = t.ingredient_id
+		ORDER BY i.name ASC;
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	thresholds := []model.StockThreshold{}
+	for rows.Next() {
+		var t model.StockThreshold
+		err = rows.Scan(&t.IngredientID, &t.Name, &t.MinQuantity, &t.Unit, &t.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return thresholds, nil
+}
+
+// SetThreshold creates or replaces the ingredient's threshold and returns
+// ErrUnknownIngredient when the ingredient is not in the catalog.
+func (s *SQLitePantryStore) SetThreshold(t *model.StockThreshold) (*model.StockThreshold, error) {
+	query := `
+		INSERT INTO stock_thresholds (ingredient_id, min_quantity, unit)
+		SELECT id, ?, ?
+		FROM ingredients
+		WHERE id = ?
+		ON CONFLICT (ingredient_id) DO UPDATE
+		SET min_quantity = excluded.min_quantity, unit = excluded.unit, updated_at = CURRENT_TIMESTAMP;
+	`
+
+	result, err := s.db.Exec(query, t.MinQuantity, t.Unit, t.IngredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUnknownIngredient
+	}
+
+	query = `
+		SELECT t.ingredient_id, i.name, t.min_quantity, t.unit, t.updated_at
+		FROM stock_thresholds t
+		JOIN ingredients i ON i.id = t.ingredient_id
+		WHERE t.ingredient_id = ?;
+	`
+
+	err = s.db.QueryRow(query, t.IngredientID).Scan(&t.IngredientID, &t.Name, &t.MinQuantity, &t.Unit, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *SQLitePantryStore) DeleteThreshold(ingredientID string) error {
+	query := `
+		DELETE FROM stock_thresholds
+		WHERE ingredient_id = ?;
+	`
+
+	result, err := s.db.Exec(query, ingredientID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func queryStockedIngredients(db queryer, query string, args ...any) ([]model.StockedIngredient, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := []model.StockedIngredient{}
+	for rows.Next() {
+		var si model.StockedIngredient
+		err = rows.Scan(&si.ID, &si.IngredientID, &si.Name, &si.Quantity, &si.Unit, &si.Note,
+			&si.BestBefore, &si.OpenedOn, &si.Location, &si.CreatedAt, &si.UpdatedAt,
+			&si.Density.GramsPerMilliliter, &si.Density.GramsPerPiece)
+		if err != nil {
+			return nil, err
+		}
+		stock = append(stock, si)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
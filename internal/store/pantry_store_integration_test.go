@@ -0,0 +1,115 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPantry_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO ingredients (id, name, category)
+		VALUES ("milk", "Milk", "dairy"), ("flour", "Flour", "baking");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	pantryStore := store.NewSQLitePantryStore(db)
+
+	soon, later := "2025-11-02", "2025-12-01"
+	milk, err := pantryStore.CreateStockedIngredient(&model.StockedIngredient{
+		ID: "s1", IngredientID: "milk", Quantity: 1, Unit: "l", BestBefore: &soon, Location: "fridge",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Milk", milk.Name)
+	assert.Equal(t, "fridge", milk.Location)
+
+	_, err = pantryStore.CreateStockedIngredient(&model.StockedIngredient{ID: "s2", IngredientID: "flour", Quantity: 1, Unit: "kg", BestBefore: &later})
+	require.NoError(t, err)
+
+	_, err = pantryStore.CreateStockedIngredient(&model.StockedIngredient{ID: "s3", IngredientID: "nope", Quantity: 1, Unit: "kg"})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+
+	expiring, err := pantryStore.ListExpiringIngredients("2025-11-09")
+	assert.NoError(t, err)
+	assert.Len(t, expiring, 1)
+	assert.Equal(t, "s1", expiring[0].ID)
+
+	milk.Quantity = 0
+	milk.BestBefore = nil
+	updated, err := pantryStore.UpdateStockedIngredient(milk)
+	assert.NoError(t, err)
+	assert.Nil(t, updated.BestBefore)
+
+	assert.NoError(t, pantryStore.DeleteStockedIngredient("s2"))
+	stock, err := pantryStore.ListStockedIngredients()
+	assert.NoError(t, err)
+	assert.Len(t, stock, 1)
+}
+
+func TestPantryThresholds_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO ingredients (id, name, category)
+		VALUES ("milk", "Milk", "dairy");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	pantryStore := store.NewSQLitePantryStore(db)
+	shoppingListStore := store.NewSQLiteShoppingListStore(db)
+
+	threshold, err := pantryStore.SetThreshold(&model.StockThreshold{IngredientID: "milk", MinQuantity: 2, Unit: "l"})
+	require.NoError(t, err)
+	assert.Equal(t, "Milk", threshold.Name)
+
+	_, err = pantryStore.SetThreshold(&model.StockThreshold{IngredientID: "nope", MinQuantity: 1, Unit: "l"})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+
+	milk, err := pantryStore.CreateStockedIngredient(&model.StockedIngredient{ID: "s1", IngredientID: "milk", Quantity: 3, Unit: "l"})
+	require.NoError(t, err)
+
+	milk.Quantity = 500
+	milk.Unit = "ml"
+	_, err = pantryStore.UpdateStockedIngredient(milk)
+	require.NoError(t, err)
+
+	list, err := shoppingListStore.GetActiveShoppingList()
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, "milk", list.Items[0].IngredientID)
+	assert.Equal(t, 1.5, list.Items[0].Quantity)
+	assert.Equal(t, "l", list.Items[0].Unit)
+	assert.Equal(t, store.ShoppingListItemSourceLowStock, list.Items[0].Source)
+
+	// Dropping further does not add the ingredient twice.
+	assert.NoError(t, pantryStore.DeleteStockedIngredient("s1"))
+	list, err = shoppingListStore.GetActiveShoppingList()
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+
+	completed, err := shoppingListStore.CompleteActiveShoppingList()
+	require.NoError(t, err)
+	assert.Equal(t, "completed", completed.Status)
+	assert.NotNil(t, completed.CompletedAt)
+
+	list, err = shoppingListStore.GetActiveShoppingList()
+	require.NoError(t, err)
+	assert.NotEqual(t, completed.ID, list.ID)
+	assert.Empty(t, list.Items)
+
+	assert.NoError(t, pantryStore.DeleteThreshold("milk"))
+	thresholds, err := pantryStore.ListThresholds()
+	assert.NoError(t, err)
+	assert.Empty(t, thresholds)
+}
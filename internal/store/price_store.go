@@ -0,0 +1,124 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLitePriceStore struct {
+	db *sql.DB
+}
+
+func NewSQLitePriceStore(db *sql.DB) *SQLitePriceStore {
+	return &SQLitePriceStore{db: db}
+}
+
+type PriceStore interface {
+	ListPrices(ingredientID string) ([]model.IngredientPrice, error)
+	GetPriceByID(id string) (*model.IngredientPrice, error)
+	CreatePrice(*model.IngredientPrice) (*model.IngredientPrice, error)
+	DeletePrice(id string) error
+}
+
+// ListPrices lists the prices recorded for an ingredient, or for every
+// ingredient when ingredientID is empty, newest first.
+func (s *SQLitePriceStore) ListPrices(ingredientID string) ([]model.IngredientPrice, error) {
+	query := `
+		SELECT p.id, p.ingredient_id, i.name, p.amount, p.unit, p.price, COALESCE(p.store, ''), p.priced_on, p.created_at
+		FROM ingredient_prices p
+		JOIN ingredients i ON i.id = p.ingredient_id
+		WHERE ? = '' OR p.ingredient_id = ?
+		ORDER BY p.priced_on DESC, p.created_at DESC;
+	`
+
+	rows, err := s.db.Query(query, ingredientID, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []model.IngredientPrice{}
+	for rows.Next() {
+		var p model.IngredientPrice
+		err = rows.Scan(&p.ID, &p.IngredientID, &p.Name, &p.Amount, &p.Unit, &p.Price, &p.Store, &p.PricedOn, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (s *SQLitePriceStore) GetPriceByID(id string) (*model.IngredientPrice, error) {
+	p := &model.IngredientPrice{}
+	query := `
+		SELECT p.id, p.ingredient_id, i.name, p.amount, p.unit, p.price, COALESCE(p.store, ''), p.priced_on, p.created_at
+		FROM ingredient_prices p
+		JOIN ingredients i ON i.id = p.ingredient_id
+		WHERE p.id = ?;
+	`
+
+	err := s.db.QueryRow(query, id).Scan(&p.ID, &p.IngredientID, &p.Name, &p.Amount, &p.Unit, &p.Price, &p.Store, &p.PricedOn, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// CreatePrice returns ErrUnknownIngredient when the ingredient is not in
+// the catalog.
+func (s *SQLitePriceStore) CreatePrice(p *model.IngredientPrice) (*model.IngredientPrice, error) {
+	query := `
+		INSERT INTO ingredient_prices (id, ingredient_id, amount, unit, price, store, priced_on)
+		SELECT ?, id, ?, ?, ?, ?, ?
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, p.ID, p.Amount, p.Unit, p.Price, p.Store, p.PricedOn, p.IngredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUnknownIngredient
+	}
+
+	return s.GetPriceByID(p.ID)
+}
+
+func (s *SQLitePriceStore) DeletePrice(id string) error {
+	query := `
+		DELETE FROM ingredient_prices
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
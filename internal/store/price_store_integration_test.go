@@ -0,0 +1,50 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceStore_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	q := `
+		INSERT INTO ingredients (id, name, category)
+		VALUES ("flour", "Flour", "baking"), ("milk", "Milk", "dairy");
+	`
+
+	_, err := db.Exec(q)
+	require.NoError(t, err)
+
+	priceStore := store.NewSQLitePriceStore(db)
+
+	created, err := priceStore.CreatePrice(&model.IngredientPrice{ID: "p1", IngredientID: "flour", Amount: 1, Unit: "kg", Price: 2, Store: "Market", PricedOn: "2025-09-01"})
+	require.NoError(t, err)
+	assert.Equal(t, "Flour", created.Name)
+	assert.Equal(t, "Market", created.Store)
+
+	_, err = priceStore.CreatePrice(&model.IngredientPrice{ID: "p2", IngredientID: "flour", Amount: 1, Unit: "kg", Price: 3, PricedOn: "2025-10-01"})
+	require.NoError(t, err)
+	_, err = priceStore.CreatePrice(&model.IngredientPrice{ID: "p3", IngredientID: "milk", Amount: 1, Unit: "l", Price: 1, PricedOn: "2025-10-01"})
+	require.NoError(t, err)
+
+	_, err = priceStore.CreatePrice(&model.IngredientPrice{ID: "p4", IngredientID: "nope", Amount: 1, Unit: "kg", Price: 1, PricedOn: "2025-10-01"})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+
+	prices, err := priceStore.ListPrices("flour")
+	assert.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, "p2", prices[0].ID)
+
+	prices, err = priceStore.ListPrices("")
+	assert.NoError(t, err)
+	assert.Len(t, prices, 3)
+
+	assert.NoError(t, priceStore.DeletePrice("p1"))
+	assert.Error(t, priceStore.DeletePrice("p1"))
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+// ListRevisions returns the recipe's revisions, oldest first, without
+// their snapshots.
+func (s *SQLiteRecipeStore) ListRevisions(recipeID string) ([]model.RecipeRevision, error) {
+	query := `
+		SELECT recipe_id, number, author_id, created_at
+		FROM recipe_revisions
+		WHERE recipe_id = ?
+		ORDER BY number ASC;
+	`
+
+	rows, err := s.db.Query(query, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.RecipeRevision{}
+	for rows.Next() {
+		var r model.RecipeRevision
+		err = rows.Scan(&r.RecipeID, &r.Number, &r.AuthorID, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteRecipeStore) GetRevision(recipeID string, number int) (*model.RecipeRevision, error) {
+	query := `
+		SELECT recipe_id, number, author_id, created_at, snapshot
+		FROM recipe_revisions
+		WHERE recipe_id = ? AND number = ?;
+	`
+
+	var r model.RecipeRevision
+	var snapshot string
+	err := s.db.QueryRow(query, recipeID, number).Scan(&r.RecipeID, &r.Number, &r.AuthorID, &r.CreatedAt, &snapshot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(snapshot), &r.Recipe); err != nil {
+		return nil, err
+	}
+	r.Recipe.GroupSections()
+
+	return &r, nil
+}
+
+// revisionBaseline returns the recipe's stored state when it has no
+// revisions yet, so its history starts before the first update. It reads
+// through tx so the state is the one the update replaces.
+func revisionBaseline(tx *sql.Tx, recipeID string) (*model.Recipe, error) {
+	var hasRevisions bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM recipe_revisions WHERE recipe_id = ?)`, recipeID).Scan(&hasRevisions)
+	if err != nil || hasRevisions {
+		return nil, err
+	}
+
+	r := &model.Recipe{}
+	query := `
+		SELECT id, slug, parent_id, name, servings, prep_time_seconds, cook_time_seconds, updated_at
+		FROM recipes
+		WHERE id = ?;
+	`
+
+	err = tx.QueryRow(query, recipeID).Scan(&r.ID, &r.Slug, &r.ParentID, &r.Name, &r.Servings, &r.PrepTimeSeconds, &r.CookTimeSeconds, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Ingredients, err = getIngredientsForRecipe(tx, r.ID); err != nil {
+		return nil, err
+	}
+	if r.Instructions, err = getInstructionsForRecipe(tx, r.ID); err != nil {
+		return nil, err
+	}
+	if r.Sections, err = getSectionsForRecipe(tx, r.ID); err != nil {
+		return nil, err
+	}
+	if r.Tags, err = getTagsForRecipe(tx, r.ID); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// insertRevision stores a snapshot of the recipe's contents as its next
+// revision. createdAt defaults to now.
+func insertRevision(tx *sql.Tx, recipe *model.Recipe, authorID string, createdAt *time.Time) error {
+	sections := []model.Section{}
+	for _, section := range recipe.Sections {
+		sections = append(sections, model.Section{ID: section.ID, Name: section.Name})
+	}
+
+	snapshot, err := json.Marshal(model.Recipe{
+		ID:              recipe.ID,
+		Slug:            recipe.Slug,
+		ParentID:        recipe.ParentID,
+		Name:            recipe.Name,
+		Servings:        recipe.Servings,
+		PrepTimeSeconds: recipe.PrepTimeSeconds,
+		CookTimeSeconds: recipe.CookTimeSeconds,
+		Ingredients:     recipe.Ingredients,
+		Instructions:    recipe.Instructions,
+		Sections:        sections,
+		Tags:            recipe.Tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recipe_revisions (recipe_id, number, author_id, snapshot, created_at)
+		SELECT ?, COALESCE(MAX(number), 0) + 1, NULLIF(?, ''), ?, COALESCE(?, CURRENT_TIMESTAMP)
+		FROM recipe_revisions
+		WHERE recipe_id = ?;
+	`
+
+	_, err = tx.Exec(query, recipe.ID, authorID, string(snapshot), createdAt, recipe.ID)
+	return err
+}
@@ -2,10 +2,39 @@ package store
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/stevmwhitfield/recipe-api/internal/model"
 )
 
+var (
+	// ErrRecipeInUse is returned when deleting a recipe that other recipes
+	// use as a sub-recipe.
+	ErrRecipeInUse = errors.New("recipe is used by other recipes")
+	// ErrRecipeHasVariations is returned when deleting a recipe that other
+	// recipes were forked from.
+	ErrRecipeHasVariations = errors.New("recipe has variations")
+	// ErrVersionConflict is returned when changing a recipe that has been
+	// changed since the version the caller read.
+	ErrVersionConflict = errors.New("recipe version conflict")
+	// ErrDeletedSubRecipe is returned when restoring a recipe that uses a
+	// sub-recipe which is still in the trash.
+	ErrDeletedSubRecipe = errors.New("recipe uses a deleted sub-recipe")
+)
+
+// ReferenceError is returned when saving a recipe that uses ingredients,
+// sub-recipes or tags that do not exist.
+type ReferenceError struct {
+	Missing []model.MissingReference
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("recipe refers to %d missing records", len(e.Missing))
+}
+
 type SQLiteRecipeStore struct {
 	db *sql.DB
 }
@@ -14,22 +43,82 @@ func NewSQLiteRecipeStore(db *sql.DB) *SQLiteRecipeStore {
 	return &SQLiteRecipeStore{db: db}
 }
 
+type RecipeFilter struct {
+	// NotCookedSince keeps recipes with no cook log on or after this date (YYYY-MM-DD).
+	NotCookedSince string
+	// ExcludeAllergens drops recipes with an ingredient carrying any of these allergens.
+	ExcludeAllergens []string
+	// Diets keeps recipes whose every ingredient is suitable for all of these diets.
+	Diets []string
+	// ExcludeIngredientIDs drops recipes using any of these ingredients.
+	ExcludeIngredientIDs []string
+}
+
 type RecipeStore interface {
-	ListRecipes() ([]model.Recipe, error)
+	ListRecipes(RecipeFilter) ([]model.Recipe, error)
 	CreateRecipe(*model.Recipe) (*model.Recipe, error)
 	GetRecipeByID(id string) (*model.Recipe, error)
-	UpdateRecipe(*model.Recipe) (*model.Recipe, error)
-	DeleteRecipe(id string) error
+	UpdateRecipe(recipe *model.Recipe, authorID string) (*model.Recipe, error)
+	DeleteRecipe(id string, version int, detachVariations bool) error
+	ListRevisions(recipeID string) ([]model.RecipeRevision, error)
+	GetRevision(recipeID string, number int) (*model.RecipeRevision, error)
+	ListDeletedRecipes() ([]model.Recipe, error)
+	RestoreRecipe(id string) error
+	PurgeRecipe(id string) error
+	PurgeDeletedRecipes(before time.Time) (int64, error)
+	SyncRecipes(since int64) (*model.RecipeSync, error)
+}
+
+func (s *SQLiteRecipeStore) ListRecipes(f RecipeFilter) ([]model.Recipe, error) {
+	conditions := []string{"r.deleted_at IS NULL"}
+	args := []any{}
+	if f.NotCookedSince != "" {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM cook_logs c WHERE c.recipe_id = r.id AND c.cooked_on >= ?)")
+		args = append(args, f.NotCookedSince)
+	}
+	if len(f.ExcludeAllergens) > 0 {
+		conditions = append(conditions, `NOT EXISTS (
+			SELECT 1 FROM recipe_ingredient_closure ri
+			JOIN ingredient_allergens a ON a.ingredient_id = ri.ingredient_id
+			WHERE ri.recipe_id = r.id AND a.allergen IN (`+placeholders(len(f.ExcludeAllergens))+`))`)
+		for _, a := range f.ExcludeAllergens {
+			args = append(args, a)
+		}
+	}
+	if len(f.ExcludeIngredientIDs) > 0 {
+		conditions = append(conditions, `NOT EXISTS (
+			SELECT 1 FROM recipe_ingredient_closure ri
+			WHERE ri.recipe_id = r.id AND ri.ingredient_id IN (`+placeholders(len(f.ExcludeIngredientIDs))+`))`)
+		for _, id := range f.ExcludeIngredientIDs {
+			args = append(args, id)
+		}
+	}
+	if len(f.Diets) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM recipe_ingredient_closure ri WHERE ri.recipe_id = r.id)")
+	}
+	for _, d := range f.Diets {
+		conditions = append(conditions, `NOT EXISTS (
+			SELECT 1 FROM recipe_ingredient_closure ri
+			WHERE ri.recipe_id = r.id AND NOT EXISTS (
+				SELECT 1 FROM ingredient_diets d WHERE d.ingredient_id = ri.ingredient_id AND d.diet = ?))`)
+		args = append(args, d)
+	}
+
+	return s.queryRecipes(strings.Join(conditions, " AND ")+" ORDER BY r.name ASC", args...)
 }
 
-func (s *SQLiteRecipeStore) ListRecipes() ([]model.Recipe, error) {
+// queryRecipes loads every recipe matching the where clause, which may end
+// with an ORDER BY.
+func (s *SQLiteRecipeStore) queryRecipes(where string, args ...any) ([]model.Recipe, error) {
 	query := `
-		SELECT id, slug, name, servings, prep_time_seconds, cook_time_seconds, created_at, updated_at 
-		FROM recipes
-		ORDER BY name ASC;
+		SELECT r.id, r.slug, r.version, r.parent_id, r.name, r.servings, r.prep_time_seconds, r.cook_time_seconds, r.created_at, r.updated_at, r.deleted_at,
+			(SELECT MAX(c.cooked_on) FROM cook_logs c WHERE c.recipe_id = r.id),
+			(SELECT COUNT(*) FROM cook_logs c WHERE c.recipe_id = r.id)
+		FROM recipes r
+		WHERE ` + where + `;
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -38,18 +127,31 @@ func (s *SQLiteRecipeStore) ListRecipes() ([]model.Recipe, error) {
 	var recipes []model.Recipe
 	for rows.Next() {
 		var r model.Recipe
-		err = rows.Scan(&r.ID, &r.Slug, &r.Name, &r.Servings, &r.PrepTimeSeconds, &r.CookTimeSeconds, &r.CreatedAt, &r.UpdatedAt)
+		err = rows.Scan(&r.ID, &r.Slug, &r.Version, &r.ParentID, &r.Name, &r.Servings, &r.PrepTimeSeconds, &r.CookTimeSeconds, &r.CreatedAt, &r.UpdatedAt, &r.DeletedAt, &r.LastCookedAt, &r.TimesCooked)
 		if err != nil {
 			return nil, err
 		}
 
-		if r.Ingredients, err = s.getIngredientsForRecipe(r.ID); err != nil {
+		if r.Ingredients, err = getIngredientsForRecipe(s.db, r.ID); err != nil {
+			return nil, err
+		}
+		if r.Instructions, err = getInstructionsForRecipe(s.db, r.ID); err != nil {
+			return nil, err
+		}
+		if r.Sections, err = getSectionsForRecipe(s.db, r.ID); err != nil {
+			return nil, err
+		}
+		r.GroupSections()
+		if r.Tags, err = getTagsForRecipe(s.db, r.ID); err != nil {
 			return nil, err
 		}
-		if r.Instructions, err = s.getInstructionsForRecipe(r.ID); err != nil {
+		if err = s.getFlagsForRecipe(&r); err != nil {
 			return nil, err
 		}
-		if r.Tags, err = s.getTagsForRecipe(r.ID); err != nil {
+		if r.UsedIn, err = s.getUsedInForRecipe(r.ID); err != nil {
+			return nil, err
+		}
+		if r.Variations, err = s.getVariationsForRecipe(r.ID); err != nil {
 			return nil, err
 		}
 
@@ -70,23 +172,36 @@ func (s *SQLiteRecipeStore) CreateRecipe(recipe *model.Recipe) (*model.Recipe, e
 	}
 	defer tx.Rollback()
 
+	if err := checkReferences(tx, recipe); err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO recipes (id, slug, name, servings, prep_time_seconds, cook_time_seconds)
-		VALUES (?, ?, ?, ?, ?, ?);
+		INSERT INTO recipes (id, slug, parent_id, name, servings, prep_time_seconds, cook_time_seconds, change_seq)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ` + nextChangeSeq + `);
 	`
 
-	_, err = tx.Exec(query, recipe.ID, recipe.Slug, recipe.Name, recipe.Servings, recipe.PrepTimeSeconds, recipe.CookTimeSeconds)
+	_, err = tx.Exec(query, recipe.ID, recipe.Slug, recipe.ParentID, recipe.Name, recipe.Servings, recipe.PrepTimeSeconds, recipe.CookTimeSeconds)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, i := range recipe.Ingredients {
+	err = tx.QueryRow(`SELECT created_at, updated_at FROM recipes WHERE id = ?`, recipe.ID).Scan(&recipe.CreatedAt, &recipe.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertSections(tx, recipe); err != nil {
+		return nil, err
+	}
+
+	for pos, i := range recipe.Ingredients {
 		query := `
-			INSERT INTO recipe_ingredient (recipe_id, ingredient_id, quantity, unit, note)
-			VALUES (?, ?, ?, ?, ?);
+			INSERT INTO recipe_ingredient (id, recipe_id, ingredient_id, sub_recipe_id, position, quantity, unit, note, section_id)
+			VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''));
 		`
 
-		_, err = tx.Exec(query, recipe.ID, i.ID, i.Quantity, i.Unit, i.Note)
+		_, err = tx.Exec(query, i.LineID, recipe.ID, i.ID, i.RecipeID, pos, i.Quantity, i.Unit, i.Note, i.SectionID)
 		if err != nil {
 			return nil, err
 		}
@@ -94,11 +209,11 @@ func (s *SQLiteRecipeStore) CreateRecipe(recipe *model.Recipe) (*model.Recipe, e
 
 	for _, i := range recipe.Instructions {
 		query := `
-			INSERT INTO instructions (id, recipe_id, step_number, description)
-			VALUES (?, ?, ?, ?);
+			INSERT INTO instructions (id, recipe_id, step_number, description, section_id)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''));
 		`
 
-		_, err = tx.Exec(query, i.ID, recipe.ID, i.StepNumber, i.Description)
+		_, err = tx.Exec(query, i.ID, recipe.ID, i.StepNumber, i.Description, i.SectionID)
 		if err != nil {
 			return nil, err
 		}
@@ -121,18 +236,22 @@ func (s *SQLiteRecipeStore) CreateRecipe(recipe *model.Recipe) (*model.Recipe, e
 		return nil, err
 	}
 
+	recipe.Version = 1
+	recipe.GroupSections()
 	return recipe, nil
 }
 
 func (s *SQLiteRecipeStore) GetRecipeByID(id string) (*model.Recipe, error) {
 	r := &model.Recipe{}
 	query := `
-		SELECT id, slug, name, servings, prep_time_seconds, cook_time_seconds, created_at, updated_at 
-		FROM recipes
-		WHERE id = ?;
+		SELECT r.id, r.slug, r.version, r.parent_id, r.name, r.servings, r.prep_time_seconds, r.cook_time_seconds, r.created_at, r.updated_at,
+			(SELECT MAX(c.cooked_on) FROM cook_logs c WHERE c.recipe_id = r.id),
+			(SELECT COUNT(*) FROM cook_logs c WHERE c.recipe_id = r.id)
+		FROM recipes r
+		WHERE r.id = ? AND r.deleted_at IS NULL;
 	`
 
-	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.Slug, &r.Name, &r.Servings, &r.PrepTimeSeconds, &r.CookTimeSeconds, &r.CreatedAt, &r.UpdatedAt)
+	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.Slug, &r.Version, &r.ParentID, &r.Name, &r.Servings, &r.PrepTimeSeconds, &r.CookTimeSeconds, &r.CreatedAt, &r.UpdatedAt, &r.LastCookedAt, &r.TimesCooked)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -140,33 +259,54 @@ func (s *SQLiteRecipeStore) GetRecipeByID(id string) (*model.Recipe, error) {
 		return nil, err
 	}
 
-	if r.Ingredients, err = s.getIngredientsForRecipe(r.ID); err != nil {
+	if r.Ingredients, err = getIngredientsForRecipe(s.db, r.ID); err != nil {
+		return nil, err
+	}
+	if r.Instructions, err = getInstructionsForRecipe(s.db, r.ID); err != nil {
+		return nil, err
+	}
+	if r.Sections, err = getSectionsForRecipe(s.db, r.ID); err != nil {
+		return nil, err
+	}
+	r.GroupSections()
+	if r.Tags, err = getTagsForRecipe(s.db, r.ID); err != nil {
+		return nil, err
+	}
+	if err = s.getFlagsForRecipe(r); err != nil {
 		return nil, err
 	}
-	if r.Instructions, err = s.getInstructionsForRecipe(r.ID); err != nil {
+	if r.UsedIn, err = s.getUsedInForRecipe(r.ID); err != nil {
 		return nil, err
 	}
-	if r.Tags, err = s.getTagsForRecipe(r.ID); err != nil {
+	if r.Variations, err = s.getVariationsForRecipe(r.ID); err != nil {
 		return nil, err
 	}
 
 	return r, nil
 }
 
-func (s *SQLiteRecipeStore) UpdateRecipe(recipe *model.Recipe) (*model.Recipe, error) {
+// UpdateRecipe stores a revision of the updated recipe credited to
+// authorID, which may be empty. A recipe without revisions, such as one
+// never updated before, first gets a revision of its current state. The
+// update only applies while the stored recipe is still at recipe.Version
+// and returns ErrVersionConflict otherwise. On success recipe.Version is
+// the new version.
+func (s *SQLiteRecipeStore) UpdateRecipe(recipe *model.Recipe, authorID string) (*model.Recipe, error) {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
+	// Claiming the version first takes the write lock, so no other update
+	// can change the recipe between reading the baseline and replacing it.
 	query := `
 		UPDATE recipes
-		SET name = ?, servings = ?, prep_time_seconds = ?, cook_time_seconds = ?
-		WHERE id = ?;
+		SET version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL;
 	`
 
-	result, err := tx.Exec(query, recipe.Name, recipe.Servings, recipe.PrepTimeSeconds, recipe.CookTimeSeconds, recipe.ID)
+	result, err := tx.Exec(query, recipe.ID, recipe.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -176,41 +316,54 @@ func (s *SQLiteRecipeStore) UpdateRecipe(recipe *model.Recipe) (*model.Recipe, e
 		return nil, err
 	}
 	if rowsAffected == 0 {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM recipes WHERE id = ? AND deleted_at IS NULL)`
+		if err := tx.QueryRow(query, recipe.ID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrVersionConflict
+		}
 		return nil, sql.ErrNoRows
 	}
 
-	_, err = tx.Exec(`DELETE FROM recipe_ingredient WHERE recipe_id = ?`, recipe.ID)
+	if err := checkReferences(tx, recipe); err != nil {
+		return nil, err
+	}
+
+	baseline, err := revisionBaseline(tx, recipe.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, i := range recipe.Ingredients {
-		query := `
-			INSERT INTO recipe_ingredient (recipe_id, ingredient_id, quantity, unit, note)
-			VALUES (?, ?, ?, ?, ?);
-		`
+	query = `
+		UPDATE recipes
+		SET name = ?, servings = ?, prep_time_seconds = ?, cook_time_seconds = ?,
+			updated_at = CURRENT_TIMESTAMP, change_seq = ` + nextChangeSeq + `
+		WHERE id = ?
+		RETURNING updated_at;
+	`
 
-		_, err = tx.Exec(query, recipe.ID, i.ID, i.Quantity, i.Unit, i.Note)
-		if err != nil {
-			return nil, err
-		}
+	err = tx.QueryRow(query, recipe.Name, recipe.Servings, recipe.PrepTimeSeconds, recipe.CookTimeSeconds, recipe.ID).Scan(&recipe.UpdatedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = tx.Exec(`DELETE FROM instructions WHERE recipe_id = ?`, recipe.ID)
+	_, err = tx.Exec(`DELETE FROM recipe_sections WHERE recipe_id = ?`, recipe.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, i := range recipe.Instructions {
-		query := `
-			INSERT INTO instructions (id, recipe_id, step_number, description)
-			VALUES (?, ?, ?, ?);
-		`
+	if err := insertSections(tx, recipe); err != nil {
+		return nil, err
+	}
 
-		_, err = tx.Exec(query, i.ID, recipe.ID, i.StepNumber, i.Description)
-		if err != nil {
-			return nil, err
-		}
+	if err := updateIngredientLines(tx, recipe); err != nil {
+		return nil, err
+	}
+
+	if err := updateInstructions(tx, recipe); err != nil {
+		return nil, err
 	}
 
 	_, err = tx.Exec(`DELETE FROM recipe_tag WHERE recipe_id = ?`, recipe.ID)
@@ -230,21 +383,63 @@ func (s *SQLiteRecipeStore) UpdateRecipe(recipe *model.Recipe) (*model.Recipe, e
 		}
 	}
 
+	if baseline != nil {
+		if err := insertRevision(tx, baseline, "", &baseline.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := insertRevision(tx, recipe, authorID, nil); err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
 
+	recipe.Version++
+	recipe.GroupSections()
 	return recipe, nil
 }
 
-func (s *SQLiteRecipeStore) DeleteRecipe(id string) error {
+// DeleteRecipe moves the recipe to the trash. It returns ErrRecipeInUse
+// while other recipes use the recipe as a sub-recipe and
+// ErrRecipeHasVariations while it has variations, so their lineage is not
+// lost, unless detachVariations makes the variations standalone recipes
+// first. They are only detached if the recipe is deleted. Recipes already
+// in the trash do not count. A version other than 0 must match the stored
+// one, or ErrVersionConflict is returned.
+func (s *SQLiteRecipeStore) DeleteRecipe(id string, version int, detachVariations bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if detachVariations {
+		query := `
+			UPDATE recipes
+			SET parent_id = NULL, updated_at = CURRENT_TIMESTAMP, version = version + 1, change_seq = ` + nextChangeSeq + `
+			WHERE parent_id = ?;
+		`
+
+		_, err := tx.Exec(query, id)
+		if err != nil {
+			return err
+		}
+	}
+
 	query := `
-		DELETE FROM recipes
-		WHERE id = ?;
+		UPDATE recipes
+		SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, version = version + 1, change_seq = ` + nextChangeSeq + `
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) AND NOT EXISTS (
+			SELECT 1 FROM recipe_ingredient ri
+			JOIN recipes r ON r.id = ri.recipe_id
+			WHERE ri.sub_recipe_id = ? AND r.id <> ? AND r.deleted_at IS NULL
+		) AND NOT EXISTS (SELECT 1 FROM recipes v WHERE v.parent_id = ? AND v.deleted_at IS NULL);
 	`
 
-	result, err := s.db.Exec(query, id)
+	result, err := tx.Exec(query, id, version, version, id, id, id)
 	if err != nil {
 		return err
 	}
@@ -254,21 +449,42 @@ func (s *SQLiteRecipeStore) DeleteRecipe(id string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		var current int
+		var hasVariations bool
+		query := `
+			SELECT COALESCE((SELECT version FROM recipes WHERE id = ? AND deleted_at IS NULL), 0),
+				EXISTS (SELECT 1 FROM recipes WHERE parent_id = ? AND deleted_at IS NULL)
+		`
+		if err := tx.QueryRow(query, id, id).Scan(&current, &hasVariations); err != nil {
+			return err
+		}
+		switch {
+		case current == 0:
+			return sql.ErrNoRows
+		case version != 0 && version != current:
+			return ErrVersionConflict
+		case hasVariations:
+			return ErrRecipeHasVariations
+		default:
+			return ErrRecipeInUse
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (s *SQLiteRecipeStore) getIngredientsForRecipe(recipeID string) ([]model.Ingredient, error) {
+func getIngredientsForRecipe(db queryer, recipeID string) ([]model.Ingredient, error) {
 	query := `
-		SELECT i.id, i.name, ri.quantity, ri.unit, ri.note
+		SELECT ri.id, COALESCE(ri.ingredient_id, ''), COALESCE(ri.sub_recipe_id, ''), COALESCE(i.name, sr.name, ''),
+			ri.quantity, ri.unit, ri.note, COALESCE(ri.section_id, ''), COALESCE(i.density_g_per_ml, 0), COALESCE(i.piece_weight_g, 0)
 		FROM recipe_ingredient ri
-		JOIN ingredients i ON i.id = ri.ingredient_id
-		WHERE ri.recipe_id = ?;
+		LEFT JOIN ingredients i ON i.id = ri.ingredient_id
+		LEFT JOIN recipes sr ON sr.id = ri.sub_recipe_id
+		WHERE ri.recipe_id = ? AND (i.id IS NOT NULL OR sr.id IS NOT NULL)
+		ORDER BY ri.position;
 	`
 
-	rows, err := s.db.Query(query, recipeID)
+	rows, err := db.Query(query, recipeID)
 	if err != nil {
 		return nil, err
 	}
@@ -277,7 +493,7 @@ func (s *SQLiteRecipeStore) getIngredientsForRecipe(recipeID string) ([]model.In
 	ingredients := []model.Ingredient{}
 	for rows.Next() {
 		var i model.Ingredient
-		err = rows.Scan(&i.ID, &i.Name, &i.Quantity, &i.Unit, &i.Note)
+		err = rows.Scan(&i.LineID, &i.ID, &i.RecipeID, &i.Name, &i.Quantity, &i.Unit, &i.Note, &i.SectionID, &i.Density.GramsPerMilliliter, &i.Density.GramsPerPiece)
 		if err != nil {
 			return nil, err
 		}
@@ -286,15 +502,15 @@ func (s *SQLiteRecipeStore) getIngredientsForRecipe(recipeID string) ([]model.In
 	return ingredients, rows.Err()
 }
 
-func (s *SQLiteRecipeStore) getInstructionsForRecipe(recipeID string) ([]model.Instruction, error) {
+func getInstructionsForRecipe(db queryer, recipeID string) ([]model.Instruction, error) {
 	query := `
-		SELECT id, step_number, description
+		SELECT id, step_number, description, COALESCE(section_id, '')
 		FROM instructions
 		WHERE recipe_id = ?
 		ORDER BY step_number ASC;
 	`
 
-	rows, err := s.db.Query(query, recipeID)
+	rows, err := db.Query(query, recipeID)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +519,7 @@ func (s *SQLiteRecipeStore) getInstructionsForRecipe(recipeID string) ([]model.I
 	instructions := []model.Instruction{}
 	for rows.Next() {
 		var i model.Instruction
-		err = rows.Scan(&i.ID, &i.StepNumber, &i.Description)
+		err = rows.Scan(&i.ID, &i.StepNumber, &i.Description, &i.SectionID)
 		if err != nil {
 			return nil, err
 		}
@@ -312,7 +528,51 @@ func (s *SQLiteRecipeStore) getInstructionsForRecipe(recipeID string) ([]model.I
 	return instructions, rows.Err()
 }
 
-func (s *SQLiteRecipeStore) getTagsForRecipe(recipeID string) ([]model.Tag, error) {
+// getSectionsForRecipe returns the recipe's sections in order, without
+// their items.
+func getSectionsForRecipe(db queryer, recipeID string) ([]model.Section, error) {
+	query := `
+		SELECT id, name
+		FROM recipe_sections
+		WHERE recipe_id = ?
+		ORDER BY position;
+	`
+
+	rows, err := db.Query(query, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []model.Section{}
+	for rows.Next() {
+		var section model.Section
+		err = rows.Scan(&section.ID, &section.Name)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, rows.Err()
+}
+
+// insertSections stores the recipe's sections in order. Their items are
+// stored with the flat ingredient and instruction lists.
+func insertSections(tx *sql.Tx, recipe *model.Recipe) error {
+	for pos, section := range recipe.Sections {
+		query := `
+			INSERT INTO recipe_sections (id, recipe_id, name, position)
+			VALUES (?, ?, ?, ?);
+		`
+
+		if _, err := tx.Exec(query, section.ID, recipe.ID, section.Name, pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTagsForRecipe(db queryer, recipeID string) ([]model.Tag, error) {
 	query := `
 		SELECT t.id, t.name
 		FROM recipe_tag rt
@@ -320,7 +580,7 @@ func (s *SQLiteRecipeStore) getTagsForRecipe(recipeID string) ([]model.Tag, erro
 		WHERE rt.recipe_id = ?;
 	`
 
-	rows, err := s.db.Query(query, recipeID)
+	rows, err := db.Query(query, recipeID)
 	if err != nil {
 		return nil, err
 	}
@@ -337,3 +597,296 @@ func (s *SQLiteRecipeStore) getTagsForRecipe(recipeID string) ([]model.Tag, erro
 	}
 	return tags, rows.Err()
 }
+
+// recipeTreeIngredients lists, as the CTE "used", every ingredient the
+// recipe given as its argument uses, directly or through its sub-recipes.
+// It is the single-recipe form of the recipe_ingredient_closure view.
+const recipeTreeIngredients = `
+	WITH RECURSIVE tree(recipe_id) AS (
+		SELECT ?
+		UNION
+		SELECT ri.sub_recipe_id
+		FROM tree t
+		JOIN recipe_ingredient ri ON ri.recipe_id = t.recipe_id
+		WHERE ri.sub_recipe_id IS NOT NULL
+	),
+	used(ingredient_id) AS (
+		SELECT DISTINCT ri.ingredient_id
+		FROM tree t
+		JOIN recipe_ingredient ri ON ri.recipe_id = t.recipe_id
+		WHERE ri.ingredient_id IS NOT NULL
+	)
+`
+
+// getFlagsForRecipe derives the recipe's allergens, the union of its
+// ingredients' allergens, and its diets, those every ingredient is
+// suitable for. Ingredients of sub-recipes count too. They are computed on
+// read so they follow changes to the ingredient catalog.
+func (s *SQLiteRecipeStore) getFlagsForRecipe(r *model.Recipe) error {
+	query := recipeTreeIngredients + `
+		SELECT DISTINCT a.allergen
+		FROM used u
+		JOIN ingredient_allergens a ON a.ingredient_id = u.ingredient_id
+		ORDER BY a.allergen ASC;
+	`
+
+	var err error
+	if r.Allergens, err = queryStrings(s.db, query, r.ID); err != nil {
+		return err
+	}
+
+	query = recipeTreeIngredients + `
+		SELECT d.diet
+		FROM used u
+		JOIN ingredient_diets d ON d.ingredient_id = u.ingredient_id
+		GROUP BY d.diet
+		HAVING COUNT(*) = (SELECT COUNT(*) FROM used)
+		ORDER BY d.diet ASC;
+	`
+
+	r.Diets, err = queryStrings(s.db, query, r.ID)
+	return err
+}
+
+// getVariationsForRecipe lists the recipes forked from the recipe.
+func (s *SQLiteRecipeStore) getVariationsForRecipe(recipeID string) ([]model.RecipeRef, error) {
+	query := `
+		SELECT id, slug, name
+		FROM recipes
+		WHERE parent_id = ? AND deleted_at IS NULL
+		ORDER BY name ASC;
+	`
+
+	rows, err := s.db.Query(query, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := []model.RecipeRef{}
+	for rows.Next() {
+		var ref model.RecipeRef
+		err = rows.Scan(&ref.ID, &ref.Slug, &ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// getUsedInForRecipe lists the recipes that use the recipe as a
+// sub-recipe.
+func (s *SQLiteRecipeStore) getUsedInForRecipe(recipeID string) ([]model.RecipeRef, error) {
+	query := `
+		SELECT DISTINCT r.id, r.slug, r.name
+		FROM recipe_ingredient ri
+		JOIN recipes r ON r.id = ri.recipe_id
+		WHERE ri.sub_recipe_id = ? AND r.deleted_at IS NULL
+		ORDER BY r.name ASC;
+	`
+
+	rows, err := s.db.Query(query, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := []model.RecipeRef{}
+	for rows.Next() {
+		var ref model.RecipeRef
+		err = rows.Scan(&ref.ID, &ref.Slug, &ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// checkReferences returns a ReferenceError listing every ingredient,
+// sub-recipe and tag the recipe uses that does not exist. Foreign keys
+// would reject the write anyway, but only one row at a time and without
+// saying which.
+func checkReferences(tx *sql.Tx, recipe *model.Recipe) error {
+	var ingredientIDs, recipeIDs, tagIDs []string
+	for _, i := range recipe.Ingredients {
+		if i.ID != "" {
+			ingredientIDs = append(ingredientIDs, i.ID)
+		}
+		if i.RecipeID != "" {
+			recipeIDs = append(recipeIDs, i.RecipeID)
+		}
+	}
+	for _, t := range recipe.Tags {
+		tagIDs = append(tagIDs, t.ID)
+	}
+
+	ingredients, err := existingIDs(tx, `SELECT id FROM ingredients WHERE id IN (%s)`, ingredientIDs)
+	if err != nil {
+		return err
+	}
+	recipes, err := existingIDs(tx, `SELECT id FROM recipes WHERE deleted_at IS NULL AND id IN (%s)`, recipeIDs)
+	if err != nil {
+		return err
+	}
+	tags, err := existingIDs(tx, `SELECT id FROM tags WHERE id IN (%s)`, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	var missing []model.MissingReference
+	for n, i := range recipe.Ingredients {
+		if i.ID != "" && !ingredients[i.ID] {
+			missing = append(missing, model.MissingReference{Field: fmt.Sprintf("/ingredients/%d/id", n), ID: i.ID})
+		}
+		if i.RecipeID != "" && !recipes[i.RecipeID] {
+			missing = append(missing, model.MissingReference{Field: fmt.Sprintf("/ingredients/%d/recipeId", n), ID: i.RecipeID})
+		}
+	}
+	for n, t := range recipe.Tags {
+		if !tags[t.ID] {
+			missing = append(missing, model.MissingReference{Field: fmt.Sprintf("/tags/%d/id", n), ID: t.ID})
+		}
+	}
+
+	if len(missing) > 0 {
+		return &ReferenceError{Missing: missing}
+	}
+	return nil
+}
+
+// existingIDs runs query, which selects ids from a list filled in for %s,
+// and returns the ids it found.
+func existingIDs(db queryer, query string, ids []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	args := make([]any, len(ids))
+	for n, id := range ids {
+		args[n] = id
+	}
+	values, err := queryStrings(db, fmt.Sprintf(query, placeholders(len(ids))), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		found[v] = true
+	}
+	return found, nil
+}
+
+// updateIngredientLines brings the recipe's stored lines in line with
+// recipe.Ingredients by id: lines it still has are updated in place, new
+// ones are inserted and the rest are deleted, so line ids stay stable.
+func updateIngredientLines(tx *sql.Tx, recipe *model.Recipe) error {
+	existing, err := queryStrings(tx, `SELECT id FROM recipe_ingredient WHERE recipe_id = ?`, recipe.ID)
+	if err != nil {
+		return err
+	}
+	stale := map[string]bool{}
+	for _, id := range existing {
+		stale[id] = true
+	}
+
+	for pos, i := range recipe.Ingredients {
+		if stale[i.LineID] {
+			delete(stale, i.LineID)
+			query := `
+				UPDATE recipe_ingredient
+				SET ingredient_id = NULLIF(?, ''), sub_recipe_id = NULLIF(?, ''), position = ?, quantity = ?, unit = ?, note = ?, section_id = NULLIF(?, '')
+				WHERE id = ?;
+			`
+			_, err = tx.Exec(query, i.ID, i.RecipeID, pos, i.Quantity, i.Unit, i.Note, i.SectionID, i.LineID)
+		} else {
+			query := `
+				INSERT INTO recipe_ingredient (id, recipe_id, ingredient_id, sub_recipe_id, position, quantity, unit, note, section_id)
+				VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''));
+			`
+			_, err = tx.Exec(query, i.LineID, recipe.ID, i.ID, i.RecipeID, pos, i.Quantity, i.Unit, i.Note, i.SectionID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range stale {
+		if _, err := tx.Exec(`DELETE FROM recipe_ingredient WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateInstructions does for steps what updateIngredientLines does for
+// ingredient lines.
+func updateInstructions(tx *sql.Tx, recipe *model.Recipe) error {
+	existing, err := queryStrings(tx, `SELECT id FROM instructions WHERE recipe_id = ?`, recipe.ID)
+	if err != nil {
+		return err
+	}
+	stale := map[string]bool{}
+	for _, id := range existing {
+		stale[id] = true
+	}
+
+	for _, i := range recipe.Instructions {
+		if stale[i.ID] {
+			delete(stale, i.ID)
+			query := `
+				UPDATE instructions
+				SET step_number = ?, description = ?, section_id = NULLIF(?, '')
+				WHERE id = ?;
+			`
+			_, err = tx.Exec(query, i.StepNumber, i.Description, i.SectionID, i.ID)
+		} else {
+			query := `
+				INSERT INTO instructions (id, recipe_id, step_number, description, section_id)
+				VALUES (?, ?, ?, ?, NULLIF(?, ''));
+			`
+			_, err = tx.Exec(query, i.ID, recipe.ID, i.StepNumber, i.Description, i.SectionID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range stale {
+		if _, err := tx.Exec(`DELETE FROM instructions WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryStrings(db queryer, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,685 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipeIngredientLines_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "sugar", Name: "Sugar"},
+		{ID: "butter", Name: "Butter"},
+		{ID: "flour", Name: "Flour"},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	// Butter is used in the dough and again in the topping.
+	_, err := recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "crumble", Name: "Crumble",
+		Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "sugar", Quantity: 100, Unit: "g"},
+			{LineID: "l2", ID: "butter", Quantity: 50, Unit: "g", Note: "for the dough"},
+			{LineID: "l3", ID: "flour", Quantity: 200, Unit: "g"},
+			{LineID: "l4", ID: "butter", Quantity: 25, Unit: "g", Note: "for the topping"},
+		},
+	})
+	require.NoError(t, err)
+
+	lineIDs := func(r *model.Recipe) []string {
+		ids := []string{}
+		for _, i := range r.Ingredients {
+			ids = append(ids, i.LineID)
+		}
+		return ids
+	}
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"l1", "l2", "l3", "l4"}, lineIDs(recipe))
+	assert.Equal(t, "for the topping", recipe.Ingredients[3].Note)
+
+	recipe.Ingredients = []model.Ingredient{recipe.Ingredients[3], recipe.Ingredients[0], recipe.Ingredients[1]}
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	require.NoError(t, err)
+
+	recipe, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"l4", "l1", "l2"}, lineIDs(recipe))
+
+	recipes, err := recipeStore.ListRecipes(store.RecipeFilter{ExcludeIngredientIDs: []string{"butter"}})
+	require.NoError(t, err)
+	assert.Empty(t, recipes)
+}
+
+func TestRecipeItemIDs_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "flour", Name: "Flour"},
+		{ID: "milk", Name: "Milk"},
+		{ID: "egg", Name: "Egg"},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	_, err := recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "pancakes", Name: "Pancakes",
+		Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "flour", Quantity: 200, Unit: "g"},
+			{LineID: "l2", ID: "milk", Quantity: 300, Unit: "ml"},
+		},
+		Instructions: []model.Instruction{
+			{ID: "s1", StepNumber: 1, Description: "Whisk."},
+			{ID: "s2", StepNumber: 2, Description: "Rest."},
+			{ID: "s3", StepNumber: 3, Description: "Fry."},
+		},
+	})
+	require.NoError(t, err)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+
+	// Lines and steps that survive an update keep their ids and rows.
+	recipe.Ingredients = []model.Ingredient{
+		{LineID: "l5", ID: "egg", Quantity: 2},
+		{LineID: "l1", ID: "flour", Quantity: 250, Unit: "g"},
+	}
+	recipe.Instructions = []model.Instruction{
+		{ID: "s1", StepNumber: 1, Description: "Whisk well."},
+		{ID: "s3", StepNumber: 2, Description: "Fry."},
+		{ID: "s4", StepNumber: 3, Description: "Serve."},
+	}
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	require.NoError(t, err)
+
+	recipe, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, recipe.Ingredients, 2)
+	assert.Equal(t, "l5", recipe.Ingredients[0].LineID)
+	assert.Equal(t, "l1", recipe.Ingredients[1].LineID)
+	assert.Equal(t, 250.0, recipe.Ingredients[1].Quantity)
+	assert.Equal(t, []model.Instruction{
+		{ID: "s1", StepNumber: 1, Description: "Whisk well."},
+		{ID: "s3", StepNumber: 2, Description: "Fry."},
+		{ID: "s4", StepNumber: 3, Description: "Serve."},
+	}, recipe.Instructions)
+
+	var lines, steps int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM recipe_ingredient WHERE recipe_id = 'r1'`).Scan(&lines))
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM instructions WHERE recipe_id = 'r1'`).Scan(&steps))
+	assert.Equal(t, 2, lines)
+	assert.Equal(t, 3, steps)
+}
+
+func TestRecipeSections_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, i := range []model.CatalogIngredient{{ID: "flour", Name: "Flour"}, {ID: "butter", Name: "Butter"}, {ID: "sugar", Name: "Sugar"}} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	created, err := recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "layer-cake", Name: "Layer Cake",
+		Sections: []model.Section{{ID: "sponge", Name: "For the sponge"}, {ID: "frosting", Name: "For the frosting"}},
+		Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "flour", Quantity: 200, Unit: "g", SectionID: "sponge"},
+			{LineID: "l2", ID: "butter", Quantity: 100, Unit: "g", SectionID: "sponge"},
+			{LineID: "l3", ID: "butter", Quantity: 50, Unit: "g", SectionID: "frosting"},
+			{LineID: "l4", ID: "sugar", Quantity: 150, Unit: "g", SectionID: "frosting"},
+		},
+		Instructions: []model.Instruction{
+			{ID: "s1", StepNumber: 1, Description: "Bake the sponge.", SectionID: "sponge"},
+			{ID: "s2", StepNumber: 2, Description: "Beat the frosting.", SectionID: "frosting"},
+			{ID: "s3", StepNumber: 3, Description: "Assemble."},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, created.Sections, 2)
+	assert.Len(t, created.Sections[0].Ingredients, 2)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, recipe.Sections, 2)
+	assert.Equal(t, "For the sponge", recipe.Sections[0].Name)
+	assert.Equal(t, []model.Ingredient{recipe.Ingredients[2], recipe.Ingredients[3]}, recipe.Sections[1].Ingredients)
+	assert.Equal(t, "s2", recipe.Sections[1].Instructions[0].ID)
+	assert.Len(t, recipe.Ingredients, 4)
+	assert.Len(t, recipe.Instructions, 3)
+	assert.Empty(t, recipe.Instructions[2].SectionID)
+
+	// Updates replace the sections along with their items.
+	recipe.Sections = recipe.Sections[1:]
+	recipe.Ingredients = recipe.Ingredients[2:]
+	recipe.Instructions = recipe.Instructions[1:]
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	require.NoError(t, err)
+
+	recipe, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	require.Len(t, recipe.Sections, 1)
+	assert.Equal(t, "frosting", recipe.Sections[0].ID)
+	assert.Len(t, recipe.Sections[0].Ingredients, 2)
+	assert.Len(t, recipe.Ingredients, 2)
+}
+
+func TestSubRecipes_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "butter", Name: "Butter", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+		{ID: "flour", Name: "Flour", Allergens: []string{"gluten"}, Diets: []string{"vegan", "vegetarian"}},
+		{ID: "pasta", Name: "Pasta", Diets: []string{"vegan", "vegetarian"}},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	for _, r := range []model.Recipe{
+		{ID: "roux", Slug: "roux", Name: "Roux", Servings: 4, Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "butter", Quantity: 50, Unit: "g"},
+			{LineID: "l2", ID: "flour", Quantity: 50, Unit: "g"},
+		}},
+		{ID: "bechamel", Slug: "bechamel", Name: "Bechamel", Servings: 4, Ingredients: []model.Ingredient{
+			{LineID: "l3", RecipeID: "roux", Quantity: 4, Unit: "servings"},
+		}},
+		{ID: "lasagne", Slug: "lasagne", Name: "Lasagne", Servings: 6, Ingredients: []model.Ingredient{
+			{LineID: "l4", ID: "pasta", Quantity: 500, Unit: "g"},
+			{LineID: "l5", RecipeID: "bechamel", Quantity: 2},
+		}},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+
+	lasagne, err := recipeStore.GetRecipeByID("lasagne")
+	require.NoError(t, err)
+	require.Len(t, lasagne.Ingredients, 2)
+	assert.Equal(t, "bechamel", lasagne.Ingredients[1].RecipeID)
+	assert.Equal(t, "Bechamel", lasagne.Ingredients[1].Name)
+	assert.Empty(t, lasagne.Ingredients[1].ID)
+	assert.Equal(t, []string{"dairy", "gluten"}, lasagne.Allergens)
+	assert.Equal(t, []string{"vegetarian"}, lasagne.Diets)
+	assert.Empty(t, lasagne.UsedIn)
+
+	roux, err := recipeStore.GetRecipeByID("roux")
+	require.NoError(t, err)
+	assert.Equal(t, []model.RecipeRef{{ID: "bechamel", Slug: "bechamel", Name: "Bechamel"}}, roux.UsedIn)
+
+	recipes, err := recipeStore.ListRecipes(store.RecipeFilter{ExcludeAllergens: []string{"dairy"}})
+	require.NoError(t, err)
+	assert.Empty(t, recipes)
+	recipes, err = recipeStore.ListRecipes(store.RecipeFilter{Diets: []string{"vegetarian"}})
+	require.NoError(t, err)
+	assert.Len(t, recipes, 3)
+
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("roux", 0, false), store.ErrRecipeInUse)
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("nope", 0, false), sql.ErrNoRows)
+	require.NoError(t, recipeStore.DeleteRecipe("lasagne", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("bechamel", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("roux", 0, false))
+}
+
+func TestRecipeVariations_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	parentID := "r1"
+	for _, r := range []model.Recipe{
+		{ID: "r1", Slug: "pancakes", Name: "Pancakes"},
+		{ID: "r2", Slug: "oat-pancakes", Name: "Oat Pancakes", ParentID: &parentID},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+
+	parent, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Nil(t, parent.ParentID)
+	assert.Equal(t, []model.RecipeRef{{ID: "r2", Slug: "oat-pancakes", Name: "Oat Pancakes"}}, parent.Variations)
+
+	variation, err := recipeStore.GetRecipeByID("r2")
+	require.NoError(t, err)
+	assert.Equal(t, "r1", *variation.ParentID)
+	assert.Empty(t, variation.Variations)
+
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("r1", 0, false), store.ErrRecipeHasVariations)
+
+	// A failed delete leaves the variations attached.
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("r1", 7, true), store.ErrVersionConflict)
+	variation, err = recipeStore.GetRecipeByID("r2")
+	require.NoError(t, err)
+	assert.Equal(t, "r1", *variation.ParentID)
+
+	require.NoError(t, recipeStore.DeleteRecipe("r1", 0, true))
+
+	variation, err = recipeStore.GetRecipeByID("r2")
+	require.NoError(t, err)
+	assert.Nil(t, variation.ParentID)
+
+	// Purging a parent detaches variations waiting in the trash with it.
+	parentID = "r2"
+	_, err = recipeStore.CreateRecipe(&model.Recipe{ID: "r3", Slug: "oat-banana-pancakes", Name: "Oat Banana Pancakes", ParentID: &parentID})
+	require.NoError(t, err)
+	require.NoError(t, recipeStore.DeleteRecipe("r3", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("r2", 0, false))
+	require.NoError(t, recipeStore.PurgeRecipe("r2"))
+
+	var parent3 sql.NullString
+	var version3 int
+	require.NoError(t, db.QueryRow(`SELECT parent_id, version FROM recipes WHERE id = 'r3'`).Scan(&parent3, &version3))
+	assert.False(t, parent3.Valid)
+	assert.Equal(t, 3, version3)
+}
+
+func TestRecipeRevisions_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour"})
+	require.NoError(t, err)
+	_, err = recipeStore.CreateRecipe(&model.Recipe{
+		ID: "r1", Slug: "pancakes", Name: "Pancakes", Servings: 4,
+		Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 1, Unit: "cup"}},
+	})
+	require.NoError(t, err)
+
+	revisions, err := recipeStore.ListRevisions("r1")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	recipe.Name = "Classic Pancakes"
+	recipe.Ingredients[0].Quantity = 2
+	_, err = recipeStore.UpdateRecipe(recipe, "u1")
+	require.NoError(t, err)
+
+	recipe.Servings = 2
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	require.NoError(t, err)
+
+	// A stale update records nothing.
+	stale := *recipe
+	stale.Version = 1
+	_, err = recipeStore.UpdateRecipe(&stale, "")
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+
+	// The first update also records the state it replaced.
+	revisions, err = recipeStore.ListRevisions("r1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	for n, r := range revisions {
+		assert.Equal(t, n+1, r.Number)
+		assert.Nil(t, r.Recipe)
+	}
+	assert.Nil(t, revisions[0].AuthorID)
+	assert.Equal(t, "u1", *revisions[1].AuthorID)
+	assert.Nil(t, revisions[2].AuthorID)
+
+	original, err := recipeStore.GetRevision("r1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Pancakes", original.Recipe.Name)
+	assert.Equal(t, 4, original.Recipe.Servings)
+	require.Len(t, original.Recipe.Ingredients, 1)
+	assert.Equal(t, "Flour", original.Recipe.Ingredients[0].Name)
+	assert.Equal(t, 1.0, original.Recipe.Ingredients[0].Quantity)
+
+	latest, err := recipeStore.GetRevision("r1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "Classic Pancakes", latest.Recipe.Name)
+	assert.Equal(t, 2, latest.Recipe.Servings)
+
+	missing, err := recipeStore.GetRevision("r1", 4)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// History survives the trash but not a purge.
+	require.NoError(t, recipeStore.DeleteRecipe("r1", 0, false))
+	revisions, err = recipeStore.ListRevisions("r1")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 3)
+
+	require.NoError(t, recipeStore.PurgeRecipe("r1"))
+	revisions, err = recipeStore.ListRevisions("r1")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestRecipeTrash_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+	mealPlanStore := store.NewSQLiteMealPlanStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour"})
+	require.NoError(t, err)
+
+	parentID := "roux"
+	for _, r := range []model.Recipe{
+		{ID: "roux", Slug: "roux", Name: "Roux", Servings: 1, Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 1}}},
+		{ID: "bechamel", Slug: "bechamel", Name: "Bechamel", Servings: 1, Ingredients: []model.Ingredient{{LineID: "l2", RecipeID: "roux", Quantity: 1}}},
+		{ID: "dark-roux", Slug: "dark-roux", Name: "Dark Roux", ParentID: &parentID, Ingredients: []model.Ingredient{{LineID: "l3", ID: "flour", Quantity: 2}}},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+	_, err = mealPlanStore.CreateEntry(&model.MealPlanEntry{ID: "m1", RecipeID: "bechamel", Date: "2025-11-03", Meal: "dinner", Servings: 2})
+	require.NoError(t, err)
+
+	// Recipes in use stay out of the trash until their users are in it.
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("roux", 0, false), store.ErrRecipeHasVariations)
+	require.NoError(t, recipeStore.DeleteRecipe("dark-roux", 0, false))
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("roux", 0, false), store.ErrRecipeInUse)
+	require.NoError(t, recipeStore.DeleteRecipe("bechamel", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("roux", 0, false))
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("roux", 0, false), sql.ErrNoRows)
+
+	recipe, err := recipeStore.GetRecipeByID("roux")
+	require.NoError(t, err)
+	assert.Nil(t, recipe)
+
+	recipes, err := recipeStore.ListRecipes(store.RecipeFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, recipes)
+
+	entries, err := mealPlanStore.ListEntries("", "2025-11-01", "2025-11-30")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	trashed, err := recipeStore.ListDeletedRecipes()
+	require.NoError(t, err)
+	require.Len(t, trashed, 3)
+	for _, r := range trashed {
+		assert.NotNil(t, r.DeletedAt)
+	}
+
+	// Bechamel cannot come back without its roux.
+	assert.ErrorIs(t, recipeStore.RestoreRecipe("bechamel"), store.ErrDeletedSubRecipe)
+	require.NoError(t, recipeStore.RestoreRecipe("roux"))
+	require.NoError(t, recipeStore.RestoreRecipe("bechamel"))
+	assert.ErrorIs(t, recipeStore.RestoreRecipe("bechamel"), sql.ErrNoRows)
+
+	bechamel, err := recipeStore.GetRecipeByID("bechamel")
+	require.NoError(t, err)
+	require.Len(t, bechamel.Ingredients, 1)
+	assert.Nil(t, bechamel.DeletedAt)
+
+	entries, err = mealPlanStore.ListEntries("", "2025-11-01", "2025-11-30")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Only recipes in the trash are purged, and only once old enough.
+	assert.ErrorIs(t, recipeStore.PurgeRecipe("roux"), sql.ErrNoRows)
+
+	purged, err := recipeStore.PurgeDeletedRecipes(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = recipeStore.PurgeDeletedRecipes(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trashed, err = recipeStore.ListDeletedRecipes()
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+
+	// The purged recipe's lines went with it.
+	var lines int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM recipe_ingredient WHERE recipe_id = 'dark-roux'`).Scan(&lines))
+	assert.Zero(t, lines)
+	orphans, err := store.CountOrphans(db)
+	require.NoError(t, err)
+	assert.Zero(t, orphans)
+
+	roux, err := recipeStore.GetRecipeByID("roux")
+	require.NoError(t, err)
+	assert.Empty(t, roux.Variations)
+}
+
+func TestPurgeSubRecipe_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour"})
+	require.NoError(t, err)
+	for _, r := range []model.Recipe{
+		{ID: "roux", Slug: "roux", Name: "Roux", Servings: 1, Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 1}}},
+		{ID: "bechamel", Slug: "bechamel", Name: "Bechamel", Servings: 1, Ingredients: []model.Ingredient{{LineID: "l2", RecipeID: "roux", Quantity: 1}}},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+	require.NoError(t, recipeStore.DeleteRecipe("bechamel", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("roux", 0, false))
+
+	// The roux went to the trash a week before the bechamel using it.
+	_, err = db.Exec(`UPDATE recipes SET deleted_at = datetime('now', '-7 days') WHERE id = 'roux'`)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, recipeStore.PurgeRecipe("roux"), store.ErrRecipeInUse)
+	purged, err := recipeStore.PurgeDeletedRecipes(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	// So the bechamel still comes back whole.
+	require.NoError(t, recipeStore.RestoreRecipe("roux"))
+	require.NoError(t, recipeStore.RestoreRecipe("bechamel"))
+	bechamel, err := recipeStore.GetRecipeByID("bechamel")
+	require.NoError(t, err)
+	assert.Len(t, bechamel.Ingredients, 1)
+
+	// Purged together, neither is left behind.
+	require.NoError(t, recipeStore.DeleteRecipe("bechamel", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("roux", 0, false))
+	purged, err = recipeStore.PurgeDeletedRecipes(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	orphans, err := store.CountOrphans(db)
+	require.NoError(t, err)
+	assert.Zero(t, orphans)
+}
+
+func TestRecipeVersions_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	created, err := recipeStore.CreateRecipe(&model.Recipe{ID: "r1", Slug: "pancakes", Name: "Pancakes"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.Version)
+
+	// Two people edit the same version; the second one loses.
+	first, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	second, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+
+	first.Name = "Fluffy Pancakes"
+	updated, err := recipeStore.UpdateRecipe(first, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	second.Name = "Thin Pancakes"
+	_, err = recipeStore.UpdateRecipe(second, "")
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+
+	recipe, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, "Fluffy Pancakes", recipe.Name)
+	assert.Equal(t, 2, recipe.Version)
+
+	revisions, err := recipeStore.ListRevisions("r1")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	recipe.ID = "nope"
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.ErrorIs(t, recipeStore.DeleteRecipe("r1", 1, false), store.ErrVersionConflict)
+	require.NoError(t, recipeStore.DeleteRecipe("r1", 2, false))
+}
+
+func TestRecipeSync_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	ids := func(recipes []model.Recipe) []string {
+		ids := []string{}
+		for _, r := range recipes {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	for _, r := range []model.Recipe{
+		{ID: "r1", Slug: "pancakes", Name: "Pancakes"},
+		{ID: "r2", Slug: "waffles", Name: "Waffles"},
+		{ID: "r3", Slug: "crepes", Name: "Crepes"},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+
+	full, err := recipeStore.SyncRecipes(0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1", "r2", "r3"}, ids(full.Recipes))
+	assert.Empty(t, full.Deleted)
+
+	nothing, err := recipeStore.SyncRecipes(full.Cursor)
+	require.NoError(t, err)
+	assert.Empty(t, nothing.Recipes)
+	assert.Equal(t, full.Cursor, nothing.Cursor)
+
+	// Make the stored timestamps old enough to see updates move them.
+	_, err = db.Exec(`UPDATE recipes SET created_at = '2025-01-01 00:00:00', updated_at = '2025-01-01 00:00:00'`)
+	require.NoError(t, err)
+
+	pancakes, err := recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	pancakes.Name = "Fluffy Pancakes"
+	updated, err := recipeStore.UpdateRecipe(pancakes, "")
+	require.NoError(t, err)
+	assert.True(t, updated.UpdatedAt.After(updated.CreatedAt))
+
+	pancakes, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	assert.Equal(t, updated.UpdatedAt, pancakes.UpdatedAt)
+	assert.Equal(t, 2025, pancakes.CreatedAt.Year())
+
+	_, err = db.Exec(`UPDATE recipes SET updated_at = '2025-01-01 00:00:00'`)
+	require.NoError(t, err)
+
+	require.NoError(t, recipeStore.DeleteRecipe("r2", 0, false))
+	require.NoError(t, recipeStore.DeleteRecipe("r3", 0, false))
+	require.NoError(t, recipeStore.PurgeRecipe("r3"))
+
+	changes, err := recipeStore.SyncRecipes(full.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1"}, ids(changes.Recipes))
+	require.Len(t, changes.Deleted, 2)
+	assert.Equal(t, "r2", changes.Deleted[0].ID)
+	assert.Equal(t, "r3", changes.Deleted[1].ID)
+	assert.False(t, changes.Deleted[1].DeletedAt.IsZero())
+	assert.Greater(t, changes.Cursor, full.Cursor)
+
+	// A restored recipe comes back as an update.
+	require.NoError(t, recipeStore.RestoreRecipe("r2"))
+	restored, err := recipeStore.SyncRecipes(changes.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r2"}, ids(restored.Recipes))
+	assert.Empty(t, restored.Deleted)
+}
+
+func TestRecipeReferences_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	_, err := ingredientStore.CreateIngredient(&model.CatalogIngredient{ID: "flour", Name: "Flour"})
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO tags (id, name) VALUES ('baking', 'Baking')`)
+	require.NoError(t, err)
+
+	recipe := &model.Recipe{
+		ID: "r1", Slug: "bread", Name: "Bread",
+		Ingredients: []model.Ingredient{
+			{LineID: "l1", ID: "flour", Quantity: 500, Unit: "g"},
+			{LineID: "l2", ID: "yeast", Quantity: 7, Unit: "g"},
+			{LineID: "l3", RecipeID: "starter", Quantity: 1},
+		},
+		Tags: []model.Tag{{ID: "baking"}, {ID: "sourdough"}},
+	}
+	_, err = recipeStore.CreateRecipe(recipe)
+	var refErr *store.ReferenceError
+	require.ErrorAs(t, err, &refErr)
+	assert.Equal(t, []model.MissingReference{
+		{Field: "/ingredients/1/id", ID: "yeast"},
+		{Field: "/ingredients/2/recipeId", ID: "starter"},
+		{Field: "/tags/1/id", ID: "sourdough"},
+	}, refErr.Missing)
+
+	recipe.Ingredients = recipe.Ingredients[:1]
+	recipe.Tags = recipe.Tags[:1]
+	_, err = recipeStore.CreateRecipe(recipe)
+	require.NoError(t, err)
+
+	recipe, err = recipeStore.GetRecipeByID("r1")
+	require.NoError(t, err)
+	recipe.Tags = append(recipe.Tags, model.Tag{ID: "sourdough"})
+	_, err = recipeStore.UpdateRecipe(recipe, "")
+	require.ErrorAs(t, err, &refErr)
+	assert.Equal(t, []model.MissingReference{{Field: "/tags/1/id", ID: "sourdough"}}, refErr.Missing)
+
+	// Purging still works with foreign keys enforced.
+	require.NoError(t, recipeStore.DeleteRecipe("r1", 0, false))
+	require.NoError(t, recipeStore.PurgeRecipe("r1"))
+
+	var tags int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM recipe_tag WHERE recipe_id = 'r1'`).Scan(&tags))
+	assert.Zero(t, tags)
+}
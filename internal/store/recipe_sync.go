@@ -0,0 +1,57 @@
+package store
+
+import "github.com/stevmwhitfield/recipe-api/internal/model"
+
+// nextChangeSeq numbers a new change to recipes after every change so far,
+// including purges. SQLite serializes writes, so changes commit in this
+// order and a reader never sees a later number before an earlier one.
+const nextChangeSeq = `(SELECT COALESCE(MAX(seq), 0) + 1 FROM (
+	SELECT MAX(change_seq) AS seq FROM recipes
+	UNION ALL SELECT MAX(change_seq) FROM recipe_tombstones))`
+
+// SyncRecipes returns the recipes created or updated after the since
+// cursor, and tombstones for those deleted after it. A since of 0 returns
+// every recipe. A recipe changed more than once is only returned as it is
+// now.
+func (s *SQLiteRecipeStore) SyncRecipes(since int64) (*model.RecipeSync, error) {
+	sync := &model.RecipeSync{Recipes: []model.Recipe{}, Deleted: []model.RecipeTombstone{}}
+
+	err := s.db.QueryRow(`SELECT ` + nextChangeSeq + ` - 1`).Scan(&sync.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes after the cursor are left for the next sync, which will see
+	// them in full.
+	recipes, err := s.queryRecipes("r.deleted_at IS NULL AND r.change_seq > ? AND r.change_seq <= ? ORDER BY r.change_seq ASC", since, sync.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	sync.Recipes = append(sync.Recipes, recipes...)
+
+	query := `
+		SELECT id, deleted_at, change_seq FROM recipes
+		WHERE deleted_at IS NOT NULL AND change_seq > ? AND change_seq <= ?
+		UNION ALL
+		SELECT recipe_id, deleted_at, change_seq FROM recipe_tombstones
+		WHERE change_seq > ? AND change_seq <= ?
+		ORDER BY change_seq ASC;
+	`
+
+	rows, err := s.db.Query(query, since, sync.Cursor, since, sync.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.RecipeTombstone
+		var seq int64
+		if err := rows.Scan(&t.ID, &t.DeletedAt, &seq); err != nil {
+			return nil, err
+		}
+		sync.Deleted = append(sync.Deleted, t)
+	}
+
+	return sync, rows.Err()
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+// ListDeletedRecipes returns the recipes in the trash, most recently
+// deleted first.
+func (s *SQLiteRecipeStore) ListDeletedRecipes() ([]model.Recipe, error) {
+	return s.queryRecipes("r.deleted_at IS NOT NULL ORDER BY r.deleted_at DESC, r.name ASC")
+}
+
+// RestoreRecipe takes the recipe out of the trash. It returns
+// ErrDeletedSubRecipe while a sub-recipe it uses is still in the trash, as
+// the recipe could not be expanded.
+func (s *SQLiteRecipeStore) RestoreRecipe(id string) error {
+	query := `
+		UPDATE recipes
+		SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, version = version + 1, change_seq = ` + nextChangeSeq + `
+		WHERE id = ? AND deleted_at IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM recipe_ingredient ri
+			JOIN recipes sr ON sr.id = ri.sub_recipe_id
+			WHERE ri.recipe_id = ? AND sr.deleted_at IS NOT NULL
+		);
+	`
+
+	result, err := s.db.Exec(query, id, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		var deleted bool
+		query := `SELECT EXISTS (SELECT 1 FROM recipes WHERE id = ? AND deleted_at IS NOT NULL)`
+		if err := s.db.QueryRow(query, id).Scan(&deleted); err != nil {
+			return err
+		}
+		if !deleted {
+			return sql.ErrNoRows
+		}
+		return ErrDeletedSubRecipe
+	}
+
+	return nil
+}
+
+// PurgeRecipe permanently deletes a recipe in the trash. It returns
+// sql.ErrNoRows when the recipe is not in the trash and ErrRecipeInUse
+// while other recipes in the trash use it as a sub-recipe, as they could
+// not be restored whole.
+func (s *SQLiteRecipeStore) PurgeRecipe(id string) error {
+	purged, inUse, err := s.purge("id = ?", id)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return ErrRecipeInUse
+	}
+	if purged == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeDeletedRecipes permanently deletes the recipes moved to the trash
+// before the given time and returns how many there were. Sub-recipes still
+// used by recipes that stay in the trash are kept until those go too.
+func (s *SQLiteRecipeStore) PurgeDeletedRecipes(before time.Time) (int64, error) {
+	purged, _, err := s.purge("deleted_at <= ?", before.UTC().Format(time.DateTime))
+	return purged, err
+}
+
+// purge deletes the trashed recipes matching the condition, leaving a
+// tombstone for sync, and returns how many it deleted and how many it kept
+// because recipes staying in the trash use them as sub-recipes. Lines
+// using a purged recipe as a sub-recipe are then all in purged recipes,
+// but would block the delete and are dropped first. Foreign keys cascade
+// the delete to everything else that belongs to a recipe and make its
+// variations standalone, which get a new version first.
+func (s *SQLiteRecipeStore) purge(condition string, args ...any) (int64, int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	ids, err := queryStrings(tx, `SELECT id FROM recipes WHERE deleted_at IS NOT NULL AND `+condition, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	purgeable, err := withoutUsedSubRecipes(tx, ids)
+	if err != nil {
+		return 0, 0, err
+	}
+	inUse := int64(len(ids) - len(purgeable))
+	ids = purgeable
+
+	for _, id := range ids {
+		_, err = tx.Exec(`DELETE FROM recipe_ingredient WHERE sub_recipe_id = ?`, id)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		query := `
+			UPDATE recipes
+			SET updated_at = CURRENT_TIMESTAMP, version = version + 1, change_seq = ` + nextChangeSeq + `
+			WHERE parent_id = ?;
+		`
+		_, err = tx.Exec(query, id)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// Clients syncing after the purge still need to hear the recipe is gone.
+		query = `
+			INSERT OR REPLACE INTO recipe_tombstones (recipe_id, change_seq, deleted_at)
+			SELECT id, ` + nextChangeSeq + `, deleted_at FROM recipes WHERE id = ?;
+		`
+		_, err = tx.Exec(query, id)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		_, err = tx.Exec(`DELETE FROM recipes WHERE id = ?`, id)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return int64(len(ids)), inUse, nil
+}
+
+// withoutUsedSubRecipes leaves out every recipe that a recipe outside the
+// result uses as a sub-recipe.
+func withoutUsedSubRecipes(db queryer, ids []string) ([]string, error) {
+	for {
+		kept := []string{}
+		for _, id := range ids {
+			users, err := queryStrings(db, `SELECT recipe_id FROM recipe_ingredient WHERE sub_recipe_id = ?`, id)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.ContainsFunc(users, func(u string) bool { return !slices.Contains(ids, u) }) {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(ids) {
+			return kept, nil
+		}
+		ids = kept
+	}
+}
@@ -0,0 +1,342 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stevmwhitfield/recipe-api/internal/util"
+)
+
+const (
+	ShoppingListItemSourceManual   = "manual"
+	ShoppingListItemSourceLowStock = "low stock"
+)
+
+type SQLiteShoppingListStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteShoppingListStore(db *sql.DB) *SQLiteShoppingListStore {
+	return &SQLiteShoppingListStore{db: db}
+}
+
+type ShoppingListStore interface {
+	GetActiveShoppingList() (*model.ShoppingList, error)
+	CompleteActiveShoppingList() (*model.ShoppingList, error)
+	GetShoppingListItemByID(id string) (*model.ShoppingListEntry, error)
+	AddShoppingListItem(*model.ShoppingListEntry) (*model.ShoppingListEntry, error)
+	UpdateShoppingListItem(*model.ShoppingListEntry) (*model.ShoppingListEntry, error)
+	DeleteShoppingListItem(id string) error
+}
+
+// GetActiveShoppingList returns the household's active list, starting a
+// new one when the previous list was completed.
+func (s *SQLiteShoppingListStore) GetActiveShoppingList() (*model.ShoppingList, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	listID, err := ensureActiveShoppingList(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := getShoppingList(tx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *SQLiteShoppingListStore) CompleteActiveShoppingList() (*model.ShoppingList, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var listID string
+	err = tx.QueryRow(`SELECT id FROM shopping_lists WHERE status = 'active'`).Scan(&listID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE shopping_lists
+		SET status = 'completed', completed_at = CURRENT_TIMESTAMP
+		WHERE id = ?;
+	`
+
+	_, err = tx.Exec(query, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := getShoppingList(tx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *SQLiteShoppingListStore) GetShoppingListItemByID(id string) (*model.ShoppingListEntry, error) {
+	e := &model.ShoppingListEntry{}
+	query := `
+		SELECT li.id, li.ingredient_id, i.name, li.quantity, li.unit, COALESCE(li.note, ''), li.source, li.checked, li.created_at
+		FROM shopping_list_items li
+		JOIN shopping_lists l ON l.id = li.shopping_list_id
+		JOIN ingredients i ON i.id = li.ingredient_id
+		WHERE li.id = ? AND l.status = 'active';
+	`
+
+	err := s.db.QueryRow(query, id).Scan(&e.ID, &e.IngredientID, &e.Name, &e.Quantity, &e.Unit, &e.Note, &e.Source, &e.Checked, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// AddShoppingListItem adds an item to the active list and returns
+// ErrUnknownIngredient when the ingredient is not in the catalog.
+func (s *SQLiteShoppingListStore) AddShoppingListItem(e *model.ShoppingListEntry) (*model.ShoppingListEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	listID, err := ensureActiveShoppingList(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO shopping_list_items (id, shopping_list_id, ingredient_id, quantity, unit, note, source)
+		SELECT ?, ?, id, ?, ?, ?, ?
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	result, err := tx.Exec(query, e.ID, listID, e.Quantity, e.Unit, e.Note, e.Source, e.IngredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUnknownIngredient
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetShoppingListItemByID(e.ID)
+}
+
+func (s *SQLiteShoppingListStore) UpdateShoppingListItem(e *model.ShoppingListEntry) (*model.ShoppingListEntry, error) {
+	query := `
+		UPDATE shopping_list_items
+		SET quantity = ?, unit = ?, note = ?, checked = ?
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, e.Quantity, e.Unit, e.Note, e.Checked, e.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s.GetShoppingListItemByID(e.ID)
+}
+
+func (s *SQLiteShoppingListStore) DeleteShoppingListItem(id string) error {
+	query := `
+		DELETE FROM shopping_list_items
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func ensureActiveShoppingList(tx *sql.Tx) (string, error) {
+	var listID string
+	err := tx.QueryRow(`SELECT id FROM shopping_lists WHERE status = 'active'`).Scan(&listID)
+	if err == nil {
+		return listID, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	listID, err = util.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`INSERT INTO shopping_lists (id, status) VALUES (?, 'active')`, listID)
+	if err != nil {
+		return "", err
+	}
+
+	return listID, nil
+}
+
+func getShoppingList(tx *sql.Tx, listID string) (*model.ShoppingList, error) {
+	list := &model.ShoppingList{}
+	query := `
+		SELECT id, status, created_at, completed_at
+		FROM shopping_lists
+		WHERE id = ?;
+	`
+
+	err := tx.QueryRow(query, listID).Scan(&list.ID, &list.Status, &list.CreatedAt, &list.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT li.id, li.ingredient_id, i.name, li.quantity, li.unit, COALESCE(li.note, ''), li.source, li.checked, li.created_at
+		FROM shopping_list_items li
+		JOIN ingredients i ON i.id = li.ingredient_id
+		WHERE li.shopping_list_id = ?
+		ORDER BY li.checked ASC, i.name ASC;
+	`
+
+	rows, err := tx.Query(query, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list.Items = []model.ShoppingListEntry{}
+	for rows.Next() {
+		var e model.ShoppingListEntry
+		err = rows.Scan(&e.ID, &e.IngredientID, &e.Name, &e.Quantity, &e.Unit, &e.Note, &e.Source, &e.Checked, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, e)
+	}
+
+	return list, rows.Err()
+}
+
+// restockIfLow puts the ingredient on the active shopping list when its
+// stock has dropped below its threshold. It is called from every store
+// method that reduces stock, inside that method's transaction, and does
+// nothing if the ingredient is already on the list and not yet checked.
+func restockIfLow(tx *sql.Tx, ingredientID string) error {
+	threshold := model.StockThreshold{IngredientID: ingredientID}
+	err := tx.QueryRow(`SELECT min_quantity, unit FROM stock_thresholds WHERE ingredient_id = ?`, ingredientID).
+		Scan(&threshold.MinQuantity, &threshold.Unit)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT s.quantity, s.unit, COALESCE(i.density_g_per_ml, 0), COALESCE(i.piece_weight_g, 0)
+		FROM stocked_ingredients s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		WHERE s.ingredient_id = ?;
+	`
+
+	rows, err := tx.Query(query, ingredientID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	stock := []model.StockedIngredient{}
+	for rows.Next() {
+		si := model.StockedIngredient{IngredientID: ingredientID}
+		if err := rows.Scan(&si.Quantity, &si.Unit, &si.Density.GramsPerMilliliter, &si.Density.GramsPerPiece); err != nil {
+			return err
+		}
+		stock = append(stock, si)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, shortfall := pantry.Shortfall(threshold, stock)
+	if shortfall == 0 {
+		return nil
+	}
+
+	query = `
+		SELECT COUNT(*)
+		FROM shopping_list_items li
+		JOIN shopping_lists l ON l.id = li.shopping_list_id
+		WHERE l.status = 'active' AND li.ingredient_id = ? AND li.checked = 0;
+	`
+
+	var onList int
+	if err := tx.QueryRow(query, ingredientID).Scan(&onList); err != nil {
+		return err
+	}
+	if onList > 0 {
+		return nil
+	}
+
+	listID, err := ensureActiveShoppingList(tx)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := util.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO shopping_list_items (id, shopping_list_id, ingredient_id, quantity, unit, source)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`
+
+	_, err = tx.Exec(query, itemID, listID, ingredientID, shortfall, threshold.Unit, ShoppingListItemSourceLowStock)
+	return err
+}
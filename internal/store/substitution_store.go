@@ -0,0 +1,193 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLiteSubstitutionStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteSubstitutionStore(db *sql.DB) *SQLiteSubstitutionStore {
+	return &SQLiteSubstitutionStore{db: db}
+}
+
+type SubstitutionStore interface {
+	ListSubstitutions(ingredientID string) ([]model.Substitution, error)
+	GetSubstitutionByID(id string) (*model.Substitution, error)
+	CreateSubstitution(*model.Substitution) (*model.Substitution, error)
+	DeleteSubstitution(id string) error
+}
+
+// ListSubstitutions lists the substitutions for an ingredient, or for every
+// ingredient when ingredientID is empty, oldest first.
+func (s *SQLiteSubstitutionStore) ListSubstitutions(ingredientID string) ([]model.Substitution, error) {
+	query := `
+		SELECT s.id, s.ingredient_id, i.name, COALESCE(s.notes, ''), s.created_at
+		FROM substitutions s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		WHERE ? = '' OR s.ingredient_id = ?
+		ORDER BY s.created_at ASC, s.id ASC;
+	`
+
+	rows, err := s.db.Query(query, ingredientID, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	substitutions := []model.Substitution{}
+	for rows.Next() {
+		var sub model.Substitution
+		err = rows.Scan(&sub.ID, &sub.IngredientID, &sub.Name, &sub.Notes, &sub.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		substitutions = append(substitutions, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range substitutions {
+		if err := s.getComponentsForSubstitution(&substitutions[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return substitutions, nil
+}
+
+func (s *SQLiteSubstitutionStore) GetSubstitutionByID(id string) (*model.Substitution, error) {
+	sub := &model.Substitution{}
+	query := `
+		SELECT s.id, s.ingredient_id, i.name, COALESCE(s.notes, ''), s.created_at
+		FROM substitutions s
+		JOIN ingredients i ON i.id = s.ingredient_id
+		WHERE s.id = ?;
+	`
+
+	err := s.db.QueryRow(query, id).Scan(&sub.ID, &sub.IngredientID, &sub.Name, &sub.Notes, &sub.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.getComponentsForSubstitution(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// CreateSubstitution returns ErrUnknownIngredient when the replaced
+// ingredient or any component is not in the catalog.
+func (s *SQLiteSubstitutionStore) CreateSubstitution(sub *model.Substitution) (*model.Substitution, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO substitutions (id, ingredient_id, notes)
+		SELECT ?, id, ?
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	result, err := tx.Exec(query, sub.ID, sub.Notes, sub.IngredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUnknownIngredient
+	}
+
+	query = `
+		INSERT INTO substitution_components (substitution_id, ingredient_id, ratio, position)
+		SELECT ?, id, ?, ?
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	for position, c := range sub.Components {
+		result, err := tx.Exec(query, sub.ID, c.Ratio, position, c.IngredientID)
+		if err != nil {
+			return nil, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected == 0 {
+			return nil, ErrUnknownIngredient
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetSubstitutionByID(sub.ID)
+}
+
+func (s *SQLiteSubstitutionStore) DeleteSubstitution(id string) error {
+	query := `
+		DELETE FROM substitutions
+		WHERE id = ?;
+	`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *SQLiteSubstitutionStore) getComponentsForSubstitution(sub *model.Substitution) error {
+	query := `
+		SELECT c.ingredient_id, i.name, c.ratio
+		FROM substitution_components c
+		JOIN ingredients i ON i.id = c.ingredient_id
+		WHERE c.substitution_id = ?
+		ORDER BY c.position ASC;
+	`
+
+	rows, err := s.db.Query(query, sub.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sub.Components = []model.SubstitutionComponent{}
+	for rows.Next() {
+		var c model.SubstitutionComponent
+		if err := rows.Scan(&c.IngredientID, &c.Name, &c.Ratio); err != nil {
+			return err
+		}
+		sub.Components = append(sub.Components, c)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,71 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstitutionStore_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	substitutionStore := store.NewSQLiteSubstitutionStore(db)
+
+	for _, i := range []model.CatalogIngredient{
+		{ID: "buttermilk", Name: "Buttermilk"},
+		{ID: "milk", Name: "Milk"},
+		{ID: "lemon", Name: "Lemon Juice"},
+		{ID: "butter", Name: "Butter"},
+		{ID: "oil", Name: "Olive Oil"},
+	} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	created, err := substitutionStore.CreateSubstitution(&model.Substitution{
+		ID:           "s1",
+		IngredientID: "buttermilk",
+		Notes:        "let it stand for five minutes",
+		Components: []model.SubstitutionComponent{
+			{IngredientID: "milk", Ratio: 0.9},
+			{IngredientID: "lemon", Ratio: 0.1},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Buttermilk", created.Name)
+	assert.Equal(t, []model.SubstitutionComponent{
+		{IngredientID: "milk", Name: "Milk", Ratio: 0.9},
+		{IngredientID: "lemon", Name: "Lemon Juice", Ratio: 0.1},
+	}, created.Components)
+
+	_, err = substitutionStore.CreateSubstitution(&model.Substitution{
+		ID: "s2", IngredientID: "butter", Components: []model.SubstitutionComponent{{IngredientID: "oil", Ratio: 0.75}},
+	})
+	require.NoError(t, err)
+
+	all, err := substitutionStore.ListSubstitutions("")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	forButter, err := substitutionStore.ListSubstitutions("butter")
+	require.NoError(t, err)
+	require.Len(t, forButter, 1)
+	assert.Equal(t, "s2", forButter[0].ID)
+
+	_, err = substitutionStore.CreateSubstitution(&model.Substitution{
+		ID: "s3", IngredientID: "butter", Components: []model.SubstitutionComponent{{IngredientID: "margarine", Ratio: 1}},
+	})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+	missing, err := substitutionStore.GetSubstitutionByID("s3")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, substitutionStore.DeleteSubstitution("s2"))
+	assert.ErrorIs(t, substitutionStore.DeleteSubstitution("s2"), sql.ErrNoRows)
+}
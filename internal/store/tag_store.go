@@ -21,7 +21,7 @@ type TagStore interface {
 
 func (s *SQLiteTagStore) ListTags() ([]model.Tag, error) {
 	q := `
-		SELECT id, name
+		SELECT id, name, created_at
 		FROM tags
 		ORDER BY name ASC;
 	`
@@ -35,7 +35,7 @@ func (s *SQLiteTagStore) ListTags() ([]model.Tag, error) {
 	tags := []model.Tag{}
 	for rows.Next() {
 		var t model.Tag
-		err = rows.Scan(&t.ID, &t.Name)
+		err = rows.Scan(&t.ID, &t.Name, &t.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -57,11 +57,12 @@ func (s *SQLiteTagStore) CreateTag(t *model.Tag) (*model.Tag, error) {
 	defer tx.Rollback()
 
 	q := `
-		INSERT INTO tags (id, name)
-		VALUES (?, ?);
+		INSERT INTO tags (id, name, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		RETURNING created_at;
 	`
 
-	_, err = tx.Exec(q, t.ID, t.Name)
+	err = tx.QueryRow(q, t.ID, t.Name).Scan(&t.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -2,7 +2,9 @@ package store_test
 
 import (
 	"database/sql"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stevmwhitfield/recipe-api/internal/data/migrations"
 	"github.com/stevmwhitfield/recipe-api/internal/model"
@@ -11,8 +13,11 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// Each test gets its own named in-memory database. The shared cache lets
+// every pooled connection see the same data, which store methods that
+// query while iterating rows rely on.
 func setupDB(t *testing.T) *sql.DB {
-	db, err := sql.Open("sqlite3", ":memory:")
+	db, err := sql.Open("sqlite3", store.DSN(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())))
 	require.NoError(t, err)
 
 	err = store.MigrateFS(db, migrations.FS, ".")
@@ -26,8 +31,8 @@ func TestListTags_Integration(t *testing.T) {
 	defer db.Close()
 
 	q := `
-		INSERT INTO tags (id, name)
-		VALUES ("1", "t1"), ("2", "t2")
+		INSERT INTO tags (id, name, created_at)
+		VALUES ("1", "t1", CURRENT_TIMESTAMP), ("2", "t2", CURRENT_TIMESTAMP)
 	`
 
 	_, err := db.Exec(q)
@@ -57,4 +62,9 @@ func TestCreateTag_Integration(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, "t1", createdTag.Name)
+	assert.WithinDuration(t, time.Now(), createdTag.CreatedAt, time.Minute)
+
+	tags, err := tagStore.ListTags()
+	require.NoError(t, err)
+	assert.Equal(t, []model.Tag{*createdTag}, tags)
 }
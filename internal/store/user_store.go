@@ -0,0 +1,199 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+type SQLiteUserStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteUserStore(db *sql.DB) *SQLiteUserStore {
+	return &SQLiteUserStore{db: db}
+}
+
+type UserStore interface {
+	ListUsers() ([]model.User, error)
+	CreateUser(*model.User) (*model.User, error)
+	GetUserByID(id string) (*model.User, error)
+	AuthenticateUser(token string) (string, error)
+	GetDietaryProfile(userID string) (*model.DietaryProfile, error)
+	SetDietaryProfile(*model.DietaryProfile) (*model.DietaryProfile, error)
+}
+
+func (s *SQLiteUserStore) ListUsers() ([]model.User, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM users
+		ORDER BY name ASC;
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var u model.User
+		err = rows.Scan(&u.ID, &u.Name, &u.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CreateUser stores only a hash of the user's token. A user created
+// without one cannot be authenticated as.
+func (s *SQLiteUserStore) CreateUser(u *model.User) (*model.User, error) {
+	var tokenHash any
+	if u.Token != "" {
+		tokenHash = hashToken(u.Token)
+	}
+
+	query := `
+		INSERT INTO users (id, name, token_hash)
+		VALUES (?, ?, ?);
+	`
+
+	_, err := s.db.Exec(query, u.ID, u.Name, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (s *SQLiteUserStore) GetUserByID(id string) (*model.User, error) {
+	u := &model.User{}
+	query := `
+		SELECT id, name, created_at
+		FROM users
+		WHERE id = ?;
+	`
+
+	err := s.db.QueryRow(query, id).Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// AuthenticateUser returns the id of the user the token was issued to, or
+// "" when it was issued to no one.
+func (s *SQLiteUserStore) AuthenticateUser(token string) (string, error) {
+	var id string
+	err := s.db.QueryRow(`SELECT id FROM users WHERE token_hash = ?`, hashToken(token)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetDietaryProfile returns an empty profile for users that have not set
+// one.
+func (s *SQLiteUserStore) GetDietaryProfile(userID string) (*model.DietaryProfile, error) {
+	p := &model.DietaryProfile{UserID: userID}
+
+	var err error
+	p.Allergies, err = queryStrings(s.db, `SELECT allergen FROM user_allergies WHERE user_id = ? ORDER BY allergen ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Diets, err = queryStrings(s.db, `SELECT diet FROM user_diets WHERE user_id = ? ORDER BY diet ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.DislikedIngredientIDs, err = queryStrings(s.db, `SELECT ingredient_id FROM user_disliked_ingredients WHERE user_id = ? ORDER BY ingredient_id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetDietaryProfile replaces the user's profile. Disliked ingredients that
+// are not in the catalog return ErrUnknownIngredient.
+func (s *SQLiteUserStore) SetDietaryProfile(p *model.DietaryProfile) (*model.DietaryProfile, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"user_allergies", "user_diets", "user_disliked_ingredients"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, p.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range p.Allergies {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO user_allergies (user_id, allergen) VALUES (?, ?)`, p.UserID, a); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, d := range p.Diets {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO user_diets (user_id, diet) VALUES (?, ?)`, p.UserID, d); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		INSERT OR IGNORE INTO user_disliked_ingredients (user_id, ingredient_id)
+		SELECT ?, id
+		FROM ingredients
+		WHERE id = ?;
+	`
+
+	for _, id := range p.DislikedIngredientIDs {
+		result, err := tx.Exec(query, p.UserID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected == 0 {
+			var exists bool
+			err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ingredients WHERE id = ?)`, id).Scan(&exists)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, ErrUnknownIngredient
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetDietaryProfile(p.UserID)
+}
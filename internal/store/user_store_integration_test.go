@@ -0,0 +1,94 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateUser_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	userStore := store.NewSQLiteUserStore(db)
+
+	_, err := userStore.CreateUser(&model.User{ID: "u1", Name: "sam", Token: "secret"})
+	require.NoError(t, err)
+	// Users without a token cannot be authenticated as, and do not clash.
+	_, err = userStore.CreateUser(&model.User{ID: "u2", Name: "alex"})
+	require.NoError(t, err)
+	_, err = userStore.CreateUser(&model.User{ID: "u3", Name: "kim"})
+	require.NoError(t, err)
+
+	userID, err := userStore.AuthenticateUser("secret")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", userID)
+
+	for _, token := range []string{"other", ""} {
+		userID, err = userStore.AuthenticateUser(token)
+		require.NoError(t, err)
+		assert.Empty(t, userID)
+	}
+
+	// Only a hash of the token is stored.
+	var stored int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM users WHERE token_hash = 'secret'`).Scan(&stored))
+	assert.Zero(t, stored)
+}
+
+func TestDietaryProfile_Integration(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	userStore := store.NewSQLiteUserStore(db)
+	ingredientStore := store.NewSQLiteIngredientStore(db)
+	recipeStore := store.NewSQLiteRecipeStore(db)
+
+	_, err := userStore.CreateUser(&model.User{ID: "u1", Name: "sam"})
+	require.NoError(t, err)
+	for _, i := range []model.CatalogIngredient{{ID: "flour", Name: "Flour"}, {ID: "olives", Name: "Olives"}} {
+		_, err := ingredientStore.CreateIngredient(&i)
+		require.NoError(t, err)
+	}
+
+	profile, err := userStore.GetDietaryProfile("u1")
+	require.NoError(t, err)
+	assert.Equal(t, &model.DietaryProfile{UserID: "u1", Allergies: []string{}, Diets: []string{}, DislikedIngredientIDs: []string{}}, profile)
+
+	profile, err = userStore.SetDietaryProfile(&model.DietaryProfile{
+		UserID:                "u1",
+		Allergies:             []string{"peanut", "dairy"},
+		Diets:                 []string{"vegetarian"},
+		DislikedIngredientIDs: []string{"olives"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dairy", "peanut"}, profile.Allergies)
+	assert.Equal(t, []string{"vegetarian"}, profile.Diets)
+	assert.Equal(t, []string{"olives"}, profile.DislikedIngredientIDs)
+
+	// Setting a profile replaces the previous one.
+	profile, err = userStore.SetDietaryProfile(&model.DietaryProfile{UserID: "u1", Allergies: []string{"gluten"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gluten"}, profile.Allergies)
+	assert.Empty(t, profile.Diets)
+	assert.Empty(t, profile.DislikedIngredientIDs)
+
+	_, err = userStore.SetDietaryProfile(&model.DietaryProfile{UserID: "u1", DislikedIngredientIDs: []string{"nope"}})
+	assert.ErrorIs(t, err, store.ErrUnknownIngredient)
+
+	for _, r := range []model.Recipe{
+		{ID: "r1", Slug: "focaccia", Name: "Focaccia", Ingredients: []model.Ingredient{{LineID: "l1", ID: "flour", Quantity: 1}, {LineID: "l2", ID: "olives", Quantity: 1}}},
+		{ID: "r2", Slug: "flatbread", Name: "Flatbread", Ingredients: []model.Ingredient{{LineID: "l3", ID: "flour", Quantity: 1}}},
+	} {
+		_, err := recipeStore.CreateRecipe(&r)
+		require.NoError(t, err)
+	}
+
+	recipes, err := recipeStore.ListRecipes(store.RecipeFilter{ExcludeIngredientIDs: []string{"olives"}})
+	require.NoError(t, err)
+	require.Len(t, recipes, 1)
+	assert.Equal(t, "Flatbread", recipes[0].Name)
+}
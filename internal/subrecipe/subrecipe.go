@@ -0,0 +1,91 @@
+package subrecipe
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+)
+
+var (
+	ErrCycle         = errors.New("recipe uses itself as a sub-recipe")
+	ErrUnknownRecipe = errors.New("unknown sub-recipe")
+)
+
+// UnknownRecipeError names a sub-recipe that does not exist, however deep
+// it is used. It matches ErrUnknownRecipe.
+type UnknownRecipeError struct {
+	ID string
+}
+
+func (e *UnknownRecipeError) Error() string {
+	return fmt.Sprintf("%s %q", ErrUnknownRecipe, e.ID)
+}
+
+func (e *UnknownRecipeError) Is(target error) bool {
+	return target == ErrUnknownRecipe
+}
+
+// Lookup fetches a recipe by id. It returns nil, nil when there is none,
+// like the recipe store.
+type Lookup func(id string) (*model.Recipe, error)
+
+// Expand returns a copy of the recipe in which every line using another
+// recipe is replaced by that recipe's ingredient lines, scaled from its
+// servings to the quantity used, all the way down. The replacement lines
+// take the section of the line they replace. It returns ErrCycle when a
+// recipe uses itself and an *UnknownRecipeError when a sub-recipe does not
+// exist.
+func Expand(recipe *model.Recipe, lookup Lookup) (*model.Recipe, error) {
+	ingredients, err := expand(recipe, 1, lookup, map[string]bool{recipe.ID: true})
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := *recipe
+	expanded.Ingredients = ingredients
+	expanded.GroupSections()
+	return &expanded, nil
+}
+
+// expand scales the recipe's lines by factor. path holds the recipes
+// being expanded, from the top, to catch cycles.
+func expand(recipe *model.Recipe, factor float64, lookup Lookup, path map[string]bool) ([]model.Ingredient, error) {
+	ingredients := []model.Ingredient{}
+	for _, i := range recipe.Ingredients {
+		if i.RecipeID == "" {
+			i.Quantity *= factor
+			ingredients = append(ingredients, i)
+			continue
+		}
+
+		if path[i.RecipeID] {
+			return nil, ErrCycle
+		}
+		sub, err := lookup(i.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil {
+			return nil, &UnknownRecipeError{ID: i.RecipeID}
+		}
+
+		servings := sub.Servings
+		if servings < 1 {
+			servings = 1
+		}
+
+		path[sub.ID] = true
+		lines, err := expand(sub, factor*i.Quantity/float64(servings), lookup, path)
+		delete(path, sub.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for n := range lines {
+			lines[n].SectionID = i.SectionID
+		}
+		ingredients = append(ingredients, lines...)
+	}
+	return ingredients, nil
+}
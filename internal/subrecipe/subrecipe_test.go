@@ -0,0 +1,82 @@
+package subrecipe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/subrecipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupIn(recipes ...*model.Recipe) subrecipe.Lookup {
+	return func(id string) (*model.Recipe, error) {
+		for _, r := range recipes {
+			if r.ID == id {
+				return r, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+func TestExpand(t *testing.T) {
+	stock := &model.Recipe{ID: "stock", Servings: 8, Ingredients: []model.Ingredient{
+		{ID: "bones", Name: "Bones", Quantity: 2, Unit: "kg"},
+		{ID: "water", Name: "Water", Quantity: 4, Unit: "l"},
+	}}
+	gravy := &model.Recipe{ID: "gravy", Servings: 4, Ingredients: []model.Ingredient{
+		{RecipeID: "stock", Quantity: 2, Unit: "servings"},
+		{ID: "flour", Name: "Flour", Quantity: 20, Unit: "g"},
+	}}
+	roast := &model.Recipe{
+		ID: "roast", Servings: 4,
+		Sections: []model.Section{{ID: "sauce", Name: "For the gravy"}},
+		Ingredients: []model.Ingredient{
+			{ID: "beef", Name: "Beef", Quantity: 1, Unit: "kg"},
+			{RecipeID: "gravy", Quantity: 2, SectionID: "sauce"},
+		},
+	}
+
+	expanded, err := subrecipe.Expand(roast, lookupIn(stock, gravy))
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.Ingredient{
+		{ID: "beef", Name: "Beef", Quantity: 1, Unit: "kg"},
+		{ID: "bones", Name: "Bones", Quantity: 0.25, Unit: "kg", SectionID: "sauce"},
+		{ID: "water", Name: "Water", Quantity: 0.5, Unit: "l", SectionID: "sauce"},
+		{ID: "flour", Name: "Flour", Quantity: 10, Unit: "g", SectionID: "sauce"},
+	}, expanded.Ingredients)
+	assert.Len(t, expanded.Sections[0].Ingredients, 3)
+
+	// The original recipe is left alone.
+	assert.Len(t, roast.Ingredients, 2)
+	assert.Equal(t, 2.0, gravy.Ingredients[0].Quantity)
+}
+
+func TestExpandErrors(t *testing.T) {
+	a := &model.Recipe{ID: "a", Servings: 1, Ingredients: []model.Ingredient{{RecipeID: "b", Quantity: 1}}}
+	b := &model.Recipe{ID: "b", Servings: 1, Ingredients: []model.Ingredient{{RecipeID: "a", Quantity: 1}}}
+
+	_, err := subrecipe.Expand(a, lookupIn(a, b))
+	assert.ErrorIs(t, err, subrecipe.ErrCycle)
+
+	_, err = subrecipe.Expand(a, lookupIn(a))
+	assert.ErrorIs(t, err, subrecipe.ErrUnknownRecipe)
+	var unknown *subrecipe.UnknownRecipeError
+	if assert.ErrorAs(t, err, &unknown) {
+		assert.Equal(t, b.ID, unknown.ID)
+	}
+
+	boom := errors.New("boom")
+	_, err = subrecipe.Expand(a, func(string) (*model.Recipe, error) { return nil, boom })
+	assert.ErrorIs(t, err, boom)
+
+	// Using the same sub-recipe twice is not a cycle.
+	c := &model.Recipe{ID: "c", Servings: 1, Ingredients: []model.Ingredient{{ID: "salt", Quantity: 1}}}
+	d := &model.Recipe{ID: "d", Servings: 1, Ingredients: []model.Ingredient{{RecipeID: "c", Quantity: 1}, {RecipeID: "c", Quantity: 2}}}
+	expanded, err := subrecipe.Expand(d, lookupIn(c))
+	require.NoError(t, err)
+	assert.Len(t, expanded.Ingredients, 2)
+}
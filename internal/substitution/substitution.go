@@ -0,0 +1,180 @@
+package substitution
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/stevmwhitfield/recipe-api/internal/dietary"
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/pantry"
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+)
+
+const ReasonMissing = "missing"
+
+// Suggest finds the recipe ingredients that are not fully stocked or that
+// conflict with profile, and picks a substitution rule for each. Rules
+// whose components conflict with profile are never used, and rules whose
+// components are all in stock are preferred. profile may be nil.
+func Suggest(recipe *model.Recipe, rules []model.Substitution, catalog []model.CatalogIngredient, stock []model.StockedIngredient, profile *model.DietaryProfile) model.SubstitutionPreview {
+	byID := map[string]model.CatalogIngredient{}
+	for _, c := range catalog {
+		byID[c.ID] = c
+	}
+
+	missing := map[string]bool{}
+	for _, u := range pantry.PlanDeduction(recipe, recipe.Servings, stock).Unmatched {
+		missing[u.IngredientID] = true
+	}
+
+	preview := *recipe
+	preview.Ingredients = []model.Ingredient{}
+	suggestions := []model.SubstitutionSuggestion{}
+
+	for _, i := range recipe.Ingredients {
+		reasons := []string{}
+		if missing[i.ID] {
+			reasons = append(reasons, ReasonMissing)
+		}
+		for _, w := range dietary.IngredientWarnings(lookup(byID, i.ID), profile) {
+			reasons = append(reasons, w.Message)
+		}
+		if len(reasons) == 0 {
+			preview.Ingredients = append(preview.Ingredients, i)
+			continue
+		}
+
+		suggestion := model.SubstitutionSuggestion{
+			IngredientID: i.ID,
+			Name:         i.Name,
+			Quantity:     i.Quantity,
+			Unit:         i.Unit,
+			Reasons:      reasons,
+			Replacement:  []model.Ingredient{},
+		}
+
+		if rule, replacement, ok := choose(i, rules, byID, stock, profile); ok {
+			for n := range replacement {
+				replacement[n].SectionID = i.SectionID
+			}
+			suggestion.SubstitutionID = rule.ID
+			suggestion.Replacement = replacement
+			suggestion.Notes = rule.Notes
+			preview.Ingredients = append(preview.Ingredients, replacement...)
+		} else {
+			preview.Ingredients = append(preview.Ingredients, i)
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	preview.GroupSections()
+	preview.Allergens, preview.Diets = flags(preview.Ingredients, byID)
+
+	return model.SubstitutionPreview{Recipe: preview, Suggestions: suggestions}
+}
+
+func choose(i model.Ingredient, rules []model.Substitution, byID map[string]model.CatalogIngredient, stock []model.StockedIngredient, profile *model.DietaryProfile) (model.Substitution, []model.Ingredient, bool) {
+	var fallback *model.Substitution
+	var fallbackReplacement []model.Ingredient
+
+	for _, rule := range rules {
+		if rule.IngredientID != i.ID || len(rule.Components) == 0 {
+			continue
+		}
+
+		compatible := true
+		replacement := []model.Ingredient{}
+		for _, c := range rule.Components {
+			component := lookup(byID, c.IngredientID)
+			if len(dietary.IngredientWarnings(component, profile)) > 0 {
+				compatible = false
+				break
+			}
+			replacement = append(replacement, model.Ingredient{
+				ID:       c.IngredientID,
+				Name:     c.Name,
+				Quantity: round(i.Quantity * c.Ratio),
+				Unit:     i.Unit,
+				Note:     i.Note,
+				Density:  density(component),
+			})
+		}
+		if !compatible {
+			continue
+		}
+
+		report := pantry.PlanDeduction(&model.Recipe{Servings: 1, Ingredients: replacement}, 1, stock)
+		if len(report.Unmatched) == 0 {
+			return rule, replacement, true
+		}
+		if fallback == nil {
+			fallback = &rule
+			fallbackReplacement = replacement
+		}
+	}
+
+	if fallback == nil {
+		return model.Substitution{}, nil, false
+	}
+	return *fallback, fallbackReplacement, true
+}
+
+// flags works out a recipe's allergens and diets the same way the store
+// does: allergens of any ingredient, diets shared by every ingredient.
+func flags(ingredients []model.Ingredient, byID map[string]model.CatalogIngredient) ([]string, []string) {
+	allergens := []string{}
+	dietCounts := map[string]int{}
+	seen := map[string]bool{}
+	for _, i := range ingredients {
+		if seen[i.ID] {
+			continue
+		}
+		seen[i.ID] = true
+		c := lookup(byID, i.ID)
+		for _, a := range c.Allergens {
+			if !slices.Contains(allergens, a) {
+				allergens = append(allergens, a)
+			}
+		}
+		for _, d := range c.Diets {
+			dietCounts[d]++
+		}
+	}
+
+	diets := []string{}
+	for d, n := range dietCounts {
+		if n == len(seen) {
+			diets = append(diets, d)
+		}
+	}
+
+	sort.Strings(allergens)
+	sort.Strings(diets)
+	return allergens, diets
+}
+
+// lookup returns a bare entry for ingredients missing from the catalog so
+// they are treated as having no flags.
+func lookup(byID map[string]model.CatalogIngredient, id string) model.CatalogIngredient {
+	if c, ok := byID[id]; ok {
+		return c
+	}
+	return model.CatalogIngredient{ID: id}
+}
+
+func density(c model.CatalogIngredient) units.Density {
+	var d units.Density
+	if c.Density != nil {
+		d.GramsPerMilliliter = *c.Density
+	}
+	if c.PieceWeight != nil {
+		d.GramsPerPiece = *c.PieceWeight
+	}
+	return d
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,123 @@
+package substitution_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/model"
+	"github.com/stevmwhitfield/recipe-api/internal/substitution"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggest(t *testing.T) {
+	catalog := []model.CatalogIngredient{
+		{ID: "flour", Name: "Flour", Allergens: []string{"gluten"}, Diets: []string{"vegan", "vegetarian"}},
+		{ID: "buttermilk", Name: "Buttermilk", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+		{ID: "milk", Name: "Milk", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+		{ID: "lemon", Name: "Lemon Juice", Diets: []string{"vegan", "vegetarian"}},
+		{ID: "butter", Name: "Butter", Allergens: []string{"dairy"}, Diets: []string{"vegetarian"}},
+		{ID: "olive-oil", Name: "Olive Oil", Diets: []string{"vegan", "vegetarian"}},
+		{ID: "oat-milk", Name: "Oat Milk", Diets: []string{"vegan", "vegetarian"}},
+	}
+	rules := []model.Substitution{
+		{ID: "s1", IngredientID: "buttermilk", Components: []model.SubstitutionComponent{
+			{IngredientID: "milk", Name: "Milk", Ratio: 0.9},
+			{IngredientID: "lemon", Name: "Lemon Juice", Ratio: 0.1},
+		}},
+		{ID: "s2", IngredientID: "buttermilk", Components: []model.SubstitutionComponent{
+			{IngredientID: "oat-milk", Name: "Oat Milk", Ratio: 0.9},
+			{IngredientID: "lemon", Name: "Lemon Juice", Ratio: 0.1},
+		}},
+		{ID: "s3", IngredientID: "butter", Notes: "melt first", Components: []model.SubstitutionComponent{
+			{IngredientID: "olive-oil", Name: "Olive Oil", Ratio: 0.75},
+		}},
+	}
+	recipe := &model.Recipe{
+		ID:       "r1",
+		Servings: 4,
+		Ingredients: []model.Ingredient{
+			{ID: "flour", Name: "Flour", Quantity: 200, Unit: "g"},
+			{ID: "buttermilk", Name: "Buttermilk", Quantity: 1, Unit: "cup"},
+			{ID: "butter", Name: "Butter", Quantity: 100, Unit: "g"},
+		},
+	}
+
+	t.Run("missing from pantry", func(t *testing.T) {
+		stock := []model.StockedIngredient{
+			{ID: "s1", IngredientID: "flour", Quantity: 1, Unit: "kg"},
+			{ID: "s2", IngredientID: "butter", Quantity: 250, Unit: "g"},
+			{ID: "s3", IngredientID: "oat-milk", Quantity: 1, Unit: "l"},
+			{ID: "s4", IngredientID: "lemon", Quantity: 100, Unit: "ml"},
+		}
+
+		preview := substitution.Suggest(recipe, rules, catalog, stock, nil)
+
+		assert.Equal(t, []model.SubstitutionSuggestion{
+			{
+				IngredientID:   "buttermilk",
+				Name:           "Buttermilk",
+				Quantity:       1,
+				Unit:           "cup",
+				Reasons:        []string{"missing"},
+				SubstitutionID: "s2",
+				Replacement: []model.Ingredient{
+					{ID: "oat-milk", Name: "Oat Milk", Quantity: 0.9, Unit: "cup"},
+					{ID: "lemon", Name: "Lemon Juice", Quantity: 0.1, Unit: "cup"},
+				},
+			},
+		}, preview.Suggestions)
+		assert.Equal(t, []model.Ingredient{
+			{ID: "flour", Name: "Flour", Quantity: 200, Unit: "g"},
+			{ID: "oat-milk", Name: "Oat Milk", Quantity: 0.9, Unit: "cup"},
+			{ID: "lemon", Name: "Lemon Juice", Quantity: 0.1, Unit: "cup"},
+			{ID: "butter", Name: "Butter", Quantity: 100, Unit: "g"},
+		}, preview.Recipe.Ingredients)
+		assert.Equal(t, []string{"dairy", "gluten"}, preview.Recipe.Allergens)
+		assert.Equal(t, []string{"vegetarian"}, preview.Recipe.Diets)
+	})
+
+	t.Run("dietary restriction", func(t *testing.T) {
+		stock := []model.StockedIngredient{
+			{ID: "s1", IngredientID: "flour", Quantity: 1, Unit: "kg"},
+			{ID: "s2", IngredientID: "buttermilk", Quantity: 1, Unit: "l"},
+			{ID: "s3", IngredientID: "butter", Quantity: 250, Unit: "g"},
+		}
+		profile := &model.DietaryProfile{Allergies: []string{"dairy", "gluten"}}
+
+		preview := substitution.Suggest(recipe, rules, catalog, stock, profile)
+
+		assert.Equal(t, []model.SubstitutionSuggestion{
+			{
+				IngredientID: "flour",
+				Name:         "Flour",
+				Quantity:     200,
+				Unit:         "g",
+				Reasons:      []string{"contains gluten"},
+				Replacement:  []model.Ingredient{},
+			},
+			{
+				IngredientID:   "buttermilk",
+				Name:           "Buttermilk",
+				Quantity:       1,
+				Unit:           "cup",
+				Reasons:        []string{"contains dairy"},
+				SubstitutionID: "s2",
+				Replacement: []model.Ingredient{
+					{ID: "oat-milk", Name: "Oat Milk", Quantity: 0.9, Unit: "cup"},
+					{ID: "lemon", Name: "Lemon Juice", Quantity: 0.1, Unit: "cup"},
+				},
+			},
+			{
+				IngredientID:   "butter",
+				Name:           "Butter",
+				Quantity:       100,
+				Unit:           "g",
+				Reasons:        []string{"contains dairy"},
+				SubstitutionID: "s3",
+				Replacement:    []model.Ingredient{{ID: "olive-oil", Name: "Olive Oil", Quantity: 75, Unit: "g"}},
+				Notes:          "melt first",
+			},
+		}, preview.Suggestions)
+		assert.Equal(t, []string{"gluten"}, preview.Recipe.Allergens)
+		assert.Equal(t, []string{"vegan", "vegetarian"}, preview.Recipe.Diets)
+	})
+}
@@ -0,0 +1,36 @@
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Purger permanently deletes the recipes moved to the trash before a
+// given time, like the recipe store.
+type Purger interface {
+	PurgeDeletedRecipes(before time.Time) (int64, error)
+}
+
+// Run purges recipes that have been in the trash for longer than
+// retention, once at start and then every interval, until ctx is done.
+// Failures are logged and retried on the next run.
+func Run(ctx context.Context, logger *slog.Logger, p Purger, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.PurgeDeletedRecipes(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("PurgeTrash", "error", err)
+		} else if purged > 0 {
+			logger.Info("purged trashed recipes", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package trash_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stevmwhitfield/recipe-api/internal/trash"
+	"github.com/stretchr/testify/assert"
+)
+
+type purgerFunc func(before time.Time) (int64, error)
+
+func (f purgerFunc) PurgeDeletedRecipes(before time.Time) (int64, error) {
+	return f(before)
+}
+
+func TestRun(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var cutoffs []time.Time
+	purger := purgerFunc(func(before time.Time) (int64, error) {
+		cutoffs = append(cutoffs, before)
+		if len(cutoffs) == 3 {
+			cancel()
+		}
+		// A failed run does not stop the job.
+		return 0, errors.New("boom")
+	})
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		trash.Run(ctx, logger, purger, 24*time.Hour, time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop when its context was done")
+	}
+
+	assert.Len(t, cutoffs, 3)
+	assert.WithinDuration(t, start.Add(-24*time.Hour), cutoffs[0], time.Second)
+}
@@ -0,0 +1,187 @@
+package units
+
+import (
+	"errors"
+	"strings"
+)
+
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+)
+
+var (
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("incompatible units")
+)
+
+// Unit describes a unit of measure relative to the base unit of its
+// dimension (grams, milliliters, or pieces).
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	Factor    float64
+}
+
+var catalog = map[string]Unit{
+	"mg": {Name: "mg", Dimension: Mass, Factor: 0.001},
+	"g":  {Name: "g", Dimension: Mass, Factor: 1},
+	"kg": {Name: "kg", Dimension: Mass, Factor: 1000},
+	"oz": {Name: "oz", Dimension: Mass, Factor: 28.349523125},
+	"lb": {Name: "lb", Dimension: Mass, Factor: 453.59237},
+
+	"ml":     {Name: "ml", Dimension: Volume, Factor: 1},
+	"l":      {Name: "l", Dimension: Volume, Factor: 1000},
+	"tsp":    {Name: "tsp", Dimension: Volume, Factor: 4.92892159375},
+	"tbsp":   {Name: "tbsp", Dimension: Volume, Factor: 14.78676478125},
+	"fl oz":  {Name: "fl oz", Dimension: Volume, Factor: 29.5735295625},
+	"cup":    {Name: "cup", Dimension: Volume, Factor: 236.5882365},
+	"pint":   {Name: "pint", Dimension: Volume, Factor: 473.176473},
+	"quart":  {Name: "quart", Dimension: Volume, Factor: 946.352946},
+	"gallon": {Name: "gallon", Dimension: Volume, Factor: 3785.411784},
+
+	"":      {Name: "", Dimension: Count, Factor: 1},
+	"dozen": {Name: "dozen", Dimension: Count, Factor: 12},
+}
+
+var aliases = map[string]string{
+	"milligram": "mg", "milligrams": "mg",
+	"gram": "g", "grams": "g", "gr": "g",
+	"kilogram": "kg", "kilograms": "kg", "kgs": "kg",
+	"ounce": "oz", "ounces": "oz",
+	"pound": "lb", "pounds": "lb", "lbs": "lb",
+
+	"milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"teaspoon": "tsp", "teaspoons": "tsp", "tsps": "tsp",
+	"tablespoon": "tbsp", "tablespoons": "tbsp", "tbsps": "tbsp", "tbs": "tbsp",
+	"fluid ounce": "fl oz", "fluid ounces": "fl oz", "floz": "fl oz",
+	"cups": "cup", "c": "cup",
+	"pints": "pint", "pt": "pint",
+	"quarts": "quart", "qt": "quart",
+	"gallons": "gallon", "gal": "gallon",
+
+	"piece": "", "pieces": "", "pc": "", "pcs": "", "each": "", "whole": "",
+}
+
+// Normalize returns the canonical spelling of a unit. Unknown units are
+// trimmed, lower-cased and singularized so "Cloves" and "clove" match.
+func Normalize(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, ".")
+	if canonical, ok := aliases[u]; ok {
+		return canonical
+	}
+	if _, ok := catalog[u]; ok {
+		return u
+	}
+	if strings.HasSuffix(u, "s") && !strings.HasSuffix(u, "ss") {
+		return strings.TrimSuffix(u, "s")
+	}
+	return u
+}
+
+func Lookup(unit string) (Unit, bool) {
+	u, ok := catalog[Normalize(unit)]
+	return u, ok
+}
+
+// ToBase converts a quantity to the base unit of its dimension.
+func ToBase(quantity float64, unit string) (float64, Dimension, error) {
+	u, ok := Lookup(unit)
+	if !ok {
+		return 0, "", ErrUnknownUnit
+	}
+	return quantity * u.Factor, u.Dimension, nil
+}
+
+// Convert converts a quantity between units of the same dimension. Units
+// that normalize to the same spelling convert one to one even when they
+// are not in the catalog, so "cloves" converts to "clove".
+func Convert(quantity float64, from, to string) (float64, error) {
+	if Normalize(from) == Normalize(to) {
+		return quantity, nil
+	}
+	f, ok := Lookup(from)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+	t, ok := Lookup(to)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+	if f.Dimension != t.Dimension {
+		return 0, ErrIncompatibleUnits
+	}
+	return quantity * f.Factor / t.Factor, nil
+}
+
+// BaseUnit returns the unit quantities of a dimension are expressed in
+// after ToBase.
+func BaseUnit(d Dimension) string {
+	switch d {
+	case Mass:
+		return "g"
+	case Volume:
+		return "ml"
+	default:
+		return ""
+	}
+}
+
+// Density relates an ingredient's volume and piece count to its weight so
+// quantities can be converted across dimensions. Zero fields are unknown.
+type Density struct {
+	GramsPerMilliliter float64
+	GramsPerPiece      float64
+}
+
+// ToGrams converts a quantity of any dimension to grams, using the density
+// for volumes and counts.
+func ToGrams(quantity float64, unit string, d Density) (float64, error) {
+	u, ok := Lookup(unit)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+	perBase := d.gramsPerBase(u.Dimension)
+	if perBase == 0 {
+		return 0, ErrIncompatibleUnits
+	}
+	return quantity * u.Factor * perBase, nil
+}
+
+// ConvertWith is Convert with conversions between dimensions going through
+// the ingredient's weight.
+func ConvertWith(quantity float64, from, to string, d Density) (float64, error) {
+	converted, err := Convert(quantity, from, to)
+	if err != ErrIncompatibleUnits {
+		return converted, err
+	}
+
+	grams, err := ToGrams(quantity, from, d)
+	if err != nil {
+		return 0, err
+	}
+	u, _ := Lookup(to)
+	perBase := d.gramsPerBase(u.Dimension)
+	if perBase == 0 {
+		return 0, ErrIncompatibleUnits
+	}
+	return grams / perBase / u.Factor, nil
+}
+
+func (d Density) gramsPerBase(dimension Dimension) float64 {
+	switch dimension {
+	case Mass:
+		return 1
+	case Volume:
+		return d.GramsPerMilliliter
+	case Count:
+		return d.GramsPerPiece
+	default:
+		return 0
+	}
+}
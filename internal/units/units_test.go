@@ -0,0 +1,83 @@
+package units_test
+
+import (
+	"testing"
+
+	"github.com/stevmwhitfield/recipe-api/internal/units"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "tbsp", units.Normalize(" Tablespoons "))
+	assert.Equal(t, "g", units.Normalize("grams"))
+	assert.Equal(t, "", units.Normalize("pieces"))
+	assert.Equal(t, "clove", units.Normalize("Cloves"))
+	assert.Equal(t, "glass", units.Normalize("glass"))
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		from     string
+		to       string
+		want     float64
+		wantErr  error
+	}{
+		{name: "kg to g", quantity: 1.5, from: "kg", to: "g", want: 1500},
+		{name: "tbsp to tsp", quantity: 1, from: "tbsp", to: "tsp", want: 3},
+		{name: "cups to ml", quantity: 2, from: "cups", to: "ml", want: 473.176473},
+		{name: "dozen to pieces", quantity: 1, from: "dozen", to: "", want: 12},
+		{name: "mass to volume", quantity: 1, from: "g", to: "ml", wantErr: units.ErrIncompatibleUnits},
+		{name: "unknown unit", quantity: 1, from: "clove", to: "g", wantErr: units.ErrUnknownUnit},
+		{name: "same unknown unit", quantity: 3, from: "Cloves", to: "clove", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := units.Convert(tt.quantity, tt.from, tt.to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestConvertWith(t *testing.T) {
+	flour := units.Density{GramsPerMilliliter: 0.53}
+	egg := units.Density{GramsPerPiece: 50}
+
+	tests := []struct {
+		name     string
+		quantity float64
+		from     string
+		to       string
+		density  units.Density
+		want     float64
+		wantErr  error
+	}{
+		{name: "same dimension ignores density", quantity: 1, from: "kg", to: "g", want: 1000},
+		{name: "cup of flour to grams", quantity: 1, from: "cup", to: "g", density: flour, want: 125.39176534},
+		{name: "grams of flour to cups", quantity: 250, from: "g", to: "cups", density: flour, want: 1.993751},
+		{name: "eggs to grams", quantity: 2, from: "", to: "g", density: egg, want: 100},
+		{name: "a dozen eggs to kg", quantity: 1, from: "dozen", to: "kg", density: egg, want: 0.6},
+		{name: "eggs to cups without density", quantity: 2, from: "", to: "cup", density: egg, wantErr: units.ErrIncompatibleUnits},
+		{name: "volume to mass without density", quantity: 1, from: "cup", to: "g", wantErr: units.ErrIncompatibleUnits},
+		{name: "unknown unit", quantity: 1, from: "clove", to: "g", density: egg, wantErr: units.ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := units.ConvertWith(tt.quantity, tt.from, tt.to, tt.density)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-5)
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -13,21 +18,103 @@ import (
 type Envelope map[string]interface{}
 
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
-	js, err := json.MarshalIndent(data, "", " ")
+	js, err := marshalJSON(data)
 	if err != nil {
-		slog.Error("failed to marshal json", "error", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": "internal server error"}`))
+		writeMarshalError(w, err)
 		return
 	}
 
-	js = append(js, '\n')
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	w.Write(js)
 }
 
+// CacheOptions describe how clients may cache a GET response.
+type CacheOptions struct {
+	// CacheControl is sent as the Cache-Control header.
+	CacheControl string
+	// LastModified is sent as the Last-Modified header unless it is zero.
+	LastModified time.Time
+	// ETagPrefix starts the ETag, before the hash of the body.
+	ETagPrefix string
+}
+
+// WriteJSONConditional writes data like WriteJSON with status 200, tagged
+// with an ETag hashed from the body so it changes with anything in the
+// response. It answers 304 Not Modified without a body when the request's
+// If-None-Match, or without one its If-Modified-Since, shows the client
+// already has this response. The ETag is the stronger validator: HTTP dates
+// cannot tell apart changes in the same second.
+func WriteJSONConditional(w http.ResponseWriter, r *http.Request, data interface{}, opts CacheOptions) {
+	js, err := marshalJSON(data)
+	if err != nil {
+		writeMarshalError(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(js)
+	etag := `"` + opts.ETagPrefix + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if opts.CacheControl != "" {
+		w.Header().Set("Cache-Control", opts.CacheControl)
+	}
+	if !opts.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", opts.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, opts.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+// notModified evaluates If-None-Match, which uses weak comparison, and
+// falls back to If-Modified-Since only when it is absent, as RFC 9110
+// requires.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if strings.TrimSpace(header) == "*" {
+			return true
+		}
+		for _, tag := range strings.Split(header, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have whole seconds.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func marshalJSON(data interface{}) ([]byte, error) {
+	js, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append(js, '\n'), nil
+}
+
+func writeMarshalError(w http.ResponseWriter, err error) {
+	slog.Error("failed to marshal json", "error", err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(`{"error": "internal server error"}`))
+}
+
 func ReadIDParam(r *http.Request) (string, error) {
 	idParam := chi.URLParam(r, "id")
 	if idParam == "" {
@@ -47,3 +134,11 @@ func GenerateUUID() (string, error) {
 	}
 	return id.String(), nil
 }
+
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -62,6 +63,61 @@ func TestWriteJSON_Slice(t *testing.T) {
 	assert.Equal(t, data, body)
 }
 
+func TestWriteJSONConditional(t *testing.T) {
+	data := util.Envelope{"message": "test"}
+	lastModified := time.Date(2025, 11, 1, 19, 32, 0, 500, time.UTC)
+	opts := util.CacheOptions{CacheControl: "private, no-cache", LastModified: lastModified, ETagPrefix: "3-"}
+
+	w := httptest.NewRecorder()
+	util.WriteJSONConditional(w, httptest.NewRequest(http.MethodGet, "/", nil), data, opts)
+
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^"3-[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Sat, 01 Nov 2025 19:32:00 GMT", w.Header().Get("Last-Modified"))
+	assert.JSONEq(t, `{"message": "test"}`, w.Body.String())
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{"matching etag", "If-None-Match", etag, http.StatusNotModified},
+		{"matching weak etag in list", "If-None-Match", `"other", W/` + etag, http.StatusNotModified},
+		{"any etag", "If-None-Match", "*", http.StatusNotModified},
+		{"stale etag", "If-None-Match", `"3-stale"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", "Sat, 01 Nov 2025 19:32:00 GMT", http.StatusNotModified},
+		{"modified since", "If-Modified-Since", "Sat, 01 Nov 2025 19:31:59 GMT", http.StatusOK},
+		{"invalid date", "If-Modified-Since", "yesterday", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+
+			util.WriteJSONConditional(w, r, data, opts)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			if tt.wantCode == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+
+	// If-None-Match wins over If-Modified-Since.
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"3-stale"`)
+	r.Header.Set("If-Modified-Since", "Sat, 01 Nov 2025 19:32:00 GMT")
+	w = httptest.NewRecorder()
+	util.WriteJSONConditional(w, r, data, opts)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestReadIDParam_ValidID(t *testing.T) {
 	rawID, err := uuid.NewV7()
 	if err != nil {
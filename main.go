@@ -6,26 +6,61 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/stevmwhitfield/recipe-api/internal/app"
+	"github.com/stevmwhitfield/recipe-api/internal/data/migrations"
+	"github.com/stevmwhitfield/recipe-api/internal/nutrition"
 	"github.com/stevmwhitfield/recipe-api/internal/router"
+	"github.com/stevmwhitfield/recipe-api/internal/store"
+	"github.com/stevmwhitfield/recipe-api/internal/trash"
 )
 
 func main() {
 	var port int
+	var nutritionCSV string
+	var trashRetention time.Duration
+	var cleanOrphans bool
+	var publicURL string
 	flag.IntVar(&port, "port", 3000, "go server port")
+	flag.StringVar(&publicURL, "public-url", "", "url clients reach the api at, used in calendar feed links; defaults to http://localhost on the server port")
+	flag.StringVar(&nutritionCSV, "import-nutrition", "", "import nutrition data from a CSV file and exit")
+	flag.DurationVar(&trashRetention, "trash-retention", 30*24*time.Hour, "how long deleted recipes stay in the trash before they are purged; 0 keeps them until purged by hand")
+	flag.BoolVar(&cleanOrphans, "clean-orphans", false, "delete or clear rows that refer to records which no longer exist and exit")
 	flag.Parse()
 
-	app, err := app.NewApplication()
+	// The application refuses to start on a database with orphaned rows,
+	// so cleaning them cannot wait for it.
+	if cleanOrphans {
+		if err := cleanOrphanedRows(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("http://localhost:%d", port)
+	}
+
+	app, err := app.NewApplication(publicURL)
 	if err != nil {
 		panic(err)
 	}
 	defer app.DB.Close()
 
+	if nutritionCSV != "" {
+		if err := importNutrition(app, nutritionCSV); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	r := router.InitRoutes(app)
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -45,6 +80,10 @@ func main() {
 		}
 	}()
 
+	if trashRetention > 0 {
+		go trash.Run(ctx, app.Logger, store.NewSQLiteRecipeStore(app.DB), trashRetention, time.Hour)
+	}
+
 	<-ctx.Done()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -54,3 +93,53 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func importNutrition(app *app.Application, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := nutrition.ReadCSV(f, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	unmatched, err := store.NewSQLiteNutritionStore(app.DB).ImportNutrition(data)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range unmatched {
+		app.Logger.Warn("no matching ingredient", "name", n.Name, "ingredientId", n.IngredientID)
+	}
+	app.Logger.Info(fmt.Sprintf("imported nutrition for %d of %d rows", len(data)-len(unmatched), len(data)))
+
+	return nil
+}
+
+func cleanOrphanedRows() error {
+	db, err := store.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := store.MigrateFS(db, migrations.FS, "."); err != nil {
+		return err
+	}
+
+	cleanups, err := store.CleanOrphans(db)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	for _, c := range cleanups {
+		logger.Info(fmt.Sprintf("%s %d rows of %s", c.Action, c.Rows, c.Table), "column", c.Column, "missing", c.Parent)
+	}
+	logger.Info(fmt.Sprintf("fixed %d kinds of orphaned rows", len(cleanups)))
+
+	return nil
+}